@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+// runDiscover searches for stations in an area with the configured client and optionally writes them
+// to a station list file. Returns the exit code.
+func runDiscover(args []string) int {
+	flags := flag.NewFlagSet("discover", flag.ContinueOnError)
+	bbox := flags.String("bbox", "", "bounding box to search as \"minLat,minLon,maxLat,maxLon\"")
+	center := flags.String("center", "", "center of the area to search as \"lat,lon\"")
+	radius := flags.Float64("radius", 0, "radius around the center to search in km")
+	all := flags.Bool("all", false, "include stations that don't report METARs")
+	out := flags.String("out", "", "file to write the station list to, for \"station_list_file\"")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	area, err := parseDiscoveryArea(*bbox, *center, *radius)
+	if err != nil {
+		fmt.Printf("discover: %s\n", err)
+		flags.Usage()
+		return 2
+	}
+
+	settings := common.GetAppSettings()
+	client, err := createMetarClient(settings, settings.StationIDs)
+	if err != nil {
+		return 1
+	}
+
+	stations, err := metarclient.DiscoverStations(context.Background(), client, area, !*all)
+	if err != nil {
+		logger.LogError("Failed to discover stations: %s", err.Error())
+		return 1
+	}
+
+	for _, s := range stations {
+		fmt.Printf("%-6s %9.4f %10.4f %6.0fm  %-12s %s\n", s.StationID, s.Latitude, s.Longitude, s.Elevation, strings.Join(s.SiteTypes, ","), s.Name)
+	}
+	fmt.Printf("found %d stations\n", len(stations))
+
+	if *out == "" {
+		return 0
+	}
+
+	if err := stationrepo.CreateStationList(stations).Save(*out); err != nil {
+		logger.LogError("Failed to save station list: %s", err.Error())
+		return 1
+	}
+	fmt.Printf("wrote station list to '%s'\n", *out)
+
+	return 0
+}
+
+func parseDiscoveryArea(bbox string, center string, radius float64) (*metarclient.DiscoveryArea, error) {
+	if (bbox == "") == (center == "") {
+		return nil, errors.New("set either -bbox or -center and -radius")
+	}
+
+	if bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return nil, errors.New("-bbox needs 4 comma separated values")
+		}
+
+		values := make([]float64, len(parts))
+		for i, p := range parts {
+			value, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -bbox value '%s'", p)
+			}
+			values[i] = value
+		}
+
+		return &metarclient.DiscoveryArea{
+			Box: &geo.BoundingBox{MinLatitude: values[0], MinLongitude: values[1], MaxLatitude: values[2], MaxLongitude: values[3]},
+		}, nil
+	}
+
+	parts := strings.Split(center, ",")
+	if len(parts) != 2 {
+		return nil, errors.New("-center needs a comma separated latitude and longitude")
+	}
+
+	coord, err := geo.ParseDecimalCoordinate(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid -center: %s", err)
+	}
+
+	if radius <= 0 {
+		return nil, errors.New("-radius must be positive")
+	}
+
+	return &metarclient.DiscoveryArea{Center: coord, RadiusKm: radius}, nil
+}
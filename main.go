@@ -4,23 +4,47 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ataboo/go-metar-blink/pkg/common"
 	"github.com/ataboo/go-metar-blink/pkg/engine"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationdb"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 )
 
+// manualBrightnessLevels are stepped through with SIGUSR1, wrapping back to full, and held over the brightness
+// schedule until SIGUSR2.
+var manualBrightnessLevels = []byte{0x80, 0x40, 0x10, 0xFF}
+
+// PowerLogPeriod is how often the estimated led power draw is logged.
+const PowerLogPeriod = time.Minute
+
 func main() {
 	common.GetAppSettings()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "discover":
+			os.Exit(runDiscover(os.Args[2:]))
+		case "stationdb":
+			os.Exit(runRefreshStationDB(os.Args[2:]))
+		}
+	}
+
 	runMainApp()
 
 	os.Exit(0)
 }
 
 func runMainApp() {
-	stationRepo := initStationRepo(common.GetAppSettings())
+	stationRepo, err := initStationRepo(common.GetAppSettings())
+	if err != nil {
+		logger.LogError("failed to init station repo, %s", err)
+		panic("aborting")
+	}
 
 	engine, err := engine.CreateEngine(stationRepo, common.GetAppSettings())
 	if err != nil {
@@ -35,31 +59,125 @@ func runMainApp() {
 	}
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
-
-	select {
-	case <-engine.DoneSubscribe():
-		logger.LogDebug("engine done")
-		break
-	case sigVal := <-sigs:
-		logger.LogDebug("received signal: %d", sigVal)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGUSR1, syscall.SIGUSR2)
+	done := engine.DoneSubscribe()
+	brightnessStep := -1
+	powerTicker := time.NewTicker(PowerLogPeriod)
+	defer powerTicker.Stop()
+
+	running := true
+	for running {
+		select {
+		case <-done:
+			logger.LogDebug("engine done")
+			running = false
+		case <-powerTicker.C:
+			draw := engine.PowerDraw()
+			logger.LogDebug("led power draw: %.0f mA requested, %.0f mA after limiting", draw.RequestedMA, draw.LimitedMA)
+		case sigVal := <-sigs:
+			switch sigVal {
+			case syscall.SIGUSR1:
+				brightnessStep = (brightnessStep + 1) % len(manualBrightnessLevels)
+				engine.SetBrightness(manualBrightnessLevels[brightnessStep])
+			case syscall.SIGUSR2:
+				brightnessStep = -1
+				engine.ResumeBrightnessSchedule()
+				logger.LogInfo("resumed brightness schedule")
+			default:
+				logger.LogDebug("received signal: %d", sigVal)
+				running = false
+			}
+		}
 	}
 
 	engine.Dispose()
 }
 
-func initStationRepo(settings *common.AppSettings) *stationrepo.StationRepo {
+func initStationRepo(settings *common.AppSettings) (*stationrepo.StationRepo, error) {
 	logger.LogInfo("[1.1] Initializing client")
 	common.DumpSettingsInfo()
+
+	stationIDs := settings.StationIDs
+	var coordinates map[string]*geo.Coordinate
+	var names map[string]string
+	if settings.StationListFile != "" {
+		list, err := stationrepo.LoadStationList(settings.StationListFile)
+		if err != nil {
+			logger.LogError("Failed to load station list: %s", err.Error())
+		} else {
+			logger.LogInfo("using %d stations from '%s'", len(list.Stations), settings.StationListFile)
+			stationIDs = list.StationIDs()
+			coordinates = list.Coordinates()
+			names = list.Names()
+		}
+	}
+
+	db, err := stationdb.Load(stationDBPath(settings))
+	if err != nil {
+		logger.LogWarn("Failed to load station db: %s", err.Error())
+	}
+
+	client, err := createMetarClient(settings, stationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := stationrepo.CreateStationRepo(client, &stationrepo.Config{
+		StationIDs:  stationIDs,
+		StaleAfter:  time.Duration(settings.StaleAfterMins) * time.Minute,
+		ExpireAfter: time.Duration(settings.ExpireAfterMins) * time.Minute,
+		Coordinates: coordinates,
+		Names:       names,
+		StationDB:   db,
+		CacheDir:    settings.CacheDir,
+	})
+
+	return repo, nil
+}
+
+func stationDBPath(settings *common.AppSettings) string {
+	if settings.StationDBFile != "" {
+		return settings.StationDBFile
+	}
+
+	return stationdb.DefaultPath()
+}
+
+func createMetarClient(settings *common.AppSettings, stationIDs []string) (metarclient.MetarClient, error) {
+	rules, err := metarclient.FlightCategoryRulesFromSettings(settings.FlightCategory)
+	if err != nil {
+		logger.LogError("Failed to load flight category rules: %s", err.Error())
+		return nil, err
+	}
+
+	providers := make([]*metarclient.Settings, len(settings.FailoverProviders))
+	for i, p := range settings.FailoverProviders {
+		providers[i] = &metarclient.Settings{
+			Strategy:    metarclient.MetarStrategy(p.ClientStrategy),
+			EndPoint:    p.ClientEndPoint,
+			RecordDir:   settings.RecordDir,
+			ReplaySpeed: settings.ReplaySpeed,
+		}
+	}
+
 	client, err := metarclient.CreateMetarClient(&metarclient.Settings{
-		StationIDs: settings.StationIDs,
-		Strategy:   metarclient.MetarStrategy(settings.ClientStrategy),
+		StationIDs:            stationIDs,
+		Strategy:              metarclient.MetarStrategy(settings.ClientStrategy),
+		EndPoint:              settings.ClientEndPoint,
+		RecordDir:             settings.RecordDir,
+		ReplaySpeed:           settings.ReplaySpeed,
+		FlightCategoryRules:   rules,
+		Providers:             providers,
+		HTTPTimeout:           time.Duration(settings.HTTPTimeoutSecs) * time.Second,
+		RetryCount:            settings.HTTPRetries,
+		RetryBaseDelay:        time.Duration(settings.HTTPRetryDelaySecs) * time.Second,
+		ChunkSize:             settings.RequestChunkSize,
+		MaxConcurrentRequests: settings.MaxConcurrentRequests,
 	})
 	if err != nil {
 		logger.LogError("Failed to start client: %s", err.Error())
+		return nil, err
 	}
 
-	repo := stationrepo.CreateStationRepo(client, &stationrepo.Config{StationIDs: settings.StationIDs})
-
-	return repo
+	return client, nil
 }
@@ -30,13 +30,19 @@ func lerpByte(start byte, end byte, mu float64) byte {
 }
 
 func sharpPeakByte(start byte, end byte, mu float64) byte {
-	factor := 1 - 2*math.Abs(mu-0.5)
-
-	return byte(math.Round(float64(start)*(1-factor) + float64(end)*factor))
+	return lerpByte(start, end, sharpPeak(mu))
 }
 
 func cosinePeakByte(startVal byte, endVal byte, mu float64) byte {
-	factor := (1 - math.Cos(mu*math.Pi*2)) / 2
+	return lerpByte(startVal, endVal, cosinePeak(mu))
+}
+
+// sharpPeak rises linearly to 1 at the midpoint and falls back to 0.
+func sharpPeak(mu float64) float64 {
+	return 1 - 2*math.Abs(mu-0.5)
+}
 
-	return byte(math.Round(float64(startVal)*(1-factor) + float64(endVal)*factor))
+// cosinePeak rises smoothly to 1 at the midpoint and falls back to 0.
+func cosinePeak(mu float64) float64 {
+	return (1 - math.Cos(mu*math.Pi*2)) / 2
 }
@@ -52,3 +52,27 @@ func (c Color) RGBA() uint32 {
 func (c Color) RGB() uint32 {
 	return uint32(c)
 }
+
+// Scale multiplies each channel by the factor, clamped to 0 - 1.
+func (c Color) Scale(factor float64) Color {
+	if factor <= 0 {
+		return ColorBlack
+	}
+	if factor >= 1 {
+		return c
+	}
+
+	return CreateColor(scaleByte(c.R(), factor), scaleByte(c.G(), factor), scaleByte(c.B(), factor))
+}
+
+func scaleByte(b byte, factor float64) byte {
+	return byte(float64(b)*factor + 0.5)
+}
+
+func maxByte(a byte, b byte) byte {
+	if a > b {
+		return a
+	}
+
+	return b
+}
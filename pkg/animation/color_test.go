@@ -1,6 +1,9 @@
 package animation
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func TestColorLerping(t *testing.T) {
 	start := ColorRed
@@ -131,3 +134,96 @@ func assertColorsMatchExpected(expected Color, actual Color, t *testing.T) {
 		t.Errorf("expected %s instead of %s", expected.String(), actual.String())
 	}
 }
+
+func TestColorScale(t *testing.T) {
+	color := Color(0x80FF40)
+
+	assertColorsMatchExpected(0x80FF40, color.Scale(1), t)
+	assertColorsMatchExpected(0x408020, color.Scale(0.5), t)
+	assertColorsMatchExpected(0x000000, color.Scale(0), t)
+	assertColorsMatchExpected(0x80FF40, color.Scale(2), t)
+}
+
+func TestColorSpaceRoundTrips(t *testing.T) {
+	for r := 0; r <= 0xFF; r += 0x11 {
+		for g := 0; g <= 0xFF; g += 0x11 {
+			for b := 0; b <= 0xFF; b += 0x11 {
+				color := CreateColor(byte(r), byte(g), byte(b))
+
+				if c := CreateColorHSV(color.HSV()); c != color {
+					t.Fatalf("HSV round trip: expected %s, got %s", color, c)
+				}
+
+				if c := CreateColorHSL(color.HSL()); c != color {
+					t.Fatalf("HSL round trip: expected %s, got %s", color, c)
+				}
+
+				if c := CreateColorOKLab(color.OKLab()); c != color {
+					t.Fatalf("OKLab round trip: expected %s, got %s", color, c)
+				}
+			}
+		}
+	}
+}
+
+func TestColorSpaceConversions(t *testing.T) {
+	if h, s, v := ColorRed.HSV(); h != 0 || s != 1 || v != 1 {
+		t.Error("unexpected red HSV", h, s, v)
+	}
+
+	if h, s, l := ColorBlue.HSL(); h != 240 || s != 1 || l != 0.5 {
+		t.Error("unexpected blue HSL", h, s, l)
+	}
+
+	if l, a, b := ColorWhite.OKLab(); math.Abs(l-1) > 1e-4 || math.Abs(a) > 1e-4 || math.Abs(b) > 1e-4 {
+		t.Error("unexpected white OKLab", l, a, b)
+	}
+
+	assertColorsMatchExpected(ColorGreen, CreateColorHSV(120, 1, 1), t)
+	assertColorsMatchExpected(ColorMagenta, CreateColorHSL(-60, 1, 0.5), t)
+}
+
+func TestInterpolateColors(t *testing.T) {
+	rgb := InterpolateColors(ColorGreen, ColorRed, 0.5, InterpolateRGB)
+	assertColorsMatchExpected(0x808000, rgb, t)
+
+	hsv := InterpolateColors(ColorGreen, ColorRed, 0.5, InterpolateHSV)
+	assertColorsMatchExpected(0xFFFF00, hsv, t)
+
+	hsl := InterpolateColors(ColorGreen, ColorRed, 0.5, InterpolateHSL)
+	assertColorsMatchExpected(0xFFFF00, hsl, t)
+
+	wrapped := InterpolateColors(ColorMagenta, ColorRed, 0.5, InterpolateHSV)
+	assertColorsMatchExpected(0xFF0080, wrapped, t)
+
+	gray := InterpolateColors(ColorBlack, ColorBlue, 0.5, InterpolateHSV)
+	assertColorsMatchExpected(0x000080, gray, t)
+
+	okLab := InterpolateColors(ColorBlack, ColorWhite, 0.5, InterpolateOKLab)
+	if okLab.R() != okLab.G() || okLab.G() != okLab.B() || okLab.R() <= 0x60 || okLab.R() >= 0x80 {
+		t.Error("expected a neutral OKLab midpoint darker than the RGB one", okLab)
+	}
+
+	for _, interpolation := range []Interpolation{InterpolateRGB, InterpolateHSV, InterpolateHSL, InterpolateOKLab} {
+		assertColorsMatchExpected(ColorGreen, InterpolateColors(ColorGreen, ColorRed, 0, interpolation), t)
+		assertColorsMatchExpected(ColorRed, InterpolateColors(ColorGreen, ColorRed, 1, interpolation), t)
+	}
+}
+
+func TestTrackInterpolation(t *testing.T) {
+	track, _ := CreateTrack(5, false, []KeyFrame{
+		{Position: 0, Value: ColorGreen},
+		{Position: 2, Value: ColorRed},
+		{Position: 4, Value: ColorRed},
+	})
+
+	track.Seek(1)
+	assertColorsMatchExpected(0x808000, track.Value(), t)
+
+	if err := track.SetInterpolation(InterpolateHSV); err != nil {
+		t.Fatal(err)
+	}
+
+	track.Seek(1)
+	assertColorsMatchExpected(0xFFFF00, track.Value(), t)
+}
@@ -0,0 +1,236 @@
+package animation
+
+import "math"
+
+// Interpolation is the color space that colors are blended through.
+type Interpolation int
+
+const (
+	// InterpolateRGB blends each byte on its own.
+	InterpolateRGB Interpolation = iota
+	// InterpolateHSV blends around the shortest way of the hue wheel, keeping colors saturated.
+	InterpolateHSV
+	// InterpolateHSL is like InterpolateHSV with lightness in place of value.
+	InterpolateHSL
+	// InterpolateOKLab blends in a perceptual space so fades look even in brightness.
+	InterpolateOKLab
+)
+
+// InterpolateColors blends from start to end by mu through the interpolation's color space.
+func InterpolateColors(start Color, end Color, mu float64, interpolation Interpolation) Color {
+	switch interpolation {
+	case InterpolateHSV:
+		h1, s1, v1 := start.HSV()
+		h2, s2, v2 := end.HSV()
+		h1, h2 = matchGrayHue(h1, s1, h2, s2)
+		s1, s2 = matchBlackSaturation(s1, v1 == 0, s2, v2 == 0)
+
+		return CreateColorHSV(lerpHue(h1, h2, mu), lerpFloat(s1, s2, mu), lerpFloat(v1, v2, mu))
+	case InterpolateHSL:
+		h1, s1, l1 := start.HSL()
+		h2, s2, l2 := end.HSL()
+		h1, h2 = matchGrayHue(h1, s1, h2, s2)
+		s1, s2 = matchBlackSaturation(s1, l1 == 0 || l1 == 1, s2, l2 == 0 || l2 == 1)
+
+		return CreateColorHSL(lerpHue(h1, h2, mu), lerpFloat(s1, s2, mu), lerpFloat(l1, l2, mu))
+	case InterpolateOKLab:
+		l1, a1, b1 := start.OKLab()
+		l2, a2, b2 := end.OKLab()
+
+		return CreateColorOKLab(lerpFloat(l1, l2, mu), lerpFloat(a1, a2, mu), lerpFloat(b1, b2, mu))
+	default:
+		return lerpColor(start, end, mu, lerpByte)
+	}
+}
+
+// HSV gets the hue in degrees from 0 - 360 with the saturation and value from 0 - 1.
+func (c Color) HSV() (h, s, v float64) {
+	r, g, b := c.unitRGB()
+	max := math.Max(r, math.Max(g, b))
+	chroma := max - math.Min(r, math.Min(g, b))
+
+	if max > 0 {
+		s = chroma / max
+	}
+
+	return hue(r, g, b, max, chroma), s, max
+}
+
+// CreateColorHSV creates a color from a hue in degrees with the saturation and value from 0 - 1.
+func CreateColorHSV(h, s, v float64) Color {
+	chroma := clampUnit(v) * clampUnit(s)
+
+	return colorFromHue(h, chroma, clampUnit(v)-chroma)
+}
+
+// HSL gets the hue in degrees from 0 - 360 with the saturation and lightness from 0 - 1.
+func (c Color) HSL() (h, s, l float64) {
+	r, g, b := c.unitRGB()
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	chroma := max - min
+
+	l = (max + min) / 2
+	if chroma > 0 {
+		s = chroma / (1 - math.Abs(2*l-1))
+	}
+
+	return hue(r, g, b, max, chroma), s, l
+}
+
+// CreateColorHSL creates a color from a hue in degrees with the saturation and lightness from 0 - 1.
+func CreateColorHSL(h, s, l float64) Color {
+	l = clampUnit(l)
+	chroma := (1 - math.Abs(2*l-1)) * clampUnit(s)
+
+	return colorFromHue(h, chroma, l-chroma/2)
+}
+
+// OKLab gets the color's lightness from 0 - 1 and its green-red and blue-yellow components.
+func (c Color) OKLab() (l, a, b float64) {
+	r, g, bl := c.unitRGB()
+	r, g, bl = srgbToLinear(r), srgbToLinear(g), srgbToLinear(bl)
+
+	lms := [3]float64{
+		math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*bl),
+		math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*bl),
+		math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*bl),
+	}
+
+	return 0.2104542553*lms[0] + 0.7936177850*lms[1] - 0.0040720468*lms[2],
+		1.9779984951*lms[0] - 2.4285922050*lms[1] + 0.4505937099*lms[2],
+		0.0259040371*lms[0] + 0.7827717662*lms[1] - 0.8086757660*lms[2]
+}
+
+// CreateColorOKLab creates a color from OKLab components, clamping colors outside of RGB.
+func CreateColorOKLab(l, a, b float64) Color {
+	lms := [3]float64{
+		math.Pow(l+0.3963377774*a+0.2158037573*b, 3),
+		math.Pow(l-0.1055613458*a-0.0638541728*b, 3),
+		math.Pow(l-0.0894841775*a-1.2914855480*b, 3),
+	}
+
+	return createColorUnit(
+		linearToSRGB(4.0767416621*lms[0]-3.3077115913*lms[1]+0.2309699292*lms[2]),
+		linearToSRGB(-1.2684380046*lms[0]+2.6097574011*lms[1]-0.3413193965*lms[2]),
+		linearToSRGB(-0.0041960863*lms[0]-0.7034186147*lms[1]+1.7076147010*lms[2]),
+	)
+}
+
+func (c Color) unitRGB() (r, g, b float64) {
+	return float64(c.R()) / 0xFF, float64(c.G()) / 0xFF, float64(c.B()) / 0xFF
+}
+
+func createColorUnit(r, g, b float64) Color {
+	return CreateColor(unitByte(r), unitByte(g), unitByte(b))
+}
+
+func unitByte(value float64) byte {
+	return byte(math.Round(clampUnit(value) * 0xFF))
+}
+
+func clampUnit(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
+
+func hue(r, g, b, max, chroma float64) float64 {
+	if chroma == 0 {
+		return 0
+	}
+
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/chroma, 6)
+	case g:
+		h = (b-r)/chroma + 2
+	default:
+		h = (r-g)/chroma + 4
+	}
+
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	return h
+}
+
+func colorFromHue(h, chroma, min float64) Color {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+
+	sector := h / 60
+	x := chroma * (1 - math.Abs(math.Mod(sector, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case sector < 1:
+		r, g = chroma, x
+	case sector < 2:
+		r, g = x, chroma
+	case sector < 3:
+		g, b = chroma, x
+	case sector < 4:
+		g, b = x, chroma
+	case sector < 5:
+		r, b = x, chroma
+	default:
+		r, b = chroma, x
+	}
+
+	return createColorUnit(r+min, g+min, b+min)
+}
+
+// matchGrayHue gives a gray the other color's hue so fading to or from it doesn't sweep the hue wheel.
+func matchGrayHue(h1, s1, h2, s2 float64) (float64, float64) {
+	if s1 == 0 {
+		h1 = h2
+	}
+	if s2 == 0 {
+		h2 = h1
+	}
+
+	return h1, h2
+}
+
+// matchBlackSaturation gives black, or white in HSL, the other color's saturation so it fades in the other's color
+// instead of through gray.
+func matchBlackSaturation(s1 float64, undefined1 bool, s2 float64, undefined2 bool) (float64, float64) {
+	if undefined1 {
+		s1 = s2
+	}
+	if undefined2 {
+		s2 = s1
+	}
+
+	return s1, s2
+}
+
+func lerpHue(start float64, end float64, mu float64) float64 {
+	delta := math.Mod(end-start+540, 360) - 180
+
+	return start + delta*mu
+}
+
+func lerpFloat(start float64, end float64, mu float64) float64 {
+	return start*(1-mu) + end*mu
+}
+
+func srgbToLinear(value float64) float64 {
+	if value <= 0.04045 {
+		return value / 12.92
+	}
+
+	return math.Pow((value+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) float64 {
+	if value <= 0.0031308 {
+		return value * 12.92
+	}
+
+	return 1.055*math.Pow(value, 1/2.4) - 0.055
+}
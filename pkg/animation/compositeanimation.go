@@ -0,0 +1,168 @@
+package animation
+
+import (
+	"math"
+	"time"
+)
+
+// BlendMode is how a layer combines with the layers below it.
+type BlendMode int
+
+const (
+	// BlendReplace shows the layer over the layers below.
+	BlendReplace BlendMode = iota
+	// BlendMultiply darkens the layers below by the layer, like a dimmer.
+	BlendMultiply
+	// BlendAdd adds the layer to the layers below, clamped to white.
+	BlendAdd
+	// BlendAlphaOver treats the layer's brightest channel as its alpha so black is clear and bright colors cover.
+	BlendAlphaOver
+	// BlendMax takes the brighter of each channel.
+	BlendMax
+)
+
+// Layer is an animation composited over the layers below it with an opacity of 0 - 1.
+type Layer struct {
+	Animation Animation
+	Blend     BlendMode
+	Opacity   float64
+}
+
+// CreateLayer creates a fully opaque layer.
+func CreateLayer(animation Animation, blend BlendMode) *Layer {
+	return &Layer{
+		Animation: animation,
+		Blend:     blend,
+		Opacity:   1,
+	}
+}
+
+// CompositeAnimation stacks layers per channel, bottom first.
+// A layer only blends on the channels it sets, leaving the others to the layers below.
+type CompositeAnimation struct {
+	layers      []*Layer
+	layerValues map[int]Color
+}
+
+// CreateCompositeAnimation creates a new CompositeAnimation with the layers ordered bottom to top.
+func CreateCompositeAnimation(layers ...*Layer) Animation {
+	return &CompositeAnimation{
+		layers:      layers,
+		layerValues: make(map[int]Color),
+	}
+}
+
+// Reset resets all the layers.
+func (a *CompositeAnimation) Reset() {
+	for _, l := range a.layers {
+		l.Animation.Reset()
+	}
+}
+
+// Start starts all the layers.
+func (a *CompositeAnimation) Start() {
+	for _, l := range a.layers {
+		l.Animation.Start()
+	}
+}
+
+// Stop stops all the layers.
+func (a *CompositeAnimation) Stop() {
+	for _, l := range a.layers {
+		l.Animation.Stop()
+	}
+}
+
+// Update advances each layer and sets the composited channel values in the `values` map.
+func (a *CompositeAnimation) Update(delta time.Duration, values map[int]Color) {
+	a.composite(values, func(layer Animation, layerValues map[int]Color) {
+		layer.Update(delta, layerValues)
+	})
+}
+
+// Step advances each layer by a single frame.
+func (a *CompositeAnimation) Step(values map[int]Color) {
+	a.composite(values, Animation.Step)
+}
+
+// GetValues reads the composited values of the layers.
+func (a *CompositeAnimation) GetValues(values map[int]Color) {
+	a.composite(values, Animation.GetValues)
+}
+
+func (a *CompositeAnimation) composite(values map[int]Color, read func(layer Animation, layerValues map[int]Color)) {
+	for channel := range values {
+		delete(values, channel)
+	}
+
+	for _, l := range a.layers {
+		for channel := range a.layerValues {
+			delete(a.layerValues, channel)
+		}
+
+		read(l.Animation, a.layerValues)
+		for channel, value := range a.layerValues {
+			values[channel] = BlendColors(values[channel], value, l.Blend, l.Opacity)
+		}
+	}
+}
+
+// BlendColors blends the top color over the bottom, mixing the result with the bottom by the opacity.
+func BlendColors(bottom Color, top Color, blend BlendMode, opacity float64) Color {
+	if opacity <= 0 {
+		return bottom
+	}
+	opacity = math.Min(opacity, 1)
+
+	alpha := float64(maxByte(top.R(), maxByte(top.G(), top.B()))) / 0xFF
+	blendByte := func(b byte, t byte) byte {
+		below := float64(b) / 0xFF
+		above := float64(t) / 0xFF
+
+		var blended float64
+		switch blend {
+		case BlendMultiply:
+			blended = below * above
+		case BlendAdd:
+			blended = math.Min(below+above, 1)
+		case BlendAlphaOver:
+			blended = above + below*(1-alpha)
+		case BlendMax:
+			blended = math.Max(below, above)
+		default:
+			blended = above
+		}
+
+		return byte(math.Round((below + (blended-below)*opacity) * 0xFF))
+	}
+
+	return CreateColor(blendByte(bottom.R(), top.R()), blendByte(bottom.G(), top.G()), blendByte(bottom.B(), top.B()))
+}
+
+// ChannelPositions gets the position of each channel, taking the lowest layer that sets the channel.
+func (a *CompositeAnimation) ChannelPositions() map[int]int {
+	positions := make(map[int]int)
+	for _, l := range a.layers {
+		seeker, ok := l.Animation.(ChannelSeeker)
+		if !ok {
+			continue
+		}
+
+		for channel, position := range seeker.ChannelPositions() {
+			if _, ok := positions[channel]; !ok {
+				positions[channel] = position
+			}
+		}
+	}
+
+	return positions
+}
+
+// SeekChannels seeks the channels on every layer.
+func (a *CompositeAnimation) SeekChannels(positions map[int]int) {
+	for _, l := range a.layers {
+		if seeker, ok := l.Animation.(ChannelSeeker); ok {
+			seeker.SeekChannels(positions)
+		}
+	}
+}
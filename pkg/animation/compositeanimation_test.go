@@ -0,0 +1,105 @@
+package animation
+
+import (
+	"testing"
+	"time"
+)
+
+func createSolidAnimation(color Color, channels ...int) Animation {
+	track, _ := CreateTrack(2, false, []KeyFrame{{Position: 0, Value: color}})
+	track.ChannelIDs = channels
+
+	return CreateTrackAnimation([]*Track{track}, 10)
+}
+
+func TestBlendColors(t *testing.T) {
+	bottom := CreateColor(0x80, 0x40, 0x00)
+	top := CreateColor(0x80, 0x80, 0x40)
+
+	rows := []struct {
+		name     string
+		blend    BlendMode
+		opacity  float64
+		expected Color
+	}{
+		{"replace", BlendReplace, 1, top},
+		{"replace half", BlendReplace, 0.5, CreateColor(0x80, 0x60, 0x20)},
+		{"multiply", BlendMultiply, 1, CreateColor(0x40, 0x20, 0x00)},
+		{"add", BlendAdd, 1, CreateColor(0xFF, 0xC0, 0x40)},
+		{"alpha over", BlendAlphaOver, 1, CreateColor(0xC0, 0xA0, 0x40)},
+		{"max", BlendMax, 1, CreateColor(0x80, 0x80, 0x40)},
+		{"transparent", BlendAdd, 0, bottom},
+		{"clamped opacity", BlendReplace, 2, top},
+	}
+
+	for _, row := range rows {
+		if c := BlendColors(bottom, top, row.blend, row.opacity); c != row.expected {
+			t.Errorf("%s: expected %s, got %s", row.name, row.expected, c)
+		}
+	}
+
+	if c := BlendColors(bottom, ColorBlack, BlendAlphaOver, 1); c != bottom {
+		t.Error("expected black to be clear over", c)
+	}
+
+	if c := BlendColors(bottom, ColorWhite, BlendAlphaOver, 1); c != ColorWhite {
+		t.Error("expected white to cover", c)
+	}
+}
+
+func TestCompositeAnimation(t *testing.T) {
+	flash, _ := CreateTrack(10, true, []KeyFrame{
+		{Position: 0, Value: ColorBlack},
+		{Position: 4, Value: ColorBlack},
+		{Position: 5, Value: ColorBlue},
+		{Position: 6, Value: ColorBlack},
+	})
+	flash.ChannelIDs = []int{1}
+
+	composite := CreateCompositeAnimation(
+		CreateLayer(createSolidAnimation(ColorGreen, 0, 1, 2), BlendReplace),
+		CreateLayer(CreateTrackAnimation([]*Track{flash}, 10), BlendMax),
+		&Layer{Animation: createSolidAnimation(ColorWhite, 2), Blend: BlendMultiply, Opacity: 0.5},
+	)
+
+	values := map[int]Color{7: ColorRed}
+	composite.GetValues(values)
+	if len(values) != 3 || values[0] != ColorGreen || values[1] != ColorGreen {
+		t.Error("expected base values with a clear overlay", values)
+	}
+
+	if values[2] != ColorGreen {
+		t.Error("expected multiplying by white to leave the color", values[2])
+	}
+
+	composite.Start()
+	composite.Update(time.Millisecond*500, values)
+	if values[0] != ColorGreen || values[1] != ColorGreen|ColorBlue {
+		t.Error("expected overlay on channel 1 only", values)
+	}
+
+	composite.Step(values)
+	if values[1] != ColorGreen {
+		t.Error("expected overlay to step", values)
+	}
+
+	composite.Stop()
+	composite.Reset()
+	composite.Update(time.Millisecond*500, values)
+	if values[1] != ColorGreen {
+		t.Error("expected stopped layers to hold", values)
+	}
+}
+
+func TestCompositeAnimationDimming(t *testing.T) {
+	composite := CreateCompositeAnimation(
+		CreateLayer(createSolidAnimation(ColorWhite, 0, 1), BlendReplace),
+		CreateLayer(createSolidAnimation(CreateColor(0x40, 0x40, 0x40), 1), BlendMultiply),
+	)
+
+	values := make(map[int]Color)
+	composite.GetValues(values)
+	if values[0] != ColorWhite || values[1] != CreateColor(0x40, 0x40, 0x40) {
+		t.Error("expected channel 1 dimmed", values)
+	}
+}
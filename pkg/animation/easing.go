@@ -0,0 +1,115 @@
+package animation
+
+import "math"
+
+// Easing maps progress through a segment from 0 - 1 to how far the value has changed from 0 - 1.
+type Easing func(mu float64) float64
+
+// EaseStep holds the previous value until the key frame, then jumps to it.
+func EaseStep(mu float64) float64 {
+	if mu < 1 {
+		return 0
+	}
+
+	return 1
+}
+
+// EaseLinear changes at a constant rate.
+func EaseLinear(mu float64) float64 {
+	return mu
+}
+
+// EaseInQuad starts slow and speeds up.
+func EaseInQuad(mu float64) float64 {
+	return mu * mu
+}
+
+// EaseOutQuad starts fast and slows down.
+func EaseOutQuad(mu float64) float64 {
+	return 1 - (1-mu)*(1-mu)
+}
+
+// EaseInOutQuad is slow at both ends.
+func EaseInOutQuad(mu float64) float64 {
+	if mu < 0.5 {
+		return 2 * mu * mu
+	}
+
+	return 1 - math.Pow(-2*mu+2, 2)/2
+}
+
+// EaseInCubic starts slower and speeds up harder than EaseInQuad.
+func EaseInCubic(mu float64) float64 {
+	return mu * mu * mu
+}
+
+// EaseOutCubic starts faster and slows down harder than EaseOutQuad.
+func EaseOutCubic(mu float64) float64 {
+	return 1 - math.Pow(1-mu, 3)
+}
+
+// EaseInOutCubic is slower at both ends than EaseInOutQuad.
+func EaseInOutCubic(mu float64) float64 {
+	if mu < 0.5 {
+		return 4 * mu * mu * mu
+	}
+
+	return 1 - math.Pow(-2*mu+2, 3)/2
+}
+
+// EaseInOutSine follows half a cosine wave, like the pulse animation.
+func EaseInOutSine(mu float64) float64 {
+	return (1 - math.Cos(mu*math.Pi)) / 2
+}
+
+// CubicBezier creates an easing along the curve from (0, 0) to (1, 1) with control points (x1, y1) and (x2, y2),
+// like CSS's cubic-bezier(). The x values are clamped to 0 - 1 so the curve can't double back.
+func CubicBezier(x1, y1, x2, y2 float64) Easing {
+	x1 = math.Max(0, math.Min(1, x1))
+	x2 = math.Max(0, math.Min(1, x2))
+
+	return func(mu float64) float64 {
+		if mu <= 0 || mu >= 1 {
+			return mu
+		}
+
+		return bezierPoint(bezierParam(mu, x1, x2), y1, y2)
+	}
+}
+
+// bezierPoint gets a 1D cubic bezier's value at t with end points 0 and 1.
+func bezierPoint(t float64, p1 float64, p2 float64) float64 {
+	inv := 1 - t
+
+	return 3*inv*inv*t*p1 + 3*inv*t*t*p2 + t*t*t
+}
+
+// bezierParam finds the t where the curve's x is at x, with Newton's method falling back to bisection.
+func bezierParam(x float64, x1 float64, x2 float64) float64 {
+	t := x
+	for i := 0; i < 8; i++ {
+		err := bezierPoint(t, x1, x2) - x
+		if math.Abs(err) < 1e-6 {
+			return t
+		}
+
+		slope := 3*(1-t)*(1-t)*x1 + 6*(1-t)*t*(x2-x1) + 3*t*t*(1-x2)
+		if math.Abs(slope) < 1e-6 {
+			break
+		}
+		t -= err / slope
+	}
+
+	low, high := 0.0, 1.0
+	t = x
+	for i := 0; i < 30; i++ {
+		if bezierPoint(t, x1, x2) < x {
+			low = t
+		} else {
+			high = t
+		}
+		t = (low + high) / 2
+	}
+
+	return t
+}
@@ -0,0 +1,82 @@
+package animation
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEasingEndPoints(t *testing.T) {
+	easings := map[string]Easing{
+		"step":           EaseStep,
+		"linear":         EaseLinear,
+		"in quad":        EaseInQuad,
+		"out quad":       EaseOutQuad,
+		"in out quad":    EaseInOutQuad,
+		"in cubic":       EaseInCubic,
+		"out cubic":      EaseOutCubic,
+		"in out cubic":   EaseInOutCubic,
+		"in out sine":    EaseInOutSine,
+		"bezier":         CubicBezier(0.25, 0.1, 0.25, 1),
+		"bezier clamped": CubicBezier(-1, 0, 2, 1),
+	}
+
+	for name, ease := range easings {
+		if ease(0) != 0 || ease(1) != 1 {
+			t.Errorf("%s: expected to start at 0 and end at 1, got %f and %f", name, ease(0), ease(1))
+		}
+	}
+}
+
+func TestEasingCurves(t *testing.T) {
+	rows := []struct {
+		name     string
+		ease     Easing
+		mu       float64
+		expected float64
+	}{
+		{"step holds", EaseStep, 0.99, 0},
+		{"in quad", EaseInQuad, 0.5, 0.25},
+		{"out quad", EaseOutQuad, 0.5, 0.75},
+		{"in out quad", EaseInOutQuad, 0.25, 0.125},
+		{"in out cubic", EaseInOutCubic, 0.75, 0.9375},
+		{"in out sine", EaseInOutSine, 0.5, 0.5},
+		{"css ease", CubicBezier(0.25, 0.1, 0.25, 1), 0.5, 0.8024},
+		{"linear bezier", CubicBezier(0.3, 0.3, 0.7, 0.7), 0.4, 0.4},
+	}
+
+	for _, row := range rows {
+		if value := row.ease(row.mu); math.Abs(value-row.expected) > 0.001 {
+			t.Errorf("%s: expected %f, got %f", row.name, row.expected, value)
+		}
+	}
+}
+
+func TestTrackEasing(t *testing.T) {
+	track, _ := CreateTrack(10, false, []KeyFrame{
+		{Position: 0, Value: 0},
+		{Position: 4, Value: 100, Easing: EaseStep},
+		{Position: 8, Value: 0, Easing: EaseInQuad},
+		{Position: 9, Value: 0},
+	})
+
+	expected := []Color{0, 0, 0, 0, 100, 94, 75, 44, 0}
+	for i, value := range expected {
+		track.Seek(i)
+		if track.Value() != value {
+			t.Errorf("expected %d at %d, got %d", value, i, track.Value())
+		}
+	}
+}
+
+func TestTrackEasingOvershootIsClamped(t *testing.T) {
+	track, _ := CreateTrack(10, false, []KeyFrame{
+		{Position: 0, Value: CreateColor(0, 0, 0xF0)},
+		{Position: 4, Value: CreateColor(0, 0, 0xFF), Easing: CubicBezier(0.3, 3, 0.7, 3)},
+		{Position: 9, Value: 0},
+	})
+
+	track.Seek(2)
+	if track.Value() != CreateColor(0, 0, 0xFF) {
+		t.Error("expected overshoot to be clamped", track.Value())
+	}
+}
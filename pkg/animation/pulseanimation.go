@@ -6,32 +6,39 @@ import (
 
 // PulseAnimation loops between 2 values.
 type PulseAnimation struct {
-	value     Color
-	running   bool
-	start     Color
-	end       Color
-	position  time.Duration
-	period    time.Duration
-	channels  []int
-	interFunc byteInterpolation
-	fps       int
+	value         Color
+	running       bool
+	start         Color
+	end           Color
+	position      time.Duration
+	period        time.Duration
+	channels      []int
+	shape         Easing
+	interpolation Interpolation
+	fps           int
 }
 
 // CreatePulseAnimation creates a new pulse animation.
 func CreatePulseAnimation(period time.Duration, start Color, end Color, channels []int, fps int) Animation {
 	return &PulseAnimation{
-		value:     start,
-		running:   false,
-		start:     start,
-		end:       end,
-		period:    period,
-		position:  time.Duration(0),
-		channels:  channels,
-		interFunc: cosinePeakByte,
-		fps:       fps,
+		value:         start,
+		running:       false,
+		start:         start,
+		end:           end,
+		period:        period,
+		position:      time.Duration(0),
+		channels:      channels,
+		shape:         cosinePeak,
+		interpolation: InterpolateRGB,
+		fps:           fps,
 	}
 }
 
+// SetInterpolation sets the color space the pulse blends through.
+func (a *PulseAnimation) SetInterpolation(interpolation Interpolation) {
+	a.interpolation = interpolation
+}
+
 // Reset starts the animation from the beginning.
 func (a *PulseAnimation) Reset() {
 	a.position = time.Duration(0)
@@ -62,7 +69,7 @@ func (a *PulseAnimation) Step(values map[int]Color) {
 
 // GetValues gets the values for each channel.
 func (a *PulseAnimation) GetValues(values map[int]Color) {
-	value := lerpColor(a.start, a.end, float64(a.position)/float64(a.period), a.interFunc)
+	value := InterpolateColors(a.start, a.end, a.shape(float64(a.position)/float64(a.period)), a.interpolation)
 	for _, channel := range a.channels {
 		values[channel] = value
 	}
@@ -116,6 +116,16 @@ func TestPulseAnimationStep(t *testing.T) {
 	}
 }
 
+func TestPulseAnimationInterpolation(t *testing.T) {
+	values := make(map[int]Color, 0)
+	pulse := CreatePulseAnimation(time.Second*4, ColorGreen, ColorRed, []int{0, 2, 4}, 50).(*PulseAnimation)
+	pulse.SetInterpolation(InterpolateHSV)
+
+	pulse.Start()
+	pulse.Update(time.Second, values)
+	assertMapValuesMatch(values, 0xFFFF00, t)
+}
+
 func assertMapValuesMatch(values map[int]Color, expected Color, t *testing.T) {
 	if len(values) != 3 {
 		t.Error("unexpected value count", len(values))
@@ -2,22 +2,27 @@ package animation
 
 import (
 	"errors"
+	"math"
 	"sort"
 )
 
 // Track is a sequence of for playback that will give the value according to keyframes.
 type Track struct {
-	looping    bool
-	ChannelIDs []int
-	position   int
-	length     int
-	keyFrames  []KeyFrame
+	looping         bool
+	ChannelIDs      []int
+	position        int
+	length          int
+	keyFrames       []KeyFrame
+	sourceKeyFrames []KeyFrame
+	interpolation   Interpolation
 }
 
 // KeyFrame is a position and value for a track to interpolate between.
+// Easing shapes the segment leading into the key frame and is linear when nil.
 type KeyFrame struct {
 	Position int
 	Value    Color
+	Easing   Easing
 }
 
 // CreateTrack create a new track.
@@ -106,10 +111,18 @@ func (t *Track) SetKeyFrames(keyFrames []KeyFrame, length int, looping bool) err
 	if err != nil {
 		return err
 	}
+	t.sourceKeyFrames = keyFrames
 
 	return nil
 }
 
+// SetInterpolation sets the color space the key frames are blended through.
+func (t *Track) SetInterpolation(interpolation Interpolation) error {
+	t.interpolation = interpolation
+
+	return t.SetKeyFrames(t.sourceKeyFrames, t.length, t.looping)
+}
+
 // Value gets this track's value at the current position.
 func (t *Track) Value() Color {
 	if len(t.keyFrames) < 2 {
@@ -178,13 +191,16 @@ func (t *Track) normalizeKeyFrames(keyFrames []KeyFrame, length int) ([]KeyFrame
 	if lastFrame.Position != length-1 {
 		endFrameValue := lastFrame.Value
 		if t.looping {
-			endFrameValue = t.lerpFrameValues(lastFrame, keyFrames[0], length-1)
+			endFrameValue = t.lerpFrameValues(lastFrame, firstFrame, length-1)
 		}
 
 		endKeyFrame := KeyFrame{
 			Position: length - 1,
 			Value:    endFrameValue,
 		}
+		if t.looping {
+			endKeyFrame.Easing = firstFrame.Easing
+		}
 
 		keyFrames = append(keyFrames, endKeyFrame)
 	}
@@ -200,6 +216,10 @@ func (t *Track) lerpFrameValues(startFrame KeyFrame, endFrame KeyFrame, position
 
 	normalizedPosition := (position - startFrame.Position + t.length) % t.length
 	progress := float64(normalizedPosition) / float64(duration)
+	if endFrame.Easing != nil {
+		// Curves that overshoot are clamped as the channels can't go past their limits.
+		progress = math.Max(0, math.Min(1, endFrame.Easing(progress)))
+	}
 
-	return lerpColor(startFrame.Value, endFrame.Value, progress, lerpByte)
+	return InterpolateColors(startFrame.Value, endFrame.Value, progress, t.interpolation)
 }
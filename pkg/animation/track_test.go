@@ -119,7 +119,7 @@ func TestTrackSeek(t *testing.T) {
 
 func TestNormalizeShortKeyFrames(t *testing.T) {
 	track, err := CreateTrack(16, true, []KeyFrame{
-		{0, 5},
+		{Position: 0, Value: 5},
 	})
 	if err != nil {
 		t.Error(err)
@@ -157,8 +157,8 @@ func TestNormalizeShortKeyFrames(t *testing.T) {
 
 func TestKeyFramesWithSamePositionRejected(t *testing.T) {
 	_, err := CreateTrack(10, true, []KeyFrame{
-		{5, 5},
-		{5, 10},
+		{Position: 5, Value: 5},
+		{Position: 5, Value: 10},
 	})
 
 	if err == nil {
@@ -202,7 +202,7 @@ func TestPanicIfLerpSameFrame(t *testing.T) {
 		length:   10,
 	}
 
-	track.lerpFrameValues(KeyFrame{5, 0}, KeyFrame{5, 5}, 0)
+	track.lerpFrameValues(KeyFrame{Position: 5, Value: 0}, KeyFrame{Position: 5, Value: 5}, 0)
 }
 
 func TestTrackPanicsWithNonNormalizedKeyframes(t *testing.T) {
@@ -213,7 +213,7 @@ func TestTrackPanicsWithNonNormalizedKeyframes(t *testing.T) {
 	}()
 
 	track := Track{
-		keyFrames: []KeyFrame{{1, 2}},
+		keyFrames: []KeyFrame{{Position: 1, Value: 2}},
 	}
 
 	track.Value()
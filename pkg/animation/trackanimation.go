@@ -80,3 +80,27 @@ func (a *TrackAnimation) forEachTrack(action func(track *Track)) {
 		action(track)
 	}
 }
+
+// ChannelPositions gets the position of the track on each channel.
+func (a *TrackAnimation) ChannelPositions() map[int]int {
+	positions := make(map[int]int)
+	a.forEachTrack(func(track *Track) {
+		for _, trackChan := range track.ChannelIDs {
+			positions[trackChan] = track.GetPosition()
+		}
+	})
+
+	return positions
+}
+
+// SeekChannels moves the tracks on the channels to the positions, wrapping positions past the track's end.
+func (a *TrackAnimation) SeekChannels(positions map[int]int) {
+	a.forEachTrack(func(track *Track) {
+		for _, trackChan := range track.ChannelIDs {
+			if position, ok := positions[trackChan]; ok && track.GetLength() > 0 {
+				track.Seek(position % track.GetLength())
+				return
+			}
+		}
+	})
+}
@@ -100,17 +100,17 @@ func assertTrackPositions(tracks []*Track, t *testing.T, message string, expecte
 
 func createTestTracks() []*Track {
 	looping, _ := CreateTrack(10, true, []KeyFrame{
-		{0, CreateColor(0, 0xFF, 0)},
-		{4, CreateColor(0xFF, 0, 0xFF)},
-		{9, CreateColor(0, 0xFF, 0)},
+		{Position: 0, Value: CreateColor(0, 0xFF, 0)},
+		{Position: 4, Value: CreateColor(0xFF, 0, 0xFF)},
+		{Position: 9, Value: CreateColor(0, 0xFF, 0)},
 	})
 	looping.ChannelIDs = []int{0}
 	nonLooping, _ := CreateTrack(15, false, []KeyFrame{
-		{0, 0},
-		{4, 0},
-		{8, CreateColor(0xFF, 0, 0xFF)},
-		{12, 0},
-		{14, CreateColor(0, 0xFF, 0)},
+		{Position: 0, Value: 0},
+		{Position: 4, Value: 0},
+		{Position: 8, Value: CreateColor(0xFF, 0, 0xFF)},
+		{Position: 12, Value: 0},
+		{Position: 14, Value: CreateColor(0, 0xFF, 0)},
 	})
 	nonLooping.ChannelIDs = []int{1}
 
@@ -0,0 +1,153 @@
+package animation
+
+import "time"
+
+// ChannelSeeker is implemented by animations that can report and restore the position of each channel,
+// so a replacement animation can carry on where the old one was.
+type ChannelSeeker interface {
+	ChannelPositions() map[int]int
+	SeekChannels(positions map[int]int)
+}
+
+// TransitionAnimation cross-fades each channel from one animation to another.
+// Once the fade is done the old animation is dropped and the new one plays on its own.
+type TransitionAnimation struct {
+	from          Animation
+	to            Animation
+	duration      time.Duration
+	elapsed       time.Duration
+	unchanged     map[int]bool
+	fromValues    map[int]Color
+	interpolation Interpolation
+	running       bool
+	fps           int
+}
+
+// CreateTransitionAnimation creates a transition from one animation to another over the duration, fading through
+// the interpolation's color space. Unchanged channels skip the fade and, when both animations are ChannelSeekers,
+// keep their position.
+func CreateTransitionAnimation(from Animation, to Animation, duration time.Duration, unchanged map[int]bool, interpolation Interpolation, fps int) Animation {
+	a := &TransitionAnimation{
+		from:          from,
+		to:            to,
+		duration:      duration,
+		unchanged:     unchanged,
+		fromValues:    make(map[int]Color),
+		interpolation: interpolation,
+		fps:           fps,
+	}
+
+	fromSeeker, fromOk := from.(ChannelSeeker)
+	toSeeker, toOk := to.(ChannelSeeker)
+	if fromOk && toOk && len(unchanged) > 0 {
+		positions := make(map[int]int, len(unchanged))
+		for channel, position := range fromSeeker.ChannelPositions() {
+			if unchanged[channel] {
+				positions[channel] = position
+			}
+		}
+		toSeeker.SeekChannels(positions)
+	}
+
+	if from == nil || duration <= 0 {
+		a.from = nil
+	}
+
+	return a
+}
+
+// Reset resets the new animation, skipping the rest of the fade.
+func (a *TransitionAnimation) Reset() {
+	a.from = nil
+	a.to.Reset()
+}
+
+// Start starts the fade and both animations.
+func (a *TransitionAnimation) Start() {
+	a.running = true
+	a.forEachAnimation(Animation.Start)
+}
+
+// Stop holds the fade and stops both animations.
+func (a *TransitionAnimation) Stop() {
+	a.running = false
+	a.forEachAnimation(Animation.Stop)
+}
+
+// Update advances both animations and the fade, setting the blended channel values in the `values` map.
+func (a *TransitionAnimation) Update(delta time.Duration, values map[int]Color) {
+	a.advance(delta, values, func(animation Animation, animationValues map[int]Color) {
+		animation.Update(delta, animationValues)
+	})
+}
+
+// Step advances both animations and the fade by a single frame.
+func (a *TransitionAnimation) Step(values map[int]Color) {
+	a.advance(time.Second/time.Duration(a.fps), values, func(animation Animation, animationValues map[int]Color) {
+		animation.Step(animationValues)
+	})
+}
+
+// GetValues reads the blended values without advancing.
+func (a *TransitionAnimation) GetValues(values map[int]Color) {
+	a.to.GetValues(values)
+	if a.from == nil {
+		return
+	}
+
+	a.from.GetValues(a.fromValues)
+	a.blend(values)
+}
+
+// ChannelPositions gets the positions of the new animation.
+func (a *TransitionAnimation) ChannelPositions() map[int]int {
+	if seeker, ok := a.to.(ChannelSeeker); ok {
+		return seeker.ChannelPositions()
+	}
+
+	return nil
+}
+
+// SeekChannels seeks the new animation.
+func (a *TransitionAnimation) SeekChannels(positions map[int]int) {
+	if seeker, ok := a.to.(ChannelSeeker); ok {
+		seeker.SeekChannels(positions)
+	}
+}
+
+func (a *TransitionAnimation) advance(delta time.Duration, values map[int]Color, step func(animation Animation, animationValues map[int]Color)) {
+	step(a.to, values)
+	if a.from == nil {
+		return
+	}
+
+	step(a.from, a.fromValues)
+	if a.running {
+		a.elapsed += delta
+	}
+
+	a.blend(values)
+}
+
+func (a *TransitionAnimation) blend(values map[int]Color) {
+	mu := float64(a.elapsed) / float64(a.duration)
+	if mu >= 1 {
+		a.from = nil
+		return
+	}
+
+	for channel, value := range values {
+		if a.unchanged[channel] {
+			continue
+		}
+
+		values[channel] = InterpolateColors(a.fromValues[channel], value, mu, a.interpolation)
+	}
+}
+
+func (a *TransitionAnimation) forEachAnimation(action func(animation Animation)) {
+	if a.from != nil {
+		action(a.from)
+	}
+	action(a.to)
+}
@@ -0,0 +1,113 @@
+package animation
+
+import (
+	"testing"
+	"time"
+)
+
+func createRampAnimation(channels ...int) Animation {
+	tracks := make([]*Track, len(channels))
+	for i, channel := range channels {
+		tracks[i], _ = CreateTrack(10, true, []KeyFrame{
+			{Position: 0, Value: ColorBlack},
+			{Position: 9, Value: ColorWhite},
+		})
+		tracks[i].ChannelIDs = []int{channel}
+	}
+
+	return CreateTrackAnimation(tracks, 10)
+}
+
+func TestTransitionAnimationFades(t *testing.T) {
+	from := createSolidAnimation(ColorRed, 0, 1)
+	from.Start()
+	to := createSolidAnimation(ColorBlue, 0, 1)
+
+	transition := CreateTransitionAnimation(from, to, time.Second, map[int]bool{1: true}, InterpolateRGB, 10)
+	transition.Start()
+
+	values := make(map[int]Color)
+	for i := 0; i < 5; i++ {
+		transition.Step(values)
+	}
+
+	halfway := lerpColor(ColorRed, ColorBlue, 0.5, lerpByte)
+	if values[0] != halfway {
+		t.Errorf("expected %s halfway, got %s", halfway, values[0])
+	}
+
+	if values[1] != ColorBlue {
+		t.Error("expected unchanged channel to switch without fading", values[1])
+	}
+
+	for i := 0; i < 5; i++ {
+		transition.Step(values)
+	}
+
+	if values[0] != ColorBlue {
+		t.Error("expected fade to finish", values[0])
+	}
+
+	if transition.(*TransitionAnimation).from != nil {
+		t.Error("expected old animation to be dropped")
+	}
+}
+
+func TestTransitionAnimationInterpolation(t *testing.T) {
+	from := createSolidAnimation(ColorGreen, 0)
+	from.Start()
+	to := createSolidAnimation(ColorRed, 0)
+
+	transition := CreateTransitionAnimation(from, to, time.Second, nil, InterpolateOKLab, 10)
+	transition.Start()
+
+	values := make(map[int]Color)
+	for i := 0; i < 5; i++ {
+		transition.Step(values)
+	}
+
+	halfway := InterpolateColors(ColorGreen, ColorRed, 0.5, InterpolateOKLab)
+	if values[0] != halfway {
+		t.Errorf("expected %s halfway, got %s", halfway, values[0])
+	}
+
+	if muddy := lerpColor(ColorGreen, ColorRed, 0.5, lerpByte); values[0] == muddy {
+		t.Error("expected the fade not to blend bytes on their own", values[0])
+	}
+}
+
+func TestTransitionAnimationKeepsPhase(t *testing.T) {
+	from := createRampAnimation(0, 1)
+	from.Start()
+
+	values := make(map[int]Color)
+	for i := 0; i < 3; i++ {
+		from.Step(values)
+	}
+
+	to := createRampAnimation(0, 1)
+	transition := CreateTransitionAnimation(from, to, time.Second, map[int]bool{0: true}, InterpolateRGB, 10)
+
+	positions := transition.(ChannelSeeker).ChannelPositions()
+	if positions[0] != 3 {
+		t.Error("expected unchanged channel to keep its position", positions[0])
+	}
+
+	if positions[1] != 0 {
+		t.Error("expected changed channel to start over", positions[1])
+	}
+}
+
+func TestTransitionAnimationWithoutDuration(t *testing.T) {
+	from := createSolidAnimation(ColorRed, 0)
+	to := createSolidAnimation(ColorBlue, 0)
+
+	transition := CreateTransitionAnimation(from, to, 0, nil, InterpolateRGB, 10)
+	transition.Start()
+
+	values := make(map[int]Color)
+	transition.Step(values)
+	if values[0] != ColorBlue {
+		t.Error("expected instant switch", values[0])
+	}
+}
@@ -0,0 +1,146 @@
+package brightnessschedule
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+)
+
+const (
+	AnchorTime    = "time"
+	AnchorSunrise = "sunrise"
+	AnchorSunset  = "sunset"
+)
+
+// Point is a brightness level at a time of day. Offset is from midnight for AnchorTime points and from the sun
+// event for the others, so a point can be 30 minutes before sunset.
+type Point struct {
+	Anchor string
+	Offset time.Duration
+	Level  byte
+}
+
+// Config sets the schedule's points and where the sunrise and sunset are taken.
+type Config struct {
+	Points   []Point
+	Location *geo.Coordinate
+	Clock    common.Clock
+}
+
+// BrightnessSchedule ramps the brightness linearly between its points through the day.
+type BrightnessSchedule struct {
+	points   []Point
+	location *geo.Coordinate
+	clock    common.Clock
+}
+
+type event struct {
+	at    time.Time
+	level byte
+}
+
+func CreateBrightnessSchedule(config *Config) (*BrightnessSchedule, error) {
+	if len(config.Points) == 0 {
+		return nil, errors.New("brightness schedule needs at least one point")
+	}
+
+	for _, p := range config.Points {
+		switch p.Anchor {
+		case AnchorTime:
+			if p.Offset < 0 || p.Offset >= 24*time.Hour {
+				return nil, fmt.Errorf("time of day %s is out of range", p.Offset)
+			}
+		case AnchorSunrise, AnchorSunset:
+			if config.Location == nil {
+				return nil, fmt.Errorf("%s points need a location", p.Anchor)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported anchor '%s'", p.Anchor)
+		}
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = common.SystemClock{}
+	}
+
+	return &BrightnessSchedule{
+		points:   config.Points,
+		location: config.Location,
+		clock:    clock,
+	}, nil
+}
+
+// Brightness gets the scheduled level now.
+func (s *BrightnessSchedule) Brightness() byte {
+	return s.BrightnessAt(s.clock.Now())
+}
+
+// BrightnessAt gets the scheduled level at the time, in the time's location.
+// Points anchored to a sunrise or sunset that doesn't happen, like in a polar summer, are skipped for the day.
+// Full brightness is used when no points are left.
+func (s *BrightnessSchedule) BrightnessAt(t time.Time) byte {
+	events := make([]event, 0, len(s.points)*3)
+	for dayOffset := -1; dayOffset <= 1; dayOffset++ {
+		events = s.appendEvents(events, t.AddDate(0, 0, dayOffset))
+	}
+
+	if len(events) == 0 {
+		return 0xFF
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+
+	next := sort.Search(len(events), func(i int) bool {
+		return events[i].at.After(t)
+	})
+
+	switch {
+	case next == 0:
+		return events[0].level
+	case next == len(events):
+		return events[len(events)-1].level
+	}
+
+	before, after := events[next-1], events[next]
+	mu := float64(t.Sub(before.at)) / float64(after.at.Sub(before.at))
+
+	return byte(math.Round(float64(before.level)*(1-mu) + float64(after.level)*mu))
+}
+
+func (s *BrightnessSchedule) appendEvents(events []event, day time.Time) []event {
+	var sun *geo.SunTimes
+	for _, p := range s.points {
+		var anchor time.Time
+		switch p.Anchor {
+		case AnchorTime:
+			// Set by the wall clock so the times hold across daylight saving changes.
+			at := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, int(p.Offset.Seconds()), 0, day.Location())
+			events = append(events, event{at: at, level: p.Level})
+			continue
+		case AnchorSunrise, AnchorSunset:
+			if sun == nil {
+				sun = s.location.SunTimes(day)
+			}
+			if sun.AlwaysUp || sun.AlwaysDown {
+				continue
+			}
+
+			anchor = sun.Sunrise
+			if p.Anchor == AnchorSunset {
+				anchor = sun.Sunset
+			}
+		}
+
+		events = append(events, event{at: anchor.Add(p.Offset), level: p.Level})
+	}
+
+	return events
+}
@@ -0,0 +1,103 @@
+package brightnessschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+)
+
+var mdt = time.FixedZone("MDT", -6*3600)
+
+func TestTimeOfDaySchedule(t *testing.T) {
+	schedule, err := CreateBrightnessSchedule(&Config{
+		Points: []Point{
+			{Anchor: AnchorTime, Offset: 7 * time.Hour, Level: 0x80},
+			{Anchor: AnchorTime, Offset: 21 * time.Hour, Level: 0x80},
+			{Anchor: AnchorTime, Offset: 23 * time.Hour, Level: 0x10},
+			{Anchor: AnchorTime, Offset: 5 * time.Hour, Level: 0x10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := []struct {
+		hour     int
+		minute   int
+		expected byte
+	}{
+		{12, 0, 0x80},
+		{22, 0, 0x48},
+		{23, 30, 0x10},
+		{2, 0, 0x10},
+		{6, 0, 0x48},
+		{7, 0, 0x80},
+	}
+
+	for _, row := range rows {
+		at := time.Date(2021, 6, 21, row.hour, row.minute, 0, 0, mdt)
+		if level := schedule.BrightnessAt(at); level != row.expected {
+			t.Errorf("expected 0x%02x at %s, got 0x%02x", row.expected, at, level)
+		}
+	}
+}
+
+func TestSunSchedule(t *testing.T) {
+	edmonton := &geo.Coordinate{Latitude: 53.55, Longitude: -113.49}
+	now := time.Date(2021, 6, 21, 12, 0, 0, 0, mdt)
+
+	schedule, err := CreateBrightnessSchedule(&Config{
+		Points: []Point{
+			{Anchor: AnchorSunrise, Offset: 0, Level: 0x10},
+			{Anchor: AnchorSunrise, Offset: time.Hour, Level: 0xFF},
+			{Anchor: AnchorSunset, Offset: -time.Hour, Level: 0xFF},
+			{Anchor: AnchorSunset, Offset: 0, Level: 0x10},
+		},
+		Location: edmonton,
+		Clock:    common.ClockFunc(func() time.Time { return now }),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if level := schedule.Brightness(); level != 0xFF {
+		t.Errorf("expected full brightness at noon, got 0x%02x", level)
+	}
+
+	sun := edmonton.SunTimes(now)
+	if level := schedule.BrightnessAt(sun.Sunrise.Add(30 * time.Minute)); level < 0x80 || level > 0x90 {
+		t.Errorf("expected half way half an hour after sunrise, got 0x%02x", level)
+	}
+
+	if level := schedule.BrightnessAt(sun.Sunset.Add(2 * time.Hour)); level != 0x10 {
+		t.Errorf("expected dim at night, got 0x%02x", level)
+	}
+}
+
+func TestPolarScheduleFallsBack(t *testing.T) {
+	schedule, _ := CreateBrightnessSchedule(&Config{
+		Points:   []Point{{Anchor: AnchorSunset, Offset: 0, Level: 0x10}},
+		Location: &geo.Coordinate{Latitude: 69.65, Longitude: 18.96},
+	})
+
+	if level := schedule.BrightnessAt(time.Date(2021, 6, 21, 12, 0, 0, 0, time.UTC)); level != 0xFF {
+		t.Errorf("expected full brightness without a sunset, got 0x%02x", level)
+	}
+}
+
+func TestCreateBrightnessScheduleErrors(t *testing.T) {
+	configs := []*Config{
+		{},
+		{Points: []Point{{Anchor: "noon", Level: 0x10}}},
+		{Points: []Point{{Anchor: AnchorTime, Offset: 25 * time.Hour}}},
+		{Points: []Point{{Anchor: AnchorSunrise}}},
+	}
+
+	for _, config := range configs {
+		if _, err := CreateBrightnessSchedule(config); err == nil {
+			t.Errorf("expected error for %+v", config)
+		}
+	}
+}
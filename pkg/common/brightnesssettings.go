@@ -0,0 +1,74 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// BrightnessSettings ramps the brightness between points through the day. Each point is "at" a time of day
+// like "21:30", or at "sunrise" or "sunset" at the map's center, moved by "offset_mins".
+type BrightnessSettings struct {
+	Enabled bool                       `json:"enabled"`
+	Points  []*BrightnessPointSettings `json:"points"`
+}
+
+type BrightnessPointSettings struct {
+	At          string `json:"at"`
+	OffsetMins  int    `json:"offset_mins"`
+	Brightness  string `json:"brightness"`
+	timeOfDay   time.Duration
+	levelParsed byte
+}
+
+func (s *BrightnessSettings) Validate(errors map[string]string) {
+	if !s.Enabled {
+		return
+	}
+
+	if len(s.Points) == 0 {
+		errors["BrightnessSchedule.Points"] = "brightness schedule needs at least one point"
+	}
+
+	for i, p := range s.Points {
+		field := fmt.Sprintf("BrightnessSchedule.Points[%d]", i)
+
+		switch p.At {
+		case BrightnessAtSunrise, BrightnessAtSunset:
+			break
+		default:
+			at, err := time.Parse("15:04", p.At)
+			if err != nil {
+				errors[field+".At"] = "expecting \"sunrise\", \"sunset\", or a time like \"21:30\""
+				continue
+			}
+			p.timeOfDay = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+		}
+
+		level, err := ParseByteHexString(p.Brightness)
+		if err != nil {
+			errors[field+".Brightness"] = "Expecting byte hex string 0x00 - 0xFF"
+		}
+		p.levelParsed = level
+	}
+}
+
+// IsSunAnchored checks if the point is relative to the sunrise or sunset.
+func (p *BrightnessPointSettings) IsSunAnchored() bool {
+	return p.At == BrightnessAtSunrise || p.At == BrightnessAtSunset
+}
+
+// Offset gets the point's time from midnight, or from the sun event when sun anchored, once validated.
+func (p *BrightnessPointSettings) Offset() time.Duration {
+	offset := time.Duration(p.OffsetMins) * time.Minute
+	if p.IsSunAnchored() {
+		return offset
+	}
+
+	// Wrap so a time pushed past midnight stays in the day.
+	return ((p.timeOfDay+offset)%(24*time.Hour) + 24*time.Hour) % (24 * time.Hour)
+}
+
+// Level gets the point's brightness once validated.
+func (p *BrightnessPointSettings) Level() byte {
+	return p.levelParsed
+}
@@ -0,0 +1,22 @@
+package common
+
+import "time"
+
+// Clock tells the time so it can be faked in tests and replays.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the wall clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClockFunc adapts a function to a Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
@@ -9,15 +9,18 @@ type ColorThemeStrings struct {
 	LIFR       string `json:"lifr"`
 	Error      string `json:"error"`
 	Brightness string `json:"brightness"`
+	// StaleBrightness scales the color of stations showing an aging report. Defaults to DefaultStaleBrightness.
+	StaleBrightness string `json:"stale_brightness"`
 }
 
 type ColorTheme struct {
-	VFR        animation.Color
-	SVFR       animation.Color
-	IFR        animation.Color
-	LIFR       animation.Color
-	Error      animation.Color
-	Brightness byte
+	VFR             animation.Color
+	SVFR            animation.Color
+	IFR             animation.Color
+	LIFR            animation.Color
+	Error           animation.Color
+	Brightness      byte
+	StaleBrightness byte
 }
 
 func (t *ColorThemeStrings) ParseColors(errors map[string]string) *ColorTheme {
@@ -26,13 +29,22 @@ func (t *ColorThemeStrings) ParseColors(errors map[string]string) *ColorTheme {
 		errors["Color.Brightness"] = "Expecting byte hex string 0x00 - 0xFF"
 	}
 
+	staleBrightness := DefaultStaleBrightness
+	if t.StaleBrightness != "" {
+		staleBrightness, err = ParseByteHexString(t.StaleBrightness)
+		if err != nil {
+			errors["Color.StaleBrightness"] = "Expecting byte hex string 0x00 - 0xFF"
+		}
+	}
+
 	return &ColorTheme{
-		VFR:        t.parseColor(errors, t.VFR, "Color.VFR"),
-		SVFR:       t.parseColor(errors, t.SVFR, "Color.SVFR"),
-		IFR:        t.parseColor(errors, t.IFR, "Color.IFR"),
-		LIFR:       t.parseColor(errors, t.LIFR, "Color.LIFR"),
-		Error:      t.parseColor(errors, t.Error, "Color.Error"),
-		Brightness: brightness,
+		VFR:             t.parseColor(errors, t.VFR, "Color.VFR"),
+		SVFR:            t.parseColor(errors, t.SVFR, "Color.SVFR"),
+		IFR:             t.parseColor(errors, t.IFR, "Color.IFR"),
+		LIFR:            t.parseColor(errors, t.LIFR, "Color.LIFR"),
+		Error:           t.parseColor(errors, t.Error, "Color.Error"),
+		Brightness:      brightness,
+		StaleBrightness: staleBrightness,
 	}
 }
 
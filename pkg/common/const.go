@@ -1,14 +1,56 @@
 package common
 
 const (
-	FlightRuleVFR                = "VFR"
-	FlightRuleSVFR               = "SVFR"
-	FlightRuleMVFR               = "MVFR"
-	FlightRuleIFR                = "IFR"
-	FlightRuleLIFR               = "LIFR"
-	FlightRuleUnknown            = "Unknown"
-	FlightRuleError              = "Error"
-	AviationWeatherMetarStrategy = "AviationWeather"
+	FlightRuleVFR                   = "VFR"
+	FlightRuleSVFR                  = "SVFR"
+	FlightRuleMVFR                  = "MVFR"
+	FlightRuleIFR                   = "IFR"
+	FlightRuleLIFR                  = "LIFR"
+	FlightRuleUnknown               = "Unknown"
+	FlightRuleError                 = "Error"
+	AviationWeatherMetarStrategy    = "AviationWeather"
+	AviationWeatherAPIMetarStrategy = "AviationWeatherAPI"
+	NOAATextMetarStrategy           = "NOAAText"
+	ReplayMetarStrategy             = "Replay"
+	FailoverMetarStrategy           = "Failover"
+	FlightCategoryPresetFAA         = "faa"
+	FlightCategoryPresetCanada      = "canada"
+	DefaultStaleBrightness          = byte(0x40)
+	DefaultStaleAfterMins           = 90
+	DefaultExpireAfterMins          = 180
+	DisplayModeConditions           = "conditions"
+	DisplayModeForecast             = "forecast"
+	DisplayModeCycle                = "cycle"
+	DefaultDisplayCycleSecs         = 20
+	DefaultTransitionMs             = 1500
+	MaxForecastHours                = 30
+	DefaultIssuanceOffsetMins       = 5
+	DefaultActiveMins               = 60
+	DefaultHTTPTimeoutSecs          = 20
+	DefaultHTTPRetryDelaySecs       = 2
+	DefaultRequestChunkSize         = 100
+	DefaultMaxConcurrentRequests    = 4
+	DefaultWindyThresholdKts        = 5
+	DefaultWindBasePeriodKts        = 40
+	DefaultWindMaxKts               = 80
+	DefaultGustStutterBlinks        = 3
+	MaxGustStutterBlinks            = 8
+	DefaultThunderstormOverlayColor = "0xffffff"
+	DefaultSnowOverlayColor         = "0x4070ff"
+	DefaultFreezingOverlayColor     = "0xff40c0"
+	DefaultLEDGamma                 = 2.2
+	MaxLEDGamma                     = 4
+	DefaultLEDChannelOrder          = "GRB"
+	DefaultPowerBudgetMA            = 2000
+	DefaultChannelMA                = 20
+	DefaultIdleMA                   = 1
+	BrightnessAtSunrise             = "sunrise"
+	BrightnessAtSunset              = "sunset"
+	QuietStyleOff                   = "off"
+	QuietStyleHeartbeat             = "heartbeat"
+	QuietWakeOnIFR                  = "ifr"
+	QuietWakeOnLIFR                 = "lifr"
+	QuietWakeOnThunderstorm         = "thunderstorm"
 )
 
 type MapQuitError struct{}
@@ -0,0 +1,26 @@
+package common
+
+type FetchScheduleSettings struct {
+	MinIntervalMins    int `json:"min_interval_mins"`
+	MaxIntervalMins    int `json:"max_interval_mins"`
+	IssuanceOffsetMins int `json:"issuance_offset_mins"`
+	ActiveMins         int `json:"active_mins"`
+}
+
+func (s *FetchScheduleSettings) Validate(errors map[string]string) {
+	if s.MinIntervalMins < 1 {
+		errors["FetchSchedule.MinIntervalMins"] = "min interval must be atleast 1 minute"
+	}
+
+	if s.MaxIntervalMins < s.MinIntervalMins {
+		errors["FetchSchedule.MaxIntervalMins"] = "max interval must be atleast the min interval"
+	}
+
+	if s.IssuanceOffsetMins < 0 || s.IssuanceOffsetMins > 59 {
+		errors["FetchSchedule.IssuanceOffsetMins"] = "issuance offset must be between 0 and 59 minutes"
+	}
+
+	if s.ActiveMins < 0 {
+		errors["FetchSchedule.ActiveMins"] = "active minutes must be positive"
+	}
+}
@@ -12,23 +12,33 @@ const (
 )
 
 func LoadCachedFile(fileName string) ([]byte, error) {
-	initFileCache()
-
-	return ioutil.ReadFile(path.Join(GetAppSettings().CacheDir, fileName))
+	return LoadCachedFileFrom(GetAppSettings().CacheDir, fileName)
 }
 
 func CacheToFile(fileName string, bytes []byte) error {
-	initFileCache()
+	return CacheToFileIn(GetAppSettings().CacheDir, fileName, bytes)
+}
+
+// LoadCachedFileFrom loads a file cached in the directory.
+func LoadCachedFileFrom(cacheDir string, fileName string) ([]byte, error) {
+	initFileCache(cacheDir)
+
+	return ioutil.ReadFile(path.Join(cacheDir, fileName))
+}
+
+// CacheToFileIn caches a file in the directory.
+func CacheToFileIn(cacheDir string, fileName string, bytes []byte) error {
+	initFileCache(cacheDir)
 
-	return ioutil.WriteFile(path.Join(GetAppSettings().CacheDir, fileName), bytes, CacheFilePermission)
+	return ioutil.WriteFile(path.Join(cacheDir, fileName), bytes, CacheFilePermission)
 }
 
-func initFileCache() {
-	if _, err := os.Stat(GetAppSettings().CacheDir); err == nil {
+func initFileCache(cacheDir string) {
+	if _, err := os.Stat(cacheDir); err == nil {
 		return
 	}
 
-	err := os.MkdirAll(GetAppSettings().CacheDir, CacheDirPermission)
+	err := os.MkdirAll(cacheDir, CacheDirPermission)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,44 @@
+package common
+
+import "fmt"
+
+type FlightCategorySettings struct {
+	Preset     string                             `json:"preset"`
+	Thresholds []*FlightCategoryThresholdSettings `json:"thresholds"`
+}
+
+type FlightCategoryThresholdSettings struct {
+	Category     string  `json:"category"`
+	CeilingFt    int     `json:"ceiling_ft"`
+	VisibilitySM float64 `json:"visibility_sm"`
+	Inclusive    bool    `json:"inclusive"`
+}
+
+func (s *FlightCategorySettings) Validate(errors map[string]string) {
+	switch s.Preset {
+	case "":
+	case FlightCategoryPresetFAA:
+	case FlightCategoryPresetCanada:
+		break
+	default:
+		errors["FlightCategory.Preset"] = "invalid flight category preset"
+	}
+
+	for i, t := range s.Thresholds {
+		field := fmt.Sprintf("FlightCategory.Thresholds[%d]", i)
+
+		switch t.Category {
+		case FlightRuleLIFR:
+		case FlightRuleIFR:
+		case FlightRuleMVFR:
+		case FlightRuleSVFR:
+			break
+		default:
+			errors[field] = "invalid flight category"
+		}
+
+		if t.CeilingFt < 0 || t.VisibilitySM < 0 {
+			errors[field] = "ceiling and visibility must be positive"
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package common
+
+import "github.com/ataboo/go-metar-blink/pkg/ledoutput"
+
+// LEDOutputSettings corrects colors for the strip. "channel_order" is the order the strip reads its channels in,
+// like "GRB", with a "W" for RGBW strips. "white_balance" scales the channels down to match a batch of LEDs.
+type LEDOutputSettings struct {
+	Gamma        float64               `json:"gamma"`
+	ChannelOrder string                `json:"channel_order"`
+	WhiteBalance *WhiteBalanceSettings `json:"white_balance"`
+}
+
+type WhiteBalanceSettings struct {
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+}
+
+func (s *LEDOutputSettings) Validate(errors map[string]string) {
+	if s.Gamma == 0 {
+		s.Gamma = DefaultLEDGamma
+	}
+	if s.Gamma < 1 || s.Gamma > MaxLEDGamma {
+		errors["LEDOutput.Gamma"] = "gamma must be between 1 and 4"
+	}
+
+	if s.ChannelOrder == "" {
+		s.ChannelOrder = DefaultLEDChannelOrder
+	}
+	if _, err := ledoutput.ParseChannelOrder(s.ChannelOrder); err != nil {
+		errors["LEDOutput.ChannelOrder"] = err.Error()
+	}
+
+	if s.WhiteBalance == nil {
+		s.WhiteBalance = &WhiteBalanceSettings{}
+	}
+	for _, scale := range []*float64{&s.WhiteBalance.Red, &s.WhiteBalance.Green, &s.WhiteBalance.Blue} {
+		if *scale == 0 {
+			*scale = 1
+		}
+		if *scale < 0 || *scale > 1 {
+			errors["LEDOutput.WhiteBalance"] = "white balance must be between 0 and 1"
+		}
+	}
+}
+
+// PipelineConfig gets the output pipeline's config once validated.
+func (s *LEDOutputSettings) PipelineConfig() *ledoutput.Config {
+	return &ledoutput.Config{
+		Gamma:        s.Gamma,
+		ChannelOrder: s.ChannelOrder,
+		RedScale:     s.WhiteBalance.Red,
+		GreenScale:   s.WhiteBalance.Green,
+		BlueScale:    s.WhiteBalance.Blue,
+	}
+}
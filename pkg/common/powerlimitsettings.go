@@ -0,0 +1,38 @@
+package common
+
+// PowerLimitSettings keeps the strip's estimated current under "budget_ma" by dimming whole frames.
+// Each channel draws its "_ma" at full level and each LED draws "idle_ma" when off.
+type PowerLimitSettings struct {
+	Enabled  bool    `json:"enabled"`
+	BudgetMA float64 `json:"budget_ma"`
+	RedMA    float64 `json:"red_ma"`
+	GreenMA  float64 `json:"green_ma"`
+	BlueMA   float64 `json:"blue_ma"`
+	WhiteMA  float64 `json:"white_ma"`
+	IdleMA   float64 `json:"idle_ma"`
+}
+
+func (s *PowerLimitSettings) Validate(errors map[string]string) {
+	if s.BudgetMA == 0 {
+		s.BudgetMA = DefaultPowerBudgetMA
+	}
+	for _, current := range []*float64{&s.RedMA, &s.GreenMA, &s.BlueMA, &s.WhiteMA} {
+		if *current == 0 {
+			*current = DefaultChannelMA
+		}
+		if *current < 0 {
+			errors["PowerLimit.ChannelMA"] = "channel current must be positive"
+		}
+	}
+	if s.IdleMA == 0 {
+		s.IdleMA = DefaultIdleMA
+	}
+
+	if s.BudgetMA < 0 {
+		errors["PowerLimit.BudgetMA"] = "power budget must be positive"
+	}
+
+	if s.IdleMA < 0 {
+		errors["PowerLimit.IdleMA"] = "idle current must be positive"
+	}
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHoursSettings blanks the map from "start" to "end", or leaves a slow "heartbeat" on "heartbeat_station"
+// (the first station when empty). Reports are still fetched and any of the "wake_on" triggers show the map until
+// they clear.
+type QuietHoursSettings struct {
+	Enabled          bool     `json:"enabled"`
+	Start            string   `json:"start"`
+	End              string   `json:"end"`
+	Style            string   `json:"style"`
+	HeartbeatStation string   `json:"heartbeat_station"`
+	WakeOn           []string `json:"wake_on"`
+	startParsed      time.Duration
+	endParsed        time.Duration
+}
+
+func (s *QuietHoursSettings) Validate(errors map[string]string) {
+	if !s.Enabled {
+		return
+	}
+
+	var startErr, endErr error
+	if s.startParsed, startErr = parseTimeOfDay(s.Start); startErr != nil {
+		errors["QuietHours.Start"] = startErr.Error()
+	}
+	if s.endParsed, endErr = parseTimeOfDay(s.End); endErr != nil {
+		errors["QuietHours.End"] = endErr.Error()
+	}
+	if startErr == nil && endErr == nil && s.startParsed == s.endParsed {
+		errors["QuietHours.End"] = "quiet hours must end at a different time than they start"
+	}
+
+	if s.Style == "" {
+		s.Style = QuietStyleHeartbeat
+	}
+	if s.Style != QuietStyleOff && s.Style != QuietStyleHeartbeat {
+		errors["QuietHours.Style"] = "quiet style must be \"off\" or \"heartbeat\""
+	}
+
+	for _, trigger := range s.WakeOn {
+		switch trigger {
+		case QuietWakeOnIFR, QuietWakeOnLIFR, QuietWakeOnThunderstorm:
+			break
+		default:
+			errors["QuietHours.WakeOn"] = fmt.Sprintf("unsupported wake trigger '%s'", trigger)
+		}
+	}
+}
+
+// StartTime gets the time of day quiet hours start once validated.
+func (s *QuietHoursSettings) StartTime() time.Duration {
+	return s.startParsed
+}
+
+// EndTime gets the time of day quiet hours end once validated.
+func (s *QuietHoursSettings) EndTime() time.Duration {
+	return s.endParsed
+}
+
+// WakesOn checks if the trigger is set.
+func (s *QuietHoursSettings) WakesOn(trigger string) bool {
+	for _, t := range s.WakeOn {
+		if t == trigger {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	at, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expecting a time like \"21:30\"")
+	}
+
+	return time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute, nil
+}
@@ -21,16 +21,47 @@ const (
 var _appSettings *AppSettings
 
 type AppSettings struct {
-	StationIDs       []string           `json:"station_ids"`
-	ClientStrategy   string             `json:"client_strategy"`
-	UpdatePeriodMins int                `json:"update_period_mins"`
-	LoggingDir       string             `json:"logging_dir"`
-	LoggingMethod    string             `json:"logging_method"`
-	LoggingLevel     string             `json:"logging_level"`
-	CacheDir         string             `json:"cache_dir"`
-	Colors           *ColorThemeStrings `json:"colors"`
-	FlashIPOnStart   bool               `json:"flash_ip_on_start"`
-	colorsParsed     *ColorTheme
+	StationIDs            []string                `json:"station_ids"`
+	StationListFile       string                  `json:"station_list_file"`
+	StationDBFile         string                  `json:"station_db_file"`
+	ClientStrategy        string                  `json:"client_strategy"`
+	ClientEndPoint        string                  `json:"client_end_point"`
+	FailoverProviders     []*ProviderSettings     `json:"failover_providers"`
+	RecordDir             string                  `json:"record_dir"`
+	ReplaySpeed           float64                 `json:"replay_speed"`
+	WindyThresholdKts     float64                 `json:"windy_threshold_kts"`
+	WindAnimation         *WindAnimationSettings  `json:"wind_animation"`
+	WeatherOverlays       *WeatherOverlaySettings `json:"weather_overlays"`
+	UpdatePeriodMins      int                     `json:"update_period_mins"`
+	FetchSchedule         *FetchScheduleSettings  `json:"fetch_schedule"`
+	StaleAfterMins        int                     `json:"stale_after_mins"`
+	ExpireAfterMins       int                     `json:"expire_after_mins"`
+	HTTPTimeoutSecs       int                     `json:"http_timeout_secs"`
+	HTTPRetries           int                     `json:"http_retries"`
+	HTTPRetryDelaySecs    int                     `json:"http_retry_delay_secs"`
+	RequestChunkSize      int                     `json:"request_chunk_size"`
+	MaxConcurrentRequests int                     `json:"max_concurrent_requests"`
+	DisplayMode           string                  `json:"display_mode"`
+	ForecastHours         int                     `json:"forecast_hours"`
+	DisplayCycleSecs      int                     `json:"display_cycle_secs"`
+	TransitionMs          int                     `json:"transition_ms"`
+	LoggingDir            string                  `json:"logging_dir"`
+	LoggingMethod         string                  `json:"logging_method"`
+	LoggingLevel          string                  `json:"logging_level"`
+	CacheDir              string                  `json:"cache_dir"`
+	Colors                *ColorThemeStrings      `json:"colors"`
+	LEDOutput             *LEDOutputSettings      `json:"led_output"`
+	PowerLimit            *PowerLimitSettings     `json:"power_limit"`
+	BrightnessSchedule    *BrightnessSettings     `json:"brightness_schedule"`
+	QuietHours            *QuietHoursSettings     `json:"quiet_hours"`
+	FlightCategory        *FlightCategorySettings `json:"flight_category"`
+	FlashIPOnStart        bool                    `json:"flash_ip_on_start"`
+	colorsParsed          *ColorTheme
+}
+
+type ProviderSettings struct {
+	ClientStrategy string `json:"client_strategy"`
+	ClientEndPoint string `json:"client_end_point"`
 }
 
 func (a *AppSettings) GetParsedColors() *ColorTheme {
@@ -58,8 +89,41 @@ func DumpSettingsInfo() {
 	settings := GetAppSettings()
 
 	logger.LogDebug("\tActive Station IDs: %s", strings.Join(settings.StationIDs, ", "))
+	logger.LogDebug("\tStation List File: %s", settings.StationListFile)
+	logger.LogDebug("\tStation DB File: %s", settings.StationDBFile)
 	logger.LogDebug("\tClient Strategy: %s", settings.ClientStrategy)
+	logger.LogDebug("\tClient End Point: %s", settings.ClientEndPoint)
+	for i, p := range settings.FailoverProviders {
+		logger.LogDebug("\tFailover Provider %d: %s %s", i, p.ClientStrategy, p.ClientEndPoint)
+	}
+	logger.LogDebug("\tRecordDir: %s", settings.RecordDir)
+	logger.LogDebug("\tReplaySpeed: %.2f", settings.ReplaySpeed)
+	logger.LogDebug("\tWindyThresholdKts: %.1f", settings.WindyThresholdKts)
+	logger.LogDebug("\tWindAnimation")
+	logger.LogDebug("\t\tBasePeriodKts: %.1f", settings.WindAnimation.BasePeriodKts)
+	logger.LogDebug("\t\tMaxKts: %.1f", settings.WindAnimation.MaxKts)
+	logger.LogDebug("\t\tGustStutterBlinks: %d", settings.WindAnimation.GustStutterBlinks)
+	logger.LogDebug("\tWeatherOverlays")
+	logger.LogDebug("\t\tThunderstorm: %t %s", settings.WeatherOverlays.Thunderstorm.Enabled, settings.WeatherOverlays.Thunderstorm.Color)
+	logger.LogDebug("\t\tSnow: %t %s", settings.WeatherOverlays.Snow.Enabled, settings.WeatherOverlays.Snow.Color)
+	logger.LogDebug("\t\tFreezing: %t %s", settings.WeatherOverlays.Freezing.Enabled, settings.WeatherOverlays.Freezing.Color)
 	logger.LogDebug("\tUpdatePeriodMins: %d", settings.UpdatePeriodMins)
+	logger.LogDebug("\tFetchSchedule")
+	logger.LogDebug("\t\tMinIntervalMins: %d", settings.FetchSchedule.MinIntervalMins)
+	logger.LogDebug("\t\tMaxIntervalMins: %d", settings.FetchSchedule.MaxIntervalMins)
+	logger.LogDebug("\t\tIssuanceOffsetMins: %d", settings.FetchSchedule.IssuanceOffsetMins)
+	logger.LogDebug("\t\tActiveMins: %d", settings.FetchSchedule.ActiveMins)
+	logger.LogDebug("\tStaleAfterMins: %d", settings.StaleAfterMins)
+	logger.LogDebug("\tExpireAfterMins: %d", settings.ExpireAfterMins)
+	logger.LogDebug("\tHTTPTimeoutSecs: %d", settings.HTTPTimeoutSecs)
+	logger.LogDebug("\tHTTPRetries: %d", settings.HTTPRetries)
+	logger.LogDebug("\tHTTPRetryDelaySecs: %d", settings.HTTPRetryDelaySecs)
+	logger.LogDebug("\tRequestChunkSize: %d", settings.RequestChunkSize)
+	logger.LogDebug("\tMaxConcurrentRequests: %d", settings.MaxConcurrentRequests)
+	logger.LogDebug("\tDisplayMode: %s", settings.DisplayMode)
+	logger.LogDebug("\tForecastHours: %d", settings.ForecastHours)
+	logger.LogDebug("\tDisplayCycleSecs: %d", settings.DisplayCycleSecs)
+	logger.LogDebug("\tTransitionMs: %d", settings.TransitionMs)
 	logger.LogDebug("\tLoggingDir: %s", settings.LoggingDir)
 	logger.LogDebug("\tLoggingMethod: %s", settings.LoggingMethod)
 	logger.LogDebug("\tLoggingLevel: %s", settings.LoggingLevel)
@@ -71,6 +135,31 @@ func DumpSettingsInfo() {
 	logger.LogDebug("\t\tLIFR: %s", settings.Colors.LIFR)
 	logger.LogDebug("\t\tError: %s", settings.Colors.Error)
 	logger.LogDebug("\t\tBrightness: %s", settings.Colors.Brightness)
+	logger.LogDebug("\t\tStaleBrightness: %s", settings.Colors.StaleBrightness)
+	logger.LogDebug("\tLEDOutput")
+	logger.LogDebug("\t\tGamma: %.2f", settings.LEDOutput.Gamma)
+	logger.LogDebug("\t\tChannelOrder: %s", settings.LEDOutput.ChannelOrder)
+	logger.LogDebug("\t\tWhiteBalance: R %.2f, G %.2f, B %.2f", settings.LEDOutput.WhiteBalance.Red, settings.LEDOutput.WhiteBalance.Green, settings.LEDOutput.WhiteBalance.Blue)
+	logger.LogDebug("\tPowerLimit")
+	logger.LogDebug("\t\tEnabled: %t", settings.PowerLimit.Enabled)
+	logger.LogDebug("\t\tBudgetMA: %.0f", settings.PowerLimit.BudgetMA)
+	logger.LogDebug("\t\tChannelMA: R %.1f, G %.1f, B %.1f, W %.1f", settings.PowerLimit.RedMA, settings.PowerLimit.GreenMA, settings.PowerLimit.BlueMA, settings.PowerLimit.WhiteMA)
+	logger.LogDebug("\t\tIdleMA: %.1f", settings.PowerLimit.IdleMA)
+	logger.LogDebug("\tBrightnessSchedule")
+	logger.LogDebug("\t\tEnabled: %t", settings.BrightnessSchedule.Enabled)
+	for _, p := range settings.BrightnessSchedule.Points {
+		logger.LogDebug("\t\t%s %+d min: %s", p.At, p.OffsetMins, p.Brightness)
+	}
+	logger.LogDebug("\tQuietHours")
+	logger.LogDebug("\t\tEnabled: %t", settings.QuietHours.Enabled)
+	logger.LogDebug("\t\tFrom %s to %s", settings.QuietHours.Start, settings.QuietHours.End)
+	logger.LogDebug("\t\tStyle: %s %s", settings.QuietHours.Style, settings.QuietHours.HeartbeatStation)
+	logger.LogDebug("\t\tWakeOn: %s", strings.Join(settings.QuietHours.WakeOn, ", "))
+	logger.LogDebug("\tFlightCategory")
+	logger.LogDebug("\t\tPreset: %s", settings.FlightCategory.Preset)
+	for _, t := range settings.FlightCategory.Thresholds {
+		logger.LogDebug("\t\t%s: ceiling %dft, visibility %.2fSM, inclusive %t", t.Category, t.CeilingFt, t.VisibilitySM, t.Inclusive)
+	}
 }
 
 func inTestEnvironment() bool {
@@ -147,15 +236,51 @@ func logErrorsAndPanic(errors map[string]string) {
 }
 
 func validateSettings(settings *AppSettings, errors map[string]string) {
-	switch settings.ClientStrategy {
-	case AviationWeatherMetarStrategy:
-		break
-	default:
-		errors["ClientStrategy"] = "invalid client strategy"
+	validateClientStrategy(settings, settings.ClientStrategy, settings.ClientEndPoint, "ClientStrategy", errors)
+
+	if settings.ClientStrategy == FailoverMetarStrategy {
+		if len(settings.FailoverProviders) == 0 {
+			errors["FailoverProviders"] = "failover strategy needs atleast 1 provider"
+		}
+
+		for i, p := range settings.FailoverProviders {
+			field := fmt.Sprintf("FailoverProviders[%d]", i)
+			if p.ClientStrategy == FailoverMetarStrategy {
+				errors[field] = "failover providers may not be nested"
+				continue
+			}
+
+			validateClientStrategy(settings, p.ClientStrategy, p.ClientEndPoint, field, errors)
+		}
 	}
 
 	settings.colorsParsed = settings.Colors.ParseColors(errors)
 
+	if settings.LEDOutput == nil {
+		settings.LEDOutput = &LEDOutputSettings{}
+	}
+	settings.LEDOutput.Validate(errors)
+
+	if settings.PowerLimit == nil {
+		settings.PowerLimit = &PowerLimitSettings{Enabled: true}
+	}
+	settings.PowerLimit.Validate(errors)
+
+	if settings.BrightnessSchedule == nil {
+		settings.BrightnessSchedule = &BrightnessSettings{}
+	}
+	settings.BrightnessSchedule.Validate(errors)
+
+	if settings.QuietHours == nil {
+		settings.QuietHours = &QuietHoursSettings{}
+	}
+	settings.QuietHours.Validate(errors)
+
+	if settings.FlightCategory == nil {
+		settings.FlightCategory = &FlightCategorySettings{Preset: FlightCategoryPresetFAA}
+	}
+	settings.FlightCategory.Validate(errors)
+
 	switch settings.LoggingMethod {
 	case logger.LoggingMethodStdio:
 	case logger.LoggingMethodMultiFile:
@@ -169,13 +294,155 @@ func validateSettings(settings *AppSettings, errors map[string]string) {
 		errors["LoggingLevel"] = "invalid logging level"
 	}
 
+	if settings.ReplaySpeed < 0 {
+		errors["ReplaySpeed"] = "replay speed must be positive"
+	}
+
+	if settings.WindyThresholdKts == 0 {
+		settings.WindyThresholdKts = DefaultWindyThresholdKts
+	}
+	if settings.WindyThresholdKts < 0 {
+		errors["WindyThresholdKts"] = "windy threshold must be positive"
+	}
+
+	if settings.WindAnimation == nil {
+		settings.WindAnimation = &WindAnimationSettings{}
+	}
+	settings.WindAnimation.Validate(settings.WindyThresholdKts, errors)
+
+	if settings.WeatherOverlays == nil {
+		settings.WeatherOverlays = &WeatherOverlaySettings{}
+	}
+	settings.WeatherOverlays.Validate(errors)
+
 	if settings.UpdatePeriodMins < 1 {
 		errors["UpdatePeriodMins"] = "update period must be atleast 1 minute"
 	}
 
+	if settings.FetchSchedule == nil {
+		// Poll on a fixed period when no schedule is set.
+		settings.FetchSchedule = &FetchScheduleSettings{
+			MinIntervalMins:    settings.UpdatePeriodMins,
+			MaxIntervalMins:    settings.UpdatePeriodMins,
+			IssuanceOffsetMins: DefaultIssuanceOffsetMins,
+			ActiveMins:         DefaultActiveMins,
+		}
+	}
+	settings.FetchSchedule.Validate(errors)
+
+	if settings.StaleAfterMins == 0 {
+		settings.StaleAfterMins = DefaultStaleAfterMins
+	}
+	if settings.ExpireAfterMins == 0 {
+		settings.ExpireAfterMins = DefaultExpireAfterMins
+	}
+	if settings.StaleAfterMins < 1 {
+		errors["StaleAfterMins"] = "stale after must be atleast 1 minute"
+	}
+	if settings.ExpireAfterMins < settings.StaleAfterMins {
+		errors["ExpireAfterMins"] = "expire after must be atleast the stale after"
+	}
+
+	validateHTTPSettings(settings, errors)
+
+	validateDisplayMode(settings, errors)
+
+	if settings.StationListFile != "" {
+		if _, err := os.Stat(settings.StationListFile); err != nil {
+			errors["StationListFile"] = "failed to find station list file"
+		}
+	}
+
+	if settings.StationDBFile != "" {
+		if _, err := os.Stat(settings.StationDBFile); err != nil {
+			errors["StationDBFile"] = "failed to find station db file"
+		}
+	}
+
 	validateStationIds(errors)
 }
 
+func validateHTTPSettings(settings *AppSettings, errors map[string]string) {
+	if settings.HTTPTimeoutSecs == 0 {
+		settings.HTTPTimeoutSecs = DefaultHTTPTimeoutSecs
+	}
+	if settings.HTTPTimeoutSecs < 1 {
+		errors["HTTPTimeoutSecs"] = "http timeout must be atleast 1 second"
+	}
+
+	if settings.HTTPRetries < 0 {
+		errors["HTTPRetries"] = "http retries must be positive"
+	}
+
+	if settings.HTTPRetryDelaySecs == 0 {
+		settings.HTTPRetryDelaySecs = DefaultHTTPRetryDelaySecs
+	}
+	if settings.HTTPRetryDelaySecs < 1 {
+		errors["HTTPRetryDelaySecs"] = "http retry delay must be atleast 1 second"
+	}
+
+	if settings.RequestChunkSize == 0 {
+		settings.RequestChunkSize = DefaultRequestChunkSize
+	}
+	if settings.RequestChunkSize < 1 {
+		errors["RequestChunkSize"] = "request chunk size must be atleast 1 station"
+	}
+
+	if settings.MaxConcurrentRequests == 0 {
+		settings.MaxConcurrentRequests = DefaultMaxConcurrentRequests
+	}
+	if settings.MaxConcurrentRequests < 1 {
+		errors["MaxConcurrentRequests"] = "max concurrent requests must be atleast 1"
+	}
+}
+
+func validateDisplayMode(settings *AppSettings, errors map[string]string) {
+	switch settings.DisplayMode {
+	case "":
+		settings.DisplayMode = DisplayModeConditions
+	case DisplayModeConditions:
+	case DisplayModeForecast:
+	case DisplayModeCycle:
+		break
+	default:
+		errors["DisplayMode"] = "invalid display mode"
+	}
+
+	if settings.ForecastHours < 0 || settings.ForecastHours > MaxForecastHours {
+		errors["ForecastHours"] = fmt.Sprintf("forecast hours must be between 0 and %d", MaxForecastHours)
+	}
+
+	if settings.DisplayCycleSecs == 0 {
+		settings.DisplayCycleSecs = DefaultDisplayCycleSecs
+	}
+	if settings.DisplayCycleSecs < 1 {
+		errors["DisplayCycleSecs"] = "display cycle must be atleast 1 second"
+	}
+
+	if settings.TransitionMs == 0 {
+		settings.TransitionMs = DefaultTransitionMs
+	}
+	if settings.TransitionMs < 1 {
+		errors["TransitionMs"] = "transition must be atleast 1 ms"
+	}
+}
+
+func validateClientStrategy(settings *AppSettings, strategy string, endPoint string, field string, errors map[string]string) {
+	switch strategy {
+	case AviationWeatherMetarStrategy:
+	case AviationWeatherAPIMetarStrategy:
+	case NOAATextMetarStrategy:
+	case FailoverMetarStrategy:
+		break
+	case ReplayMetarStrategy:
+		if endPoint == "" && settings.RecordDir == "" {
+			errors[field] = "replay strategy needs a client_end_point or record_dir"
+		}
+	default:
+		errors[field] = "invalid client strategy"
+	}
+}
+
 func validateStationIds(errors map[string]string) {
 	keyMap := make(map[string]bool)
 	for _, id := range _appSettings.StationIDs {
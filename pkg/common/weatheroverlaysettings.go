@@ -0,0 +1,46 @@
+package common
+
+import "github.com/ataboo/go-metar-blink/pkg/animation"
+
+// WeatherOverlaySettings sets the overlays drawn over stations reporting thunderstorms, snow, or freezing precipitation.
+// Overlays that aren't set are enabled with their default color.
+type WeatherOverlaySettings struct {
+	Thunderstorm *OverlaySettings `json:"thunderstorm"`
+	Snow         *OverlaySettings `json:"snow"`
+	Freezing     *OverlaySettings `json:"freezing"`
+}
+
+type OverlaySettings struct {
+	Enabled     bool   `json:"enabled"`
+	Color       string `json:"color"`
+	colorParsed animation.Color
+}
+
+func (s *WeatherOverlaySettings) Validate(errors map[string]string) {
+	s.Thunderstorm = validateOverlay(s.Thunderstorm, DefaultThunderstormOverlayColor, "WeatherOverlays.Thunderstorm", errors)
+	s.Snow = validateOverlay(s.Snow, DefaultSnowOverlayColor, "WeatherOverlays.Snow", errors)
+	s.Freezing = validateOverlay(s.Freezing, DefaultFreezingOverlayColor, "WeatherOverlays.Freezing", errors)
+}
+
+// ParsedColor gets the overlay's color once validated.
+func (s *OverlaySettings) ParsedColor() animation.Color {
+	return s.colorParsed
+}
+
+func validateOverlay(overlay *OverlaySettings, defaultColor string, field string, errors map[string]string) *OverlaySettings {
+	if overlay == nil {
+		overlay = &OverlaySettings{Enabled: true}
+	}
+
+	if overlay.Color == "" {
+		overlay.Color = defaultColor
+	}
+
+	color, err := ParseColorHexString(overlay.Color)
+	if err != nil || color > 0xFFFFFF {
+		errors[field+".Color"] = "Expecting RGB uint32 hex string 0x0 - 0xFFFFFF"
+	}
+	overlay.colorParsed = color
+
+	return overlay
+}
@@ -0,0 +1,33 @@
+package common
+
+// WindAnimationSettings tunes how wind is shown. Stations blink once a second at "base_period_kts", faster up to
+// "max_kts", and gusting stations stutter "gust_stutter_blinks" times instead of blinking once.
+type WindAnimationSettings struct {
+	BasePeriodKts     float64 `json:"base_period_kts"`
+	MaxKts            float64 `json:"max_kts"`
+	GustStutterBlinks int     `json:"gust_stutter_blinks"`
+}
+
+func (s *WindAnimationSettings) Validate(windyThresholdKts float64, errors map[string]string) {
+	if s.BasePeriodKts == 0 {
+		s.BasePeriodKts = DefaultWindBasePeriodKts
+	}
+	if s.MaxKts == 0 {
+		s.MaxKts = DefaultWindMaxKts
+	}
+	if s.GustStutterBlinks == 0 {
+		s.GustStutterBlinks = DefaultGustStutterBlinks
+	}
+
+	if s.BasePeriodKts < 0 {
+		errors["WindAnimation.BasePeriodKts"] = "base period speed must be positive"
+	}
+
+	if s.MaxKts <= windyThresholdKts {
+		errors["WindAnimation.MaxKts"] = "max speed must be more than the windy threshold"
+	}
+
+	if s.GustStutterBlinks < 1 || s.GustStutterBlinks > MaxGustStutterBlinks {
+		errors["WindAnimation.GustStutterBlinks"] = "gust stutter blinks must be between 1 and 8"
+	}
+}
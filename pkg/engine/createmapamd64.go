@@ -3,12 +3,13 @@
 package engine
 
 import (
+	"github.com/ataboo/go-metar-blink/pkg/ledoutput"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 	"github.com/ataboo/go-metar-blink/pkg/virtualmap"
 )
 
-func createMap(stations map[string]*stationrepo.Station, brightness byte) (MetarMap, error) {
+func createMap(stations map[string]*stationrepo.Station, pipeline *ledoutput.Pipeline) (MetarMap, error) {
 	logger.LogInfo("Building virtual map on AMD64")
-	return virtualmap.CreateVirtualMap(stations, brightness)
+	return virtualmap.CreateVirtualMap(stations, pipeline)
 }
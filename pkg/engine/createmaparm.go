@@ -3,12 +3,13 @@
 package engine
 
 import (
+	"github.com/ataboo/go-metar-blink/pkg/ledoutput"
 	"github.com/ataboo/go-metar-blink/pkg/lightsmap"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 )
 
-func createMap(stations map[string]*stationrepo.Station, brightness byte) (MetarMap, error) {
+func createMap(stations map[string]*stationrepo.Station, pipeline *ledoutput.Pipeline) (MetarMap, error) {
 	logger.LogInfo("Building light map on arm")
-	return lightsmap.CreateLightMap(stations, brightness)
+	return lightsmap.CreateLightMap(stations, pipeline)
 }
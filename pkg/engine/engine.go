@@ -1,72 +1,179 @@
 package engine
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/ataboo/go-metar-blink/pkg/animation"
+	"github.com/ataboo/go-metar-blink/pkg/brightnessschedule"
 	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/enginemode"
+	"github.com/ataboo/go-metar-blink/pkg/fetchscheduler"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+	"github.com/ataboo/go-metar-blink/pkg/ledoutput"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/metaranimation"
+	"github.com/ataboo/go-metar-blink/pkg/quiethours"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 )
 
+// ScheduleUpdatePeriod is how often the scheduled brightness and quiet hours are applied.
+const ScheduleUpdatePeriod = 5 * time.Second
+
 type MetarMap interface {
 	Update() error
 	Dispose()
 }
 
 type Engine struct {
-	repo          *stationrepo.StationRepo
-	stations      map[string]*stationrepo.Station
-	frameTicker   *time.Ticker
-	fetchTicker   *time.Ticker
-	lastFrame     time.Time
-	animation     animation.Animation
-	quitChan      chan int
-	metarMap      MetarMap
-	updatePeriod  time.Duration
-	fps           int
-	lock          sync.Mutex
-	colorMap      map[int]animation.Color
-	doneSubs      []chan int
-	animFactory   *metaranimation.MetarAnimationFactory
-	blinkIPActive bool
+	repo           *stationrepo.StationRepo
+	stations       map[string]*stationrepo.Station
+	frameTicker    *time.Ticker
+	fetchTimer     *time.Timer
+	scheduler      *fetchscheduler.FetchScheduler
+	lastFrame      time.Time
+	modes          *enginemode.Machine
+	animation      animation.Animation
+	channelStates  map[int]string
+	transition     time.Duration
+	quitChan       chan int
+	loopDone       chan int
+	metarMap       MetarMap
+	fps            int
+	lock           sync.Mutex
+	fetchLock      sync.Mutex
+	colorMap       map[int]animation.Color
+	frame          map[int]animation.Color
+	limiter        *ledoutput.PowerLimiter
+	powerLimited   bool
+	pipeline       *ledoutput.Pipeline
+	brightness     *brightnessschedule.BrightnessSchedule
+	brightnessHeld bool
+	heartbeat      int
+	scheduleTicker *time.Ticker
+	doneSubs       []chan int
+	animFactory    *metaranimation.MetarAnimationFactory
+	blinkIPActive  bool
+	displayMode    string
+	forecastOffset time.Duration
+	cyclePeriod    time.Duration
+	cycleTicker    *time.Ticker
+	showForecast   bool
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
 func CreateEngine(repo *stationrepo.StationRepo, settings *common.AppSettings) (*Engine, error) {
-	stations, err := repo.LoadStations()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stations, err := repo.LoadStations(ctx)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
 	parsedColors := settings.GetParsedColors()
 
 	theme := metaranimation.ColorTheme{
-		Error:      parsedColors.Error,
-		IFR:        parsedColors.IFR,
-		LIFR:       parsedColors.LIFR,
-		VFR:        parsedColors.VFR,
-		SVFR:       parsedColors.SVFR,
-		Brightness: parsedColors.Brightness,
+		Error:           parsedColors.Error,
+		IFR:             parsedColors.IFR,
+		LIFR:            parsedColors.LIFR,
+		VFR:             parsedColors.VFR,
+		SVFR:            parsedColors.SVFR,
+		Brightness:      parsedColors.Brightness,
+		StaleBrightness: parsedColors.StaleBrightness,
+	}
+
+	wind := metaranimation.WindConfig{
+		WindyThresholdKts: settings.WindyThresholdKts,
+		BasePeriodKts:     settings.WindAnimation.BasePeriodKts,
+		MaxKts:            settings.WindAnimation.MaxKts,
+		GustStutterBlinks: settings.WindAnimation.GustStutterBlinks,
+	}
+
+	overlays := settings.WeatherOverlays
+	overlayConfig := metaranimation.OverlayConfig{
+		Thunderstorm: metaranimation.WeatherOverlay{Enabled: overlays.Thunderstorm.Enabled, Color: overlays.Thunderstorm.ParsedColor()},
+		Snow:         metaranimation.WeatherOverlay{Enabled: overlays.Snow.Enabled, Color: overlays.Snow.ParsedColor()},
+		Freezing:     metaranimation.WeatherOverlay{Enabled: overlays.Freezing.Enabled, Color: overlays.Freezing.ParsedColor()},
 	}
 
 	e := &Engine{
-		repo:          repo,
-		stations:      stations,
-		quitChan:      make(chan int),
-		updatePeriod:  time.Duration(settings.UpdatePeriodMins) * time.Minute,
-		fps:           50,
-		lock:          sync.Mutex{},
-		colorMap:      make(map[int]animation.Color),
-		doneSubs:      make([]chan int, 0),
-		animFactory:   metaranimation.CreateMetarAnimationFactory(&theme),
-		blinkIPActive: settings.FlashIPOnStart,
-	}
-
-	mMap, err := createMap(stations, theme.Brightness)
+		repo:     repo,
+		stations: stations,
+		quitChan: make(chan int),
+		loopDone: make(chan int),
+		scheduler: fetchscheduler.CreateFetchScheduler(&fetchscheduler.Config{
+			MinInterval:    time.Duration(settings.FetchSchedule.MinIntervalMins) * time.Minute,
+			MaxInterval:    time.Duration(settings.FetchSchedule.MaxIntervalMins) * time.Minute,
+			IssuanceOffset: time.Duration(settings.FetchSchedule.IssuanceOffsetMins) * time.Minute,
+			ActiveFor:      time.Duration(settings.FetchSchedule.ActiveMins) * time.Minute,
+		}),
+		fps:            50,
+		lock:           sync.Mutex{},
+		colorMap:       make(map[int]animation.Color),
+		frame:          make(map[int]animation.Color),
+		doneSubs:       make([]chan int, 0),
+		animFactory:    metaranimation.CreateMetarAnimationFactory(&theme, &wind, &overlayConfig),
+		blinkIPActive:  settings.FlashIPOnStart,
+		displayMode:    settings.DisplayMode,
+		forecastOffset: time.Duration(settings.ForecastHours) * time.Hour,
+		cyclePeriod:    time.Duration(settings.DisplayCycleSecs) * time.Second,
+		transition:     time.Duration(settings.TransitionMs) * time.Millisecond,
+		showForecast:   settings.DisplayMode == common.DisplayModeForecast,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	pipeline, err := ledoutput.CreatePipeline(settings.LEDOutput.PipelineConfig())
+	if err != nil {
+		logger.LogError("failed to create led output: %s", err)
+		cancel()
+		return nil, err
+	}
+
+	pipeline.SetBrightness(theme.Brightness)
+	e.pipeline = pipeline
+
+	if settings.BrightnessSchedule.Enabled {
+		e.brightness, err = createBrightnessSchedule(settings.BrightnessSchedule, stations)
+		if err != nil {
+			logger.LogError("failed to create brightness schedule: %s", err)
+			cancel()
+			return nil, err
+		}
+		e.applyScheduledBrightness()
+	}
+
+	power := settings.PowerLimit
+	budgetMA := power.BudgetMA
+	if !power.Enabled {
+		// Keep estimating the draw for diagnostics without limiting.
+		budgetMA = math.MaxFloat64
+	}
+	e.limiter = ledoutput.CreatePowerLimiter(&ledoutput.PowerConfig{
+		BudgetMA: budgetMA,
+		RedMA:    power.RedMA,
+		GreenMA:  power.GreenMA,
+		BlueMA:   power.BlueMA,
+		WhiteMA:  power.WhiteMA,
+		IdleMA:   power.IdleMA,
+	}, pipeline)
+
+	var quiet *quiethours.QuietHours
+	if settings.QuietHours.Enabled {
+		quiet = createQuietHours(settings.QuietHours)
+		e.heartbeat = heartbeatChannel(settings.QuietHours, stations)
+	}
+	e.modes = enginemode.CreateMachine(quiet)
+
+	mMap, err := createMap(stations, pipeline)
 	if err != nil {
 		logger.LogError("failed to init map: %s", err)
+		cancel()
 		return nil, err
 	}
 
@@ -76,15 +183,24 @@ func CreateEngine(repo *stationrepo.StationRepo, settings *common.AppSettings) (
 }
 
 func (e *Engine) Start() error {
-	logger.LogInfo("started loading animation")
-	e.animation = e.animFactory.LoadingAnimation(len(e.stations))
-	e.animation.Start()
+	e.lock.Lock()
+	e.updateMode(false)
 	e.frameTicker = time.NewTicker(time.Second / time.Duration(e.fps))
-	e.fetchTicker = time.NewTicker(e.updatePeriod)
+	e.fetchTimer = time.NewTimer(e.scheduler.NextDelay())
+	e.cycleTicker = time.NewTicker(e.cyclePeriod)
+	e.scheduleTicker = time.NewTicker(ScheduleUpdatePeriod)
 
+	fetchNow := true
 	if e.blinkIPActive {
-		e.startIPAddressBlink()
-	} else {
+		if err := e.startIPAddressBlink(); err != nil {
+			logger.LogError("failed to blink ip address: %s", err)
+		} else {
+			fetchNow = false
+		}
+	}
+	e.lock.Unlock()
+
+	if fetchNow {
 		go e.fetchRoutine()
 	}
 
@@ -93,6 +209,7 @@ func (e *Engine) Start() error {
 	return nil
 }
 
+// startIPAddressBlink blinks the IP address in morse code until the first reports are loaded. The lock must be held.
 func (e *Engine) startIPAddressBlink() error {
 	ip, err := common.GetLocalIP()
 	if err != nil {
@@ -109,8 +226,38 @@ func (e *Engine) startIPAddressBlink() error {
 		track.ChannelIDs[i] = i
 	}
 
-	e.animation = animation.CreateTrackAnimation([]*animation.Track{track}, 50)
-	e.animation.Start()
+	logger.LogInfo("mode changed from %s to %s", e.modes.Mode(), enginemode.ModeIPAddress)
+	e.modes.ShowIPAddress()
+	e.fadeToAnimation(animation.CreateTrackAnimation([]*animation.Track{track}, e.fps), nil)
+
+	return nil
+}
+
+// SetDisplayMode switches between showing conditions, forecasts, or cycling between the two.
+func (e *Engine) SetDisplayMode(mode string) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	switch mode {
+	case common.DisplayModeConditions:
+		e.showForecast = false
+	case common.DisplayModeForecast:
+		e.showForecast = true
+	case common.DisplayModeCycle:
+		break
+	default:
+		return fmt.Errorf("unsupported display mode '%s'", mode)
+	}
+
+	needsForecasts := e.displayMode == common.DisplayModeConditions && mode != common.DisplayModeConditions
+	e.displayMode = mode
+	logger.LogInfo("display mode set to %s", mode)
+
+	if needsForecasts {
+		go e.fetchRoutine()
+	} else {
+		e.updateMode(true)
+	}
 
 	return nil
 }
@@ -122,11 +269,27 @@ func (e *Engine) DoneSubscribe() chan int {
 	return newChan
 }
 
+// Dispose cancels any fetch in progress, stops the main loop, and closes the map.
 func (e *Engine) Dispose() {
+	e.cancel()
+	close(e.quitChan)
+
+	if e.frameTicker != nil {
+		e.frameTicker.Stop()
+		e.fetchTimer.Stop()
+		e.cycleTicker.Stop()
+		e.scheduleTicker.Stop()
+
+		// Don't close the map under a frame in progress.
+		<-e.loopDone
+	}
+
 	e.metarMap.Dispose()
 }
 
 func (e *Engine) mainLoop() {
+	defer close(e.loopDone)
+
 	running := true
 	for running {
 		select {
@@ -135,8 +298,20 @@ func (e *Engine) mainLoop() {
 			running = e.updateFrame(currentTime)
 			e.lock.Unlock()
 
-		case <-e.fetchTicker.C:
+		case <-e.fetchTimer.C:
 			go e.fetchRoutine()
+		case <-e.scheduleTicker.C:
+			e.lock.Lock()
+			e.applyScheduledBrightness()
+			e.updateMode(false)
+			e.lock.Unlock()
+		case <-e.cycleTicker.C:
+			e.lock.Lock()
+			if e.displayMode == common.DisplayModeCycle && e.modes.Mode() == enginemode.ModeDisplay {
+				e.showForecast = !e.showForecast
+				e.startDisplayAnimation()
+			}
+			e.lock.Unlock()
 		case <-e.quitChan:
 			running = false
 			break
@@ -160,8 +335,14 @@ func (e *Engine) mainLoop() {
 func (e *Engine) updateFrame(currentTime time.Time) bool {
 	e.animation.Step(e.colorMap)
 
+	// Limit a copy as animations don't always rewrite every channel.
+	for channel, c := range e.colorMap {
+		e.frame[channel] = c
+	}
+	e.limitPower()
+
 	for _, s := range e.stations {
-		s.Color = e.colorMap[s.Ordinal]
+		s.Color = e.frame[s.Ordinal]
 	}
 	e.lastFrame = currentTime
 
@@ -178,11 +359,229 @@ func (e *Engine) updateFrame(currentTime time.Time) bool {
 	return true
 }
 
+// limitPower dims the frame when it's over the power budget, logging when limiting starts and stops.
+func (e *Engine) limitPower() {
+	limited := e.limiter.Limit(e.frame) < 1
+	if limited == e.powerLimited {
+		return
+	}
+	e.powerLimited = limited
+
+	draw := e.limiter.Draw()
+	if limited {
+		logger.LogDebug("limiting led power: %.0f mA requested, scaled to %.0f mA", draw.RequestedMA, draw.LimitedMA)
+	} else {
+		logger.LogDebug("led power back under budget at %.0f mA", draw.RequestedMA)
+	}
+}
+
+// SetBrightness sets the brightness now, holding it over the schedule until ResumeBrightnessSchedule.
+func (e *Engine) SetBrightness(level byte) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.brightnessHeld = true
+	e.pipeline.SetBrightness(level)
+	logger.LogInfo("brightness set to 0x%02x", level)
+}
+
+// ResumeBrightnessSchedule goes back to the scheduled brightness after SetBrightness.
+func (e *Engine) ResumeBrightnessSchedule() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.brightnessHeld = false
+	e.applyScheduledBrightness()
+}
+
+// applyScheduledBrightness sets the brightness from the schedule unless it's held. The lock must be held.
+func (e *Engine) applyScheduledBrightness() {
+	if e.brightness == nil || e.brightnessHeld {
+		return
+	}
+
+	e.pipeline.SetBrightness(e.brightness.Brightness())
+}
+
+// PowerDraw gets the estimated current of the last frame for diagnostics.
+func (e *Engine) PowerDraw() ledoutput.PowerDraw {
+	return e.limiter.Draw()
+}
+
+// fetchRoutine updates a copy of the stations so frames aren't held up, then shows it.
+// Only one fetch runs at a time.
 func (e *Engine) fetchRoutine() {
-	e.repo.UpdateReports(e.stations)
+	e.fetchLock.Lock()
+	defer e.fetchLock.Unlock()
+
 	e.lock.Lock()
-	e.animation = e.animFactory.ConditionsAnimation(e.stations)
-	e.animation.Start()
-	logger.LogInfo("updated conditions animation")
+	stations := copyStations(e.stations)
+	displayMode := e.displayMode
 	e.lock.Unlock()
+
+	if err := e.repo.UpdateReports(e.ctx, stations); err != nil {
+		if e.ctx.Err() != nil {
+			logger.LogInfo("fetch cancelled")
+			return
+		}
+
+		logger.LogWarn("showing last good reports: %s", err)
+	}
+
+	if displayMode != common.DisplayModeConditions {
+		if err := e.repo.UpdateForecasts(e.ctx, stations, e.forecastOffset); err != nil {
+			logger.LogWarn("failed to update forecasts: %s", err)
+		}
+	}
+
+	e.scheduler.Observe(stations)
+
+	e.lock.Lock()
+	for id, s := range stations {
+		// Keep the shown color, which the frames own.
+		s.Color = e.stations[id].Color
+		*e.stations[id] = *s
+	}
+	if e.modes.ObserveReports(e.stations) {
+		if reason := e.modes.WakeReason(); reason != "" {
+			logger.LogInfo("waking from quiet hours: %s", reason)
+		} else {
+			logger.LogInfo("nothing left to wake quiet hours")
+		}
+	}
+	e.updateMode(true)
+	e.scheduleNextFetch()
+	e.lock.Unlock()
+}
+
+// updateMode switches to the next mode's animation when the mode changes. With refresh, the display animation
+// is rebuilt even when already displaying to show new reports or display modes. The lock must be held.
+func (e *Engine) updateMode(refresh bool) {
+	previous := e.modes.Mode()
+	next, start := e.modes.Update(refresh)
+	if !start {
+		return
+	}
+
+	if next != previous {
+		logger.LogInfo("mode changed from %s to %s", previous, next)
+	}
+
+	switch next {
+	case enginemode.ModeLoading:
+		e.fadeToAnimation(e.animFactory.LoadingAnimation(len(e.stations)), nil)
+	case enginemode.ModeQuiet:
+		e.fadeToAnimation(e.animFactory.QuietAnimation(len(e.stations), e.heartbeat), nil)
+	case enginemode.ModeDisplay:
+		e.startDisplayAnimation()
+	}
+}
+
+// scheduleNextFetch resets the fetch timer to the scheduler's next fetch. The lock must be held.
+func (e *Engine) scheduleNextFetch() {
+	delay := e.scheduler.NextDelay()
+	if !e.fetchTimer.Stop() {
+		select {
+		case <-e.fetchTimer.C:
+		default:
+		}
+	}
+	e.fetchTimer.Reset(delay)
+
+	logger.LogInfo("next fetch in %.1f min", delay.Minutes())
+}
+
+// startDisplayAnimation fades to the conditions or forecast animation. The lock must be held.
+func (e *Engine) startDisplayAnimation() {
+	if e.showForecast {
+		e.fadeToAnimation(e.animFactory.ForecastAnimation(e.stations), e.animFactory.ForecastStates(e.stations))
+		logger.LogInfo("updated forecast animation")
+	} else {
+		e.fadeToAnimation(e.animFactory.ConditionsAnimation(e.stations), e.animFactory.ConditionsStates(e.stations))
+		logger.LogInfo("updated conditions animation")
+	}
+}
+
+// fadeToAnimation cross-fades from the current animation to the next. Channels with the same state as the
+// current animation switch over without fading or losing their place. The lock must be held.
+func (e *Engine) fadeToAnimation(next animation.Animation, states map[int]string) {
+	unchanged := make(map[int]bool)
+	for channel, state := range states {
+		if previous, ok := e.channelStates[channel]; ok && previous == state {
+			unchanged[channel] = true
+		}
+	}
+
+	e.animation = e.animFactory.TransitionAnimation(e.animation, next, e.transition, unchanged)
+	e.channelStates = states
+	e.animation.Start()
+}
+
+// createBrightnessSchedule creates the schedule with the sunrise and sunset taken at the center of the stations.
+func createBrightnessSchedule(settings *common.BrightnessSettings, stations map[string]*stationrepo.Station) (*brightnessschedule.BrightnessSchedule, error) {
+	coordinates := make([]*geo.Coordinate, 0, len(stations))
+	for _, s := range stations {
+		if s.Coordinate != nil {
+			coordinates = append(coordinates, s.Coordinate)
+		}
+	}
+
+	points := make([]brightnessschedule.Point, len(settings.Points))
+	for i, p := range settings.Points {
+		points[i] = brightnessschedule.Point{
+			Anchor: brightnessschedule.AnchorTime,
+			Offset: p.Offset(),
+			Level:  p.Level(),
+		}
+
+		switch p.At {
+		case common.BrightnessAtSunrise:
+			points[i].Anchor = brightnessschedule.AnchorSunrise
+		case common.BrightnessAtSunset:
+			points[i].Anchor = brightnessschedule.AnchorSunset
+		}
+	}
+
+	return brightnessschedule.CreateBrightnessSchedule(&brightnessschedule.Config{
+		Points:   points,
+		Location: geo.Center(coordinates),
+	})
+}
+
+// copyStations copies the stations so they can be updated without holding the lock.
+func copyStations(stations map[string]*stationrepo.Station) map[string]*stationrepo.Station {
+	copied := make(map[string]*stationrepo.Station, len(stations))
+	for id, s := range stations {
+		station := *s
+		copied[id] = &station
+	}
+
+	return copied
+}
+
+// createQuietHours creates the quiet hours from the validated settings.
+func createQuietHours(settings *common.QuietHoursSettings) *quiethours.QuietHours {
+	return quiethours.CreateQuietHours(&quiethours.Config{
+		Start:              settings.StartTime(),
+		End:                settings.EndTime(),
+		WakeOnIFR:          settings.WakesOn(common.QuietWakeOnIFR),
+		WakeOnLIFR:         settings.WakesOn(common.QuietWakeOnLIFR),
+		WakeOnThunderstorm: settings.WakesOn(common.QuietWakeOnThunderstorm),
+	})
+}
+
+// heartbeatChannel gets the channel of the heartbeat station, the first station when not set, or -1 for no heartbeat.
+func heartbeatChannel(settings *common.QuietHoursSettings, stations map[string]*stationrepo.Station) int {
+	if settings.Style != common.QuietStyleHeartbeat {
+		return -1
+	}
+
+	if settings.HeartbeatStation != "" {
+		if s, ok := stations[settings.HeartbeatStation]; ok {
+			return s.Ordinal
+		}
+		logger.LogWarn("heartbeat station '%s' isn't on the map, using the first station", settings.HeartbeatStation)
+	}
+
+	return 0
 }
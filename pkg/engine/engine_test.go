@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/enginemode"
+	"github.com/ataboo/go-metar-blink/pkg/fetchscheduler"
+	"github.com/ataboo/go-metar-blink/pkg/metaranimation"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+func TestFetchRoutineReplay(t *testing.T) {
+	now := time.Date(2021, 1, 10, 7, 15, 0, 0, time.UTC)
+	clock := common.ClockFunc(func() time.Time { return now })
+
+	client, err := metarclient.CreateMetarClient(&metarclient.Settings{
+		StationIDs: []string{"CYEG", "CYYC"},
+		Strategy:   common.ReplayMetarStrategy,
+		RecordDir:  createReplayDir(t),
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := stationrepo.CreateStationRepo(client, &stationrepo.Config{
+		StationIDs:  []string{"CYEG", "CYYC"},
+		StaleAfter:  90 * time.Minute,
+		ExpireAfter: 180 * time.Minute,
+		Clock:       clock,
+		CacheDir:    t.TempDir(),
+	})
+
+	e := createTestEngine(repo, map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", Ordinal: 0},
+		"CYYC": {ID: "CYYC", Ordinal: 1},
+	})
+	defer e.cancel()
+
+	// The replay steps a recorded response per fetch: VFR at both, then IFR at CYYC.
+	expected := []map[string]string{
+		{"CYEG": common.FlightRuleVFR, "CYYC": common.FlightRuleVFR},
+		{"CYEG": common.FlightRuleVFR, "CYYC": common.FlightRuleIFR},
+		{"CYEG": common.FlightRuleVFR, "CYYC": common.FlightRuleVFR},
+	}
+
+	for i, rules := range expected {
+		e.fetchRoutine()
+
+		for id, flightRules := range rules {
+			if e.stations[id].FlightRules != flightRules {
+				t.Errorf("fetch %d: expected %s at %s, got %s", i, flightRules, id, e.stations[id].FlightRules)
+			}
+		}
+
+		if e.modes.Mode() != enginemode.ModeDisplay || e.animation == nil {
+			t.Errorf("fetch %d: expected the conditions to be displayed", i)
+		}
+	}
+}
+
+// createTestEngine creates an engine without led output for exercising fetches.
+func createTestEngine(repo *stationrepo.StationRepo, stations map[string]*stationrepo.Station) *Engine {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Engine{
+		repo:        repo,
+		stations:    stations,
+		fetchTimer:  time.NewTimer(time.Hour),
+		scheduler:   fetchscheduler.CreateFetchScheduler(&fetchscheduler.Config{MinInterval: time.Minute, MaxInterval: time.Hour}),
+		modes:       enginemode.CreateMachine(nil),
+		animFactory: metaranimation.CreateMetarAnimationFactory(&metaranimation.ColorTheme{}, &metaranimation.WindConfig{}, &metaranimation.OverlayConfig{}),
+		displayMode: common.DisplayModeConditions,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+// createReplayDir records a VFR xml response followed 10 minutes later by a json response with CYYC at IFR.
+func createReplayDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"aviation-weather-example.xml":      "2021-01-10T07-00-00Z_AviationWeather.xml",
+		"aviation-weather-api-example.json": "2021-01-10T07-10-00Z_AviationWeatherAPI.json",
+	}
+
+	for example, recorded := range files {
+		raw, err := ioutil.ReadFile(path.Join(common.GetResourcesRoot(), "dev", example))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(path.Join(dir, recorded), raw, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
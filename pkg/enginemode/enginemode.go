@@ -0,0 +1,106 @@
+package enginemode
+
+import (
+	"fmt"
+
+	"github.com/ataboo/go-metar-blink/pkg/quiethours"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+// Mode is what the engine is showing on the map.
+type Mode int
+
+const (
+	ModeStarting Mode = iota
+	ModeLoading
+	ModeIPAddress
+	ModeDisplay
+	ModeQuiet
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeStarting:
+		return "starting"
+	case ModeLoading:
+		return "loading"
+	case ModeIPAddress:
+		return "ip address"
+	case ModeDisplay:
+		return "display"
+	case ModeQuiet:
+		return "quiet"
+	default:
+		return fmt.Sprintf("mode %d", int(m))
+	}
+}
+
+// Machine picks the engine's mode. The IP address is shown until the first reports are loaded, then quiet hours
+// take over unless a report wakes the map.
+type Machine struct {
+	mode          Mode
+	quiet         *quiethours.QuietHours
+	reportsLoaded bool
+	wakeReason    string
+}
+
+// CreateMachine creates a machine in the starting mode. Quiet may be nil to never be quiet.
+func CreateMachine(quiet *quiethours.QuietHours) *Machine {
+	return &Machine{
+		mode:  ModeStarting,
+		quiet: quiet,
+	}
+}
+
+// Mode gets the current mode.
+func (m *Machine) Mode() Mode {
+	return m.mode
+}
+
+// WakeReason gets why the map is woken from quiet hours, or an empty string when it isn't.
+func (m *Machine) WakeReason() string {
+	return m.wakeReason
+}
+
+// ShowIPAddress switches to showing the IP address until the first reports are loaded.
+func (m *Machine) ShowIPAddress() {
+	m.mode = ModeIPAddress
+}
+
+// ObserveReports marks the reports as loaded and checks them for anything that should wake the map.
+// Returns true when the wake reason has changed.
+func (m *Machine) ObserveReports(stations map[string]*stationrepo.Station) bool {
+	m.reportsLoaded = true
+	if m.quiet == nil {
+		return false
+	}
+
+	reason := m.quiet.WakeReason(stations)
+	changed := reason != m.wakeReason
+	m.wakeReason = reason
+
+	return changed
+}
+
+// Update moves to the next mode. Returns the mode and if its animation should be started, which is when the mode
+// changes, or with refresh when displaying to show new reports.
+func (m *Machine) Update(refresh bool) (Mode, bool) {
+	next := m.nextMode()
+	start := next != m.mode || (refresh && next == ModeDisplay)
+	m.mode = next
+
+	return next, start
+}
+
+func (m *Machine) nextMode() Mode {
+	switch {
+	case m.mode == ModeIPAddress && !m.reportsLoaded:
+		return ModeIPAddress
+	case m.quiet != nil && m.wakeReason == "" && m.quiet.InWindow():
+		return ModeQuiet
+	case !m.reportsLoaded:
+		return ModeLoading
+	default:
+		return ModeDisplay
+	}
+}
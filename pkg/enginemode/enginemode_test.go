@@ -0,0 +1,119 @@
+package enginemode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/quiethours"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+func createTestMachine(now *time.Time) *Machine {
+	return CreateMachine(quiethours.CreateQuietHours(&quiethours.Config{
+		Start:      23 * time.Hour,
+		End:        6*time.Hour + 30*time.Minute,
+		WakeOnLIFR: true,
+		Clock:      common.ClockFunc(func() time.Time { return *now }),
+	}))
+}
+
+func createTestStations(flightRules string) map[string]*stationrepo.Station {
+	return map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", Ordinal: 0, FlightRules: common.FlightRuleVFR},
+		"CYYC": {ID: "CYYC", Ordinal: 1, FlightRules: flightRules},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	day := time.Date(2021, 3, 14, 12, 0, 0, 0, time.UTC)
+	night := time.Date(2021, 3, 14, 2, 0, 0, 0, time.UTC)
+
+	rows := []struct {
+		name          string
+		ipAddress     bool
+		flightRules   string
+		now           time.Time
+		refresh       bool
+		expectedMode  Mode
+		expectedStart bool
+	}{
+		{"loading", false, "", day, false, ModeLoading, true},
+		{"quiet while loading", false, "", night, false, ModeQuiet, true},
+		{"ip address until loaded", true, "", night, false, ModeIPAddress, false},
+		{"ip address to display", true, common.FlightRuleVFR, day, false, ModeDisplay, true},
+		{"display", false, common.FlightRuleVFR, day, true, ModeDisplay, true},
+		{"quiet", false, common.FlightRuleVFR, night, false, ModeQuiet, true},
+		{"woken", false, common.FlightRuleLIFR, night, false, ModeDisplay, true},
+		{"not woken by ifr", false, common.FlightRuleIFR, night, false, ModeQuiet, true},
+	}
+
+	for _, r := range rows {
+		now := r.now
+		machine := createTestMachine(&now)
+		if r.ipAddress {
+			machine.ShowIPAddress()
+		}
+		if r.flightRules != "" {
+			machine.ObserveReports(createTestStations(r.flightRules))
+		}
+
+		mode, start := machine.Update(r.refresh)
+		if mode != r.expectedMode || start != r.expectedStart || machine.Mode() != mode {
+			t.Errorf("%s: expected %s %t, got %s %t", r.name, r.expectedMode, r.expectedStart, mode, start)
+		}
+	}
+}
+
+func TestModeSequence(t *testing.T) {
+	now := time.Date(2021, 3, 14, 22, 0, 0, 0, time.UTC)
+	machine := createTestMachine(&now)
+
+	steps := []struct {
+		name          string
+		action        func()
+		refresh       bool
+		expectedMode  Mode
+		expectedStart bool
+	}{
+		{"start", func() {}, false, ModeLoading, true},
+		{"ip address", machine.ShowIPAddress, false, ModeIPAddress, false},
+		{"loaded", func() { machine.ObserveReports(createTestStations(common.FlightRuleVFR)) }, true, ModeDisplay, true},
+		{"unchanged", func() {}, false, ModeDisplay, false},
+		{"refresh", func() {}, true, ModeDisplay, true},
+		{"quiet hours", func() { now = now.Add(90 * time.Minute) }, false, ModeQuiet, true},
+		{"refresh while quiet", func() { machine.ObserveReports(createTestStations(common.FlightRuleVFR)) }, true, ModeQuiet, false},
+		{"woken", func() { machine.ObserveReports(createTestStations(common.FlightRuleLIFR)) }, true, ModeDisplay, true},
+		{"back to sleep", func() { machine.ObserveReports(createTestStations(common.FlightRuleIFR)) }, true, ModeQuiet, true},
+		{"morning", func() { now = now.Add(7 * time.Hour) }, false, ModeDisplay, true},
+	}
+
+	for _, s := range steps {
+		s.action()
+		mode, start := machine.Update(s.refresh)
+		if mode != s.expectedMode || start != s.expectedStart {
+			t.Errorf("%s: expected %s %t, got %s %t", s.name, s.expectedMode, s.expectedStart, mode, start)
+		}
+	}
+}
+
+func TestObserveReportsWakeReason(t *testing.T) {
+	now := time.Date(2021, 3, 14, 2, 0, 0, 0, time.UTC)
+	machine := createTestMachine(&now)
+
+	if machine.ObserveReports(createTestStations(common.FlightRuleVFR)) || machine.WakeReason() != "" {
+		t.Error("expected no wake reason")
+	}
+
+	if !machine.ObserveReports(createTestStations(common.FlightRuleLIFR)) || machine.WakeReason() == "" {
+		t.Error("expected a new wake reason")
+	}
+
+	if machine.ObserveReports(createTestStations(common.FlightRuleLIFR)) {
+		t.Error("expected the same wake reason")
+	}
+
+	if CreateMachine(nil).ObserveReports(createTestStations(common.FlightRuleLIFR)) {
+		t.Error("expected no wake reason without quiet hours")
+	}
+}
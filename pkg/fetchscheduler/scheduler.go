@@ -0,0 +1,156 @@
+package fetchscheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+// Config sets how often reports are fetched.
+// Fetches are every MinInterval while any station has been active within ActiveFor, doubling with each stable
+// fetch up to MaxInterval. A fetch is always made IssuanceOffset after the top of the hour to catch the routine METARs.
+type Config struct {
+	MinInterval    time.Duration
+	MaxInterval    time.Duration
+	IssuanceOffset time.Duration
+	ActiveFor      time.Duration
+	Clock          common.Clock
+}
+
+// FetchScheduler picks when to fetch reports based on how active the weather is.
+// A station is active when its flight category changes, it issues a SPECI, or it reports a thunderstorm.
+type FetchScheduler struct {
+	config      *Config
+	clock       common.Clock
+	flightRules map[string]string
+	lastActive  time.Time
+	stableCount int
+	lock        sync.Mutex
+}
+
+func CreateFetchScheduler(config *Config) *FetchScheduler {
+	if config.MaxInterval < config.MinInterval {
+		config.MaxInterval = config.MinInterval
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = common.SystemClock{}
+	}
+
+	return &FetchScheduler{
+		config:      config,
+		clock:       clock,
+		flightRules: make(map[string]string),
+	}
+}
+
+// Observe records the stations after a fetch and returns true if any of them are active.
+func (s *FetchScheduler) Observe(stations map[string]*stationrepo.Station) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	active := false
+
+	for _, station := range stations {
+		if reason := s.activeReason(station, now); reason != "" {
+			logger.LogDebug("station '%s' is active: %s", station.ID, reason)
+			active = true
+		}
+
+		s.flightRules[station.ID] = station.FlightRules
+	}
+
+	if active {
+		s.lastActive = now
+		s.stableCount = 0
+	} else {
+		s.stableCount++
+	}
+
+	return active
+}
+
+// Interval gets the time between fetches before aligning to the top of the hour.
+func (s *FetchScheduler) Interval() time.Duration {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.interval(s.clock.Now())
+}
+
+// NextFetch gets when the next fetch should be made, at least MinInterval from now.
+func (s *FetchScheduler) NextFetch() time.Time {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.clock.Now()
+	next := now.Add(s.interval(now))
+
+	if issuance := s.nextIssuance(now); issuance.Before(next) {
+		next = issuance
+	}
+
+	// An issuance that's too close is fetched late rather than skipped.
+	if earliest := now.Add(s.config.MinInterval); next.Before(earliest) {
+		next = earliest
+	}
+
+	return next
+}
+
+// NextDelay gets how long to wait for the next fetch.
+func (s *FetchScheduler) NextDelay() time.Duration {
+	return s.NextFetch().Sub(s.clock.Now())
+}
+
+func (s *FetchScheduler) activeReason(station *stationrepo.Station, now time.Time) string {
+	if station.FlightRules == common.FlightRuleError {
+		return ""
+	}
+
+	if previous, ok := s.flightRules[station.ID]; ok && previous != common.FlightRuleError && previous != station.FlightRules {
+		return "flight category changed from " + previous + " to " + station.FlightRules
+	}
+
+	if station.Speci && (station.ObservationTime.IsZero() || station.ObservationAge(now) < s.config.ActiveFor) {
+		return "issued a speci"
+	}
+
+	if station.HasWeather("TS") {
+		return "reporting a thunderstorm"
+	}
+
+	return ""
+}
+
+func (s *FetchScheduler) interval(now time.Time) time.Duration {
+	if !s.lastActive.IsZero() && now.Sub(s.lastActive) < s.config.ActiveFor {
+		return s.config.MinInterval
+	}
+
+	interval := s.config.MinInterval
+	for i := 0; i < s.stableCount && interval < s.config.MaxInterval; i++ {
+		interval *= 2
+	}
+
+	if interval > s.config.MaxInterval {
+		return s.config.MaxInterval
+	}
+
+	return interval
+}
+
+// nextIssuance gets the first issuance time at or after the time.
+func (s *FetchScheduler) nextIssuance(t time.Time) time.Time {
+	issuance := t.UTC().Truncate(time.Hour).Add(s.config.IssuanceOffset)
+	if issuance.Before(t) {
+		issuance = issuance.Add(time.Hour)
+	}
+
+	return issuance
+}
@@ -0,0 +1,151 @@
+package fetchscheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+func TestSchedulerBacksOffWhenStable(t *testing.T) {
+	now := time.Date(2021, 1, 10, 7, 10, 0, 0, time.UTC)
+	scheduler := createTestScheduler(&now)
+	stations := map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", FlightRules: common.FlightRuleVFR},
+	}
+
+	expected := []time.Duration{10 * time.Minute, 20 * time.Minute, 40 * time.Minute, time.Hour, time.Hour}
+	for i, interval := range expected {
+		if scheduler.Observe(stations) {
+			t.Error("unnexpected activity")
+		}
+
+		if actual := scheduler.Interval(); actual != interval {
+			t.Error("unnexpected interval", i, actual, interval)
+		}
+	}
+}
+
+func TestSchedulerSpeedsUpWhenActive(t *testing.T) {
+	now := time.Date(2021, 1, 10, 7, 10, 0, 0, time.UTC)
+	scheduler := createTestScheduler(&now)
+	station := &stationrepo.Station{ID: "CYEG", FlightRules: common.FlightRuleVFR}
+	stations := map[string]*stationrepo.Station{"CYEG": station}
+
+	scheduler.Observe(stations)
+	scheduler.Observe(stations)
+
+	station.FlightRules = common.FlightRuleIFR
+	if !scheduler.Observe(stations) {
+		t.Error("expected category change to be active")
+	}
+
+	if interval := scheduler.Interval(); interval != 5*time.Minute {
+		t.Error("unnexpected active interval", interval)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if interval := scheduler.Interval(); interval != 5*time.Minute {
+		t.Error("expected to stay active", interval)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if interval := scheduler.Interval(); interval != 5*time.Minute {
+		t.Error("expected min interval before a stable fetch", interval)
+	}
+
+	if scheduler.Observe(stations) || scheduler.Interval() != 10*time.Minute {
+		t.Error("expected back off after active period", scheduler.Interval())
+	}
+
+	station.Weather = []*metarclient.WeatherPhenomenon{{Raw: "VCTS", Intensity: metarclient.IntensityVicinity, Descriptor: "TS"}}
+	if scheduler.Observe(stations) {
+		t.Error("vicinity thunderstorms should not be active")
+	}
+
+	station.Weather = []*metarclient.WeatherPhenomenon{{Raw: "TSRA", Descriptor: "TS", Phenomena: []string{"RA"}}}
+	if !scheduler.Observe(stations) {
+		t.Error("expected thunderstorm to be active")
+	}
+}
+
+func TestSchedulerSpeci(t *testing.T) {
+	now := time.Date(2021, 1, 10, 7, 10, 0, 0, time.UTC)
+	scheduler := createTestScheduler(&now)
+	station := &stationrepo.Station{ID: "CYEG", FlightRules: common.FlightRuleVFR, Speci: true, ObservationTime: now.Add(-20 * time.Minute)}
+
+	if !scheduler.Observe(map[string]*stationrepo.Station{"CYEG": station}) {
+		t.Error("expected recent speci to be active")
+	}
+
+	station.ObservationTime = now.Add(-2 * time.Hour)
+	if scheduler.Observe(map[string]*stationrepo.Station{"CYEG": station}) {
+		t.Error("expected old speci not to be active")
+	}
+}
+
+func TestSchedulerIgnoresErrors(t *testing.T) {
+	now := time.Date(2021, 1, 10, 7, 10, 0, 0, time.UTC)
+	scheduler := createTestScheduler(&now)
+	station := &stationrepo.Station{ID: "CYEG", FlightRules: common.FlightRuleVFR}
+	stations := map[string]*stationrepo.Station{"CYEG": station}
+
+	scheduler.Observe(stations)
+	station.FlightRules = common.FlightRuleError
+	if scheduler.Observe(stations) {
+		t.Error("error should not be active")
+	}
+
+	station.FlightRules = common.FlightRuleVFR
+	if scheduler.Observe(stations) {
+		t.Error("recovering from error should not be active")
+	}
+}
+
+func TestSchedulerAlignsToIssuance(t *testing.T) {
+	var now time.Time
+	scheduler := createTestScheduler(&now)
+	stations := map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", FlightRules: common.FlightRuleVFR},
+	}
+
+	for i := 0; i < 4; i++ {
+		scheduler.Observe(stations)
+	}
+
+	rows := []struct {
+		name     string
+		now      time.Time
+		expected time.Time
+	}{
+		{"issuance before interval", time.Date(2021, 1, 10, 7, 10, 0, 0, time.UTC), time.Date(2021, 1, 10, 8, 5, 0, 0, time.UTC)},
+		{"issuance at min interval", time.Date(2021, 1, 10, 8, 0, 0, 0, time.UTC), time.Date(2021, 1, 10, 8, 5, 0, 0, time.UTC)},
+		{"issuance inside min interval", time.Date(2021, 1, 10, 8, 2, 0, 0, time.UTC), time.Date(2021, 1, 10, 8, 7, 0, 0, time.UTC)},
+		{"at issuance", time.Date(2021, 1, 10, 8, 5, 0, 0, time.UTC), time.Date(2021, 1, 10, 8, 10, 0, 0, time.UTC)},
+		{"just after issuance", time.Date(2021, 1, 10, 8, 6, 0, 0, time.UTC), time.Date(2021, 1, 10, 9, 5, 0, 0, time.UTC)},
+	}
+
+	for _, row := range rows {
+		now = row.now
+		if next := scheduler.NextFetch(); !next.Equal(row.expected) {
+			t.Errorf("%s: expected %s, got %s", row.name, row.expected, next)
+		}
+	}
+
+	now = time.Date(2021, 1, 10, 7, 50, 0, 0, time.UTC)
+	if delay := scheduler.NextDelay(); delay != 15*time.Minute {
+		t.Error("unnexpected delay", delay)
+	}
+}
+
+func createTestScheduler(now *time.Time) *FetchScheduler {
+	return CreateFetchScheduler(&Config{
+		MinInterval:    5 * time.Minute,
+		MaxInterval:    time.Hour,
+		IssuanceOffset: 5 * time.Minute,
+		ActiveFor:      time.Hour,
+		Clock:          common.ClockFunc(func() time.Time { return *now }),
+	})
+}
@@ -0,0 +1,123 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// BoundingBox is an area between two latitudes and two longitudes in degrees.
+// MinLongitude is greater than MaxLongitude when the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Validate checks the latitudes are ordered and all values are in range.
+func (b *BoundingBox) Validate() error {
+	if b.MinLatitude < -90 || b.MaxLatitude > 90 || b.MinLatitude > b.MaxLatitude {
+		return fmt.Errorf("invalid latitudes %.4f to %.4f", b.MinLatitude, b.MaxLatitude)
+	}
+
+	if b.MinLongitude < -180 || b.MinLongitude > 180 || b.MaxLongitude < -180 || b.MaxLongitude > 180 {
+		return fmt.Errorf("invalid longitudes %.4f to %.4f", b.MinLongitude, b.MaxLongitude)
+	}
+
+	return nil
+}
+
+// Contains checks if the coordinate is inside the box, including its edges.
+func (b *BoundingBox) Contains(c *Coordinate) bool {
+	if c.Latitude < b.MinLatitude || c.Latitude > b.MaxLatitude {
+		return false
+	}
+
+	if b.MinLongitude <= b.MaxLongitude {
+		return c.Longitude >= b.MinLongitude && c.Longitude <= b.MaxLongitude
+	}
+
+	return c.Longitude >= b.MinLongitude || c.Longitude <= b.MaxLongitude
+}
+
+// BoundingBoxAround gets a box containing every point within the radius of the center.
+// The box is clamped to the poles and covers every longitude when it reaches one.
+func BoundingBoxAround(center *Coordinate, radiusKm float64) *BoundingBox {
+	latDelta := radiusKm / EarthRadius * RadToDeg
+
+	box := &BoundingBox{
+		MinLatitude: math.Max(center.Latitude-latDelta, -90),
+		MaxLatitude: math.Min(center.Latitude+latDelta, 90),
+	}
+
+	if box.MinLatitude == -90 || box.MaxLatitude == 90 {
+		box.MinLongitude = -180
+		box.MaxLongitude = 180
+		return box
+	}
+
+	// Widest at the latitude closest to a pole.
+	maxLat := math.Max(math.Abs(box.MinLatitude), math.Abs(box.MaxLatitude))
+	longDelta := latDelta / math.Cos(maxLat*DegToRad)
+	if longDelta >= 180 {
+		box.MinLongitude = -180
+		box.MaxLongitude = 180
+		return box
+	}
+
+	box.MinLongitude = normalizeLongitude(center.Longitude - longDelta)
+	box.MaxLongitude = normalizeLongitude(center.Longitude + longDelta)
+
+	return box
+}
+
+// DistanceKm gets the great circle distance to the other coordinate.
+func (c *Coordinate) DistanceKm(other *Coordinate) float64 {
+	lat1 := c.Latitude * DegToRad
+	lat2 := other.Latitude * DegToRad
+	dLat := lat2 - lat1
+	dLong := (other.Longitude - c.Longitude) * DegToRad
+
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1)*math.Cos(lat2)*math.Pow(math.Sin(dLong/2), 2)
+
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func normalizeLongitude(longitude float64) float64 {
+	for longitude > 180 {
+		longitude -= 360
+	}
+	for longitude < -180 {
+		longitude += 360
+	}
+
+	return longitude
+}
+
+// Center gets the middle of the box around the coordinates, going the short way around the antimeridian.
+func Center(coordinates []*Coordinate) *Coordinate {
+	if len(coordinates) == 0 {
+		return &Coordinate{}
+	}
+
+	latMin, latMax := 90.0, -90.0
+	longMin, longMax := 360.0, 0.0
+	for _, c := range coordinates {
+		latMin = math.Min(latMin, c.Latitude)
+		latMax = math.Max(latMax, c.Latitude)
+
+		posLong := math.Mod(c.Longitude+360, 360)
+		longMin = math.Min(longMin, posLong)
+		longMax = math.Max(longMax, posLong)
+	}
+
+	centerLong := (longMin + longMax) / 2
+	if longMax-longMin > 180 {
+		centerLong += 180
+	}
+
+	return &Coordinate{
+		Latitude:  (latMin + latMax) / 2,
+		Longitude: normalizeLongitude(centerLong),
+	}
+}
@@ -0,0 +1,81 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	edmonton := &Coordinate{Latitude: 53.3097, Longitude: -113.5792}
+	calgary := &Coordinate{Latitude: 51.1139, Longitude: -114.0203}
+
+	if distance := edmonton.DistanceKm(calgary); math.Abs(distance-246) > 2 {
+		t.Error("unnexpected distance", distance)
+	}
+
+	if distance := edmonton.DistanceKm(edmonton); distance != 0 {
+		t.Error("expected zero distance", distance)
+	}
+
+	west := &Coordinate{Latitude: 0, Longitude: 179.5}
+	east := &Coordinate{Latitude: 0, Longitude: -179.5}
+	if distance := west.DistanceKm(east); math.Abs(distance-111.2) > 1 {
+		t.Error("unnexpected distance across antimeridian", distance)
+	}
+}
+
+func TestBoundingBoxAround(t *testing.T) {
+	center := &Coordinate{Latitude: 53.3, Longitude: -113.6}
+	box := BoundingBoxAround(center, 100)
+
+	if err := box.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		coord    *Coordinate
+		expected bool
+	}{
+		{center, true},
+		{&Coordinate{Latitude: 54.1, Longitude: -113.6}, true},
+		{&Coordinate{Latitude: 54.3, Longitude: -113.6}, false},
+		{&Coordinate{Latitude: 53.3, Longitude: -112.2}, true},
+		{&Coordinate{Latitude: 53.3, Longitude: -111.9}, false},
+	}
+
+	for _, row := range table {
+		if contains := box.Contains(row.coord); contains != row.expected {
+			t.Error("unnexpected contains", row.coord, contains)
+		}
+	}
+}
+
+func TestBoundingBoxAntimeridian(t *testing.T) {
+	box := BoundingBoxAround(&Coordinate{Latitude: 0, Longitude: 179.9}, 50)
+	if box.MinLongitude < box.MaxLongitude {
+		t.Fatal("expected box to wrap", box)
+	}
+
+	if !box.Contains(&Coordinate{Latitude: 0, Longitude: -179.9}) || box.Contains(&Coordinate{Latitude: 0, Longitude: 0}) {
+		t.Error("unnexpected wrapped contains")
+	}
+
+	polar := BoundingBoxAround(&Coordinate{Latitude: 89.5, Longitude: 10}, 100)
+	if polar.MaxLatitude != 90 || polar.MinLongitude != -180 || polar.MaxLongitude != 180 {
+		t.Error("expected polar box to cover every longitude", polar)
+	}
+}
+
+func TestBoundingBoxValidate(t *testing.T) {
+	invalid := []*BoundingBox{
+		{MinLatitude: 10, MaxLatitude: 5},
+		{MinLatitude: -91, MaxLatitude: 5},
+		{MinLatitude: 0, MaxLatitude: 5, MinLongitude: -181},
+	}
+
+	for _, box := range invalid {
+		if box.Validate() == nil {
+			t.Error("expected invalid", box)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package geo
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	julianUnixEpoch = 2440587.5
+	julian2000      = 2451545.0
+	// sunriseAltitude is the sun's center at sunrise, allowing for refraction and the sun's radius.
+	sunriseAltitude = -0.833
+	earthTilt       = 23.4397
+)
+
+// SunTimes are the sunrise and sunset on a day. AlwaysUp is set when the sun doesn't set that day and AlwaysDown
+// when it doesn't rise, leaving the times zero.
+type SunTimes struct {
+	Sunrise    time.Time
+	Sunset     time.Time
+	AlwaysUp   bool
+	AlwaysDown bool
+}
+
+// SunTimes calculates the sunrise and sunset at the coordinate on the date's day, in the date's location.
+// Accurate to about a minute away from the poles.
+func (c *Coordinate) SunTimes(date time.Time) *SunTimes {
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, time.UTC)
+	day := math.Round(float64(noon.Unix())/86400 + julianUnixEpoch - julian2000 + 0.0008)
+
+	meanSolarTime := day - c.Longitude/360
+	anomaly := math.Mod(357.5291+0.98560028*meanSolarTime, 360) * DegToRad
+	center := 1.9148*math.Sin(anomaly) + 0.02*math.Sin(2*anomaly) + 0.0003*math.Sin(3*anomaly)
+	eclipticLongitude := math.Mod(anomaly*RadToDeg+center+180+102.9372, 360) * DegToRad
+	transit := julian2000 + meanSolarTime + 0.0053*math.Sin(anomaly) - 0.0069*math.Sin(2*eclipticLongitude)
+
+	declination := math.Asin(math.Sin(eclipticLongitude) * math.Sin(earthTilt*DegToRad))
+	latitude := c.Latitude * DegToRad
+	cosHourAngle := (math.Sin(sunriseAltitude*DegToRad) - math.Sin(latitude)*math.Sin(declination)) /
+		(math.Cos(latitude) * math.Cos(declination))
+
+	switch {
+	case cosHourAngle < -1:
+		return &SunTimes{AlwaysUp: true}
+	case cosHourAngle > 1:
+		return &SunTimes{AlwaysDown: true}
+	}
+
+	hourAngle := math.Acos(cosHourAngle) * RadToDeg
+
+	return &SunTimes{
+		Sunrise: julianToTime(transit-hourAngle/360, date.Location()),
+		Sunset:  julianToTime(transit+hourAngle/360, date.Location()),
+	}
+}
+
+func julianToTime(julian float64, location *time.Location) time.Time {
+	seconds := (julian - julianUnixEpoch) * 86400
+
+	return time.Unix(0, int64(seconds*float64(time.Second))).In(location)
+}
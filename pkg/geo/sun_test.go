@@ -0,0 +1,85 @@
+package geo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSunTimes(t *testing.T) {
+	mdt := time.FixedZone("MDT", -6*3600)
+	rows := []struct {
+		name      string
+		coord     Coordinate
+		date      time.Time
+		sunrise   time.Time
+		sunset    time.Time
+		tolerance time.Duration
+	}{
+		{
+			"edmonton solstice",
+			Coordinate{Latitude: 53.55, Longitude: -113.49},
+			time.Date(2021, 6, 21, 0, 0, 0, 0, mdt),
+			time.Date(2021, 6, 21, 5, 6, 0, 0, mdt),
+			time.Date(2021, 6, 21, 22, 7, 0, 0, mdt),
+			3 * time.Minute,
+		},
+		{
+			"greenwich equinox",
+			Coordinate{Latitude: 51.4779, Longitude: 0},
+			time.Date(2021, 3, 20, 23, 0, 0, 0, time.UTC),
+			time.Date(2021, 3, 20, 6, 3, 0, 0, time.UTC),
+			time.Date(2021, 3, 20, 18, 14, 0, 0, time.UTC),
+			3 * time.Minute,
+		},
+	}
+
+	for _, row := range rows {
+		times := row.coord.SunTimes(row.date)
+		if times.AlwaysUp || times.AlwaysDown {
+			t.Errorf("%s: expected the sun to rise and set", row.name)
+			continue
+		}
+
+		if diff := times.Sunrise.Sub(row.sunrise); diff > row.tolerance || diff < -row.tolerance {
+			t.Errorf("%s: expected sunrise near %s, got %s", row.name, row.sunrise, times.Sunrise)
+		}
+
+		if diff := times.Sunset.Sub(row.sunset); diff > row.tolerance || diff < -row.tolerance {
+			t.Errorf("%s: expected sunset near %s, got %s", row.name, row.sunset, times.Sunset)
+		}
+
+		if times.Sunrise.Location() != row.date.Location() {
+			t.Errorf("%s: expected times in the date's location", row.name)
+		}
+	}
+}
+
+func TestSunTimesPolar(t *testing.T) {
+	tromso := Coordinate{Latitude: 69.65, Longitude: 18.96}
+
+	if times := tromso.SunTimes(time.Date(2021, 6, 21, 12, 0, 0, 0, time.UTC)); !times.AlwaysUp {
+		t.Error("expected midnight sun", times)
+	}
+
+	if times := tromso.SunTimes(time.Date(2021, 12, 21, 12, 0, 0, 0, time.UTC)); !times.AlwaysDown {
+		t.Error("expected polar night", times)
+	}
+}
+
+func TestCenter(t *testing.T) {
+	center := Center([]*Coordinate{
+		{Latitude: 50, Longitude: -115},
+		{Latitude: 54, Longitude: -111},
+	})
+	if center.Latitude != 52 || center.Longitude != -113 {
+		t.Error("unexpected center", center)
+	}
+
+	center = Center([]*Coordinate{
+		{Latitude: 0, Longitude: 179},
+		{Latitude: 0, Longitude: -177},
+	})
+	if center.Longitude != -179 {
+		t.Error("expected center across the antimeridian", center)
+	}
+}
@@ -0,0 +1,154 @@
+package ledoutput
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+)
+
+const (
+	red = iota
+	green
+	blue
+	white
+)
+
+// wireShifts are the bit shifts of each channel in a packed word, in the order the strip receives them.
+// They match the driver's pass-through RGB and RGBW strip types.
+var wireShifts = []uint{16, 8, 0, 24}
+
+// Config sets up the output pipeline. ChannelOrder lists the channels in the order the strip reads them,
+// like "GRB", with a "W" for RGBW strips. The scales are the white balance applied after the gamma.
+type Config struct {
+	Gamma        float64
+	ChannelOrder string
+	RedScale     float64
+	GreenScale   float64
+	BlueScale    float64
+}
+
+// Pipeline corrects animation colors for the LEDs and packs them in the strip's channel order.
+// The brightness is applied after the gamma so dimming stays even. It's not safe to change while rendering.
+type Pipeline struct {
+	levels     [3][256]byte
+	order      []int
+	hasWhite   bool
+	brightness byte
+}
+
+// CreatePipeline creates a pipeline, building the gamma and white balance lookup for each channel.
+func CreatePipeline(config *Config) (*Pipeline, error) {
+	if config.Gamma <= 0 {
+		return nil, fmt.Errorf("gamma must be positive")
+	}
+
+	order, err := ParseChannelOrder(config.ChannelOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pipeline{order: order, brightness: 0xFF}
+	for _, channel := range order {
+		if channel == white {
+			p.hasWhite = true
+		}
+	}
+
+	for channel, scale := range []float64{config.RedScale, config.GreenScale, config.BlueScale} {
+		if scale < 0 || scale > 1 {
+			return nil, fmt.Errorf("channel scales must be between 0 and 1")
+		}
+
+		for level := range p.levels[channel] {
+			corrected := math.Pow(float64(level)/0xFF, config.Gamma) * scale
+			p.levels[channel][level] = byte(math.Round(corrected * 0xFF))
+		}
+	}
+
+	return p, nil
+}
+
+// ParseChannelOrder gets the channel at each position of an order like "GRB" or "RGBW".
+func ParseChannelOrder(channelOrder string) ([]int, error) {
+	channelOrder = strings.ToUpper(channelOrder)
+	if len(channelOrder) != 3 && len(channelOrder) != 4 {
+		return nil, fmt.Errorf("channel order '%s' must have 3 or 4 channels", channelOrder)
+	}
+
+	order := make([]int, len(channelOrder))
+	seen := make(map[int]bool)
+	for i, letter := range channelOrder {
+		channel := strings.IndexRune("RGBW", letter)
+		if channel < 0 || seen[channel] {
+			return nil, fmt.Errorf("channel order '%s' must use each of R, G, B and optionally W once", channelOrder)
+		}
+		seen[channel] = true
+		order[i] = channel
+	}
+
+	if len(order) == 4 && !seen[white] {
+		return nil, fmt.Errorf("channel order '%s' has 4 channels without a W", channelOrder)
+	}
+
+	return order, nil
+}
+
+// SetBrightness sets the level all colors are scaled by.
+func (p *Pipeline) SetBrightness(brightness byte) {
+	p.brightness = brightness
+}
+
+// Brightness gets the level all colors are scaled by.
+func (p *Pipeline) Brightness() byte {
+	return p.brightness
+}
+
+// HasWhite checks if the strip has a white channel.
+func (p *Pipeline) HasWhite() bool {
+	return p.hasWhite
+}
+
+// Correct applies the gamma, white balance, and brightness to a color.
+func (p *Pipeline) Correct(c animation.Color) animation.Color {
+	corrected := animation.CreateColor(p.levels[red][c.R()], p.levels[green][c.G()], p.levels[blue][c.B()])
+
+	return corrected.Scale(float64(p.brightness) / 0xFF)
+}
+
+// Channels gets the corrected red, green, blue and white levels.
+// RGBW strips show the part shared by all three colors on the white LED instead.
+func (p *Pipeline) Channels(c animation.Color) [4]byte {
+	corrected := p.Correct(c)
+	channels := [4]byte{corrected.R(), corrected.G(), corrected.B(), 0}
+
+	if p.hasWhite {
+		w := channels[red]
+		for _, level := range channels[green:white] {
+			if level < w {
+				w = level
+			}
+		}
+
+		for i := red; i < white; i++ {
+			channels[i] -= w
+		}
+		channels[white] = w
+	}
+
+	return channels
+}
+
+// Pack gets the word to write to the strip for a color. The strip driver should pass channels through
+// in RGB or RGBW order as the reordering is done here.
+func (p *Pipeline) Pack(c animation.Color) uint32 {
+	channels := p.Channels(c)
+
+	var word uint32
+	for position, channel := range p.order {
+		word |= uint32(channels[channel]) << wireShifts[position]
+	}
+
+	return word
+}
@@ -0,0 +1,121 @@
+package ledoutput
+
+import (
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+)
+
+func createTestPipeline(t *testing.T, config *Config) *Pipeline {
+	p, err := CreatePipeline(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestPipelinePassThrough(t *testing.T) {
+	p := createTestPipeline(t, &Config{Gamma: 1, ChannelOrder: "RGB", RedScale: 1, GreenScale: 1, BlueScale: 1})
+
+	for _, c := range []animation.Color{0x000000, 0x123456, 0xFFFFFF, 0x80FF01} {
+		if word := p.Pack(c); word != c.RGB() {
+			t.Errorf("expected 0x%06x, got 0x%06x", c.RGB(), word)
+		}
+	}
+}
+
+func TestPipelineGamma(t *testing.T) {
+	p := createTestPipeline(t, &Config{Gamma: 2, ChannelOrder: "RGB", RedScale: 1, GreenScale: 1, BlueScale: 1})
+
+	rows := []struct {
+		color    animation.Color
+		expected animation.Color
+	}{
+		{0x000000, 0x000000},
+		{0xFFFFFF, 0xFFFFFF},
+		{0x808080, 0x404040},
+		{0x40FF10, 0x10FF01},
+	}
+
+	for _, row := range rows {
+		if c := p.Correct(row.color); c != row.expected {
+			t.Errorf("expected %s for %s, got %s", row.expected, row.color, c)
+		}
+	}
+}
+
+func TestPipelineWhiteBalance(t *testing.T) {
+	p := createTestPipeline(t, &Config{Gamma: 1, ChannelOrder: "RGB", RedScale: 1, GreenScale: 0.5, BlueScale: 0.25})
+
+	if c := p.Correct(animation.ColorWhite); c != animation.CreateColor(0xFF, 0x80, 0x40) {
+		t.Error("unexpected balanced white", c)
+	}
+}
+
+func TestPipelineChannelOrder(t *testing.T) {
+	rows := []struct {
+		order    string
+		expected uint32
+	}{
+		{"RGB", 0x112233},
+		{"GRB", 0x221133},
+		{"brg", 0x331122},
+		{"BGR", 0x332211},
+	}
+
+	for _, row := range rows {
+		p := createTestPipeline(t, &Config{Gamma: 1, ChannelOrder: row.order, RedScale: 1, GreenScale: 1, BlueScale: 1})
+		if word := p.Pack(0x112233); word != row.expected {
+			t.Errorf("%s: expected 0x%06x, got 0x%06x", row.order, row.expected, word)
+		}
+	}
+}
+
+func TestPipelineWhiteExtraction(t *testing.T) {
+	p := createTestPipeline(t, &Config{Gamma: 1, ChannelOrder: "GRBW", RedScale: 1, GreenScale: 1, BlueScale: 1})
+	if !p.HasWhite() {
+		t.Fatal("expected white channel")
+	}
+
+	if channels := p.Channels(0x806040); channels != [4]byte{0x40, 0x20, 0x00, 0x40} {
+		t.Error("unexpected channels", channels)
+	}
+
+	if word := p.Pack(0x806040); word != 0x40204000 {
+		t.Errorf("unexpected word 0x%08x", word)
+	}
+
+	if word := p.Pack(animation.ColorWhite); word != 0xFF000000 {
+		t.Errorf("expected white on the white LED only, got 0x%08x", word)
+	}
+}
+
+func TestCreatePipelineErrors(t *testing.T) {
+	configs := []*Config{
+		{Gamma: 0, ChannelOrder: "RGB", RedScale: 1, GreenScale: 1, BlueScale: 1},
+		{Gamma: 1, ChannelOrder: "RGGB", RedScale: 1, GreenScale: 1, BlueScale: 1},
+		{Gamma: 1, ChannelOrder: "RG", RedScale: 1, GreenScale: 1, BlueScale: 1},
+		{Gamma: 1, ChannelOrder: "RGX", RedScale: 1, GreenScale: 1, BlueScale: 1},
+		{Gamma: 1, ChannelOrder: "RGB", RedScale: 1.5, GreenScale: 1, BlueScale: 1},
+	}
+
+	for _, config := range configs {
+		if _, err := CreatePipeline(config); err == nil {
+			t.Errorf("expected error for %+v", config)
+		}
+	}
+}
+
+func TestPipelineBrightness(t *testing.T) {
+	p := createTestPipeline(t, &Config{Gamma: 2, ChannelOrder: "RGB", RedScale: 1, GreenScale: 1, BlueScale: 1})
+	p.SetBrightness(0x80)
+
+	if c := p.Correct(animation.ColorWhite); c != 0x808080 {
+		t.Error("expected brightness to scale the output", c)
+	}
+
+	if c := p.Correct(0x808080); c != 0x202020 {
+		t.Error("expected brightness after gamma", c)
+	}
+}
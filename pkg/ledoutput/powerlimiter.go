@@ -0,0 +1,106 @@
+package ledoutput
+
+import (
+	"sync"
+
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+)
+
+// limitSearchSteps is how many times the scale is halved looking for the brightest frame under budget.
+const limitSearchSteps = 10
+
+// PowerConfig sets the current each channel draws at full level, the idle draw of each LED, and the budget for the
+// whole strip in mA.
+type PowerConfig struct {
+	BudgetMA float64
+	RedMA    float64
+	GreenMA  float64
+	BlueMA   float64
+	WhiteMA  float64
+	IdleMA   float64
+}
+
+// PowerDraw is the estimated current of the last frame, before and after limiting.
+type PowerDraw struct {
+	RequestedMA float64
+	LimitedMA   float64
+	Scale       float64
+}
+
+// PowerLimiter scales frames down so the strip's estimated current stays under budget.
+type PowerLimiter struct {
+	config   PowerConfig
+	pipeline *Pipeline
+	draw     PowerDraw
+	lock     sync.Mutex
+}
+
+// CreatePowerLimiter creates a limiter estimating the draw of colors once through the pipeline, including its brightness.
+func CreatePowerLimiter(config *PowerConfig, pipeline *Pipeline) *PowerLimiter {
+	return &PowerLimiter{
+		config:   *config,
+		pipeline: pipeline,
+		draw:     PowerDraw{Scale: 1},
+	}
+}
+
+// Estimate gets the current the colors would draw in mA.
+func (l *PowerLimiter) Estimate(colors map[int]animation.Color) float64 {
+	return l.estimate(colors, 1)
+}
+
+// Limit scales the colors down in place when they're over budget and returns the scale used.
+// The idle draw is always counted, so a budget below it turns the strip off.
+func (l *PowerLimiter) Limit(colors map[int]animation.Color) float64 {
+	draw := PowerDraw{RequestedMA: l.estimate(colors, 1), Scale: 1}
+	draw.LimitedMA = draw.RequestedMA
+
+	if draw.RequestedMA > l.config.BudgetMA {
+		// Gamma and white extraction make the draw non-linear in the scale, so search for it.
+		low, high := 0.0, 1.0
+		for i := 0; i < limitSearchSteps; i++ {
+			mid := (low + high) / 2
+			if l.estimate(colors, mid) <= l.config.BudgetMA {
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+
+		for channel, c := range colors {
+			colors[channel] = c.Scale(low)
+		}
+		draw.Scale = low
+		draw.LimitedMA = l.estimate(colors, 1)
+	}
+
+	l.lock.Lock()
+	l.draw = draw
+	l.lock.Unlock()
+
+	return draw.Scale
+}
+
+// Draw gets the estimated current of the last limited frame.
+func (l *PowerLimiter) Draw() PowerDraw {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.draw
+}
+
+func (l *PowerLimiter) estimate(colors map[int]animation.Color, scale float64) float64 {
+	levels := 0.0
+	coefficients := []float64{l.config.RedMA, l.config.GreenMA, l.config.BlueMA, l.config.WhiteMA}
+	for _, c := range colors {
+		if scale < 1 {
+			c = c.Scale(scale)
+		}
+
+		for channel, level := range l.pipeline.Channels(c) {
+			levels += float64(level) * coefficients[channel]
+		}
+	}
+
+	return levels/0xFF + l.config.IdleMA*float64(len(colors))
+}
@@ -0,0 +1,83 @@
+package ledoutput
+
+import (
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+)
+
+func createTestLimiter(t *testing.T, budgetMA float64, channelOrder string) *PowerLimiter {
+	pipeline := createTestPipeline(t, &Config{Gamma: 1, ChannelOrder: channelOrder, RedScale: 1, GreenScale: 1, BlueScale: 1})
+
+	return CreatePowerLimiter(&PowerConfig{
+		BudgetMA: budgetMA,
+		RedMA:    20,
+		GreenMA:  20,
+		BlueMA:   20,
+		WhiteMA:  40,
+		IdleMA:   1,
+	}, pipeline)
+}
+
+func createWhiteFrame(count int) map[int]animation.Color {
+	frame := make(map[int]animation.Color, count)
+	for i := 0; i < count; i++ {
+		frame[i] = animation.ColorWhite
+	}
+
+	return frame
+}
+
+func TestPowerLimiterEstimate(t *testing.T) {
+	limiter := createTestLimiter(t, 1000, "GRB")
+
+	if ma := limiter.Estimate(createWhiteFrame(10)); ma != 610 {
+		t.Error("expected 60 mA per white LED plus idle", ma)
+	}
+
+	if ma := limiter.Estimate(map[int]animation.Color{0: animation.ColorBlack}); ma != 1 {
+		t.Error("expected only idle draw", ma)
+	}
+
+	rgbw := createTestLimiter(t, 1000, "GRBW")
+	if ma := rgbw.Estimate(createWhiteFrame(10)); ma != 410 {
+		t.Error("expected white LED draw", ma)
+	}
+}
+
+func TestPowerLimiterUnderBudget(t *testing.T) {
+	limiter := createTestLimiter(t, 1000, "GRB")
+	frame := createWhiteFrame(10)
+
+	if scale := limiter.Limit(frame); scale != 1 || frame[0] != animation.ColorWhite {
+		t.Error("expected frame to be left alone", scale, frame[0])
+	}
+
+	if draw := limiter.Draw(); draw.RequestedMA != 610 || draw.LimitedMA != 610 {
+		t.Error("unexpected draw", draw)
+	}
+}
+
+func TestPowerLimiterOverBudget(t *testing.T) {
+	limiter := createTestLimiter(t, 1000, "GRB")
+	frame := createWhiteFrame(70)
+
+	scale := limiter.Limit(frame)
+	draw := limiter.Draw()
+
+	if draw.RequestedMA != 4270 {
+		t.Error("unexpected requested draw", draw.RequestedMA)
+	}
+
+	if draw.LimitedMA > 1000 || draw.LimitedMA < 950 {
+		t.Error("expected to be just under budget", draw.LimitedMA)
+	}
+
+	if scale != draw.Scale || scale > 0.25 || frame[0] == animation.ColorWhite {
+		t.Error("expected the frame to be scaled down", scale, frame[0])
+	}
+
+	if limiter.Estimate(frame) != draw.LimitedMA {
+		t.Error("expected the limited frame to match the limited draw")
+	}
+}
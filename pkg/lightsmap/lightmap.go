@@ -3,6 +3,7 @@
 package lightsmap
 
 import (
+	"github.com/ataboo/go-metar-blink/pkg/ledoutput"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 	ws2811 "github.com/rpi-ws281x/rpi-ws281x-go"
 )
@@ -10,16 +11,24 @@ import (
 type LightMap struct {
 	stations map[string]*stationrepo.Station
 	device   *ws2811.WS2811
+	pipeline *ledoutput.Pipeline
 }
 
-func CreateLightMap(stations map[string]*stationrepo.Station, brightness byte) (lMap *LightMap, err error) {
+// CreateLightMap creates a map on the strip. The brightness is set by the pipeline so it can change while running.
+func CreateLightMap(stations map[string]*stationrepo.Station, pipeline *ledoutput.Pipeline) (lMap *LightMap, err error) {
 	lMap = &LightMap{
 		stations: stations,
+		pipeline: pipeline,
 	}
 
 	options := ws2811.DefaultOptions
-	options.Channels[0].Brightness = int(brightness)
+	options.Channels[0].Brightness = 0xFF
 	options.Channels[0].LedCount = len(stations)
+	// The pipeline orders the channels so the driver passes them through as is.
+	options.Channels[0].StripeType = ws2811.WS2811StripRGB
+	if pipeline.HasWhite() {
+		options.Channels[0].StripeType = ws2811.SK6812StripRGBW
+	}
 
 	dev, err := ws2811.MakeWS2811(&options)
 	if err != nil {
@@ -38,7 +47,7 @@ func CreateLightMap(stations map[string]*stationrepo.Station, brightness byte) (
 func (l *LightMap) Update() error {
 
 	for _, s := range l.stations {
-		l.device.Leds(0)[s.Ordinal] = s.Color.RGB()
+		l.device.Leds(0)[s.Ordinal] = l.pipeline.Pack(s.Color)
 	}
 
 	return l.device.Render()
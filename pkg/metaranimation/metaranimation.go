@@ -1,6 +1,9 @@
 package metaranimation
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
 	"time"
 
 	"github.com/ataboo/go-metar-blink/pkg/animation"
@@ -10,12 +13,24 @@ import (
 )
 
 const (
-	MetarAnimationFPS    = 50
-	MinBlinkingWindSpeed = 5
-	BasePeriodWindSpeed  = float64(40)
-	MaxPeriodWindSpeed   = float64(80)
+	MetarAnimationFPS   = 50
+	ForecastFrameCount  = 150
+	ForecastDimFactor   = 0.2
+	HeartbeatFrameCount = 200
+	// FadeInterpolation blends between flight rule colors without going muddy, like green to red through brown.
+	FadeInterpolation = animation.InterpolateOKLab
 )
 
+// windBlinkMinFrameCount fits the wind blink's keyframes, which end at frame 20.
+const windBlinkMinFrameCount = 22
+
+// heartbeatColor is dim so the heartbeat can be left on overnight.
+var heartbeatColor = animation.CreateColor(0x40, 0x40, 0x40)
+
+// gustStutterFrames are the alternating off and on frame counts of a gust's stutter.
+// They're uneven so a gust reads as irregular next to the steady blink of sustained wind.
+var gustStutterFrames = []int{3, 5, 2, 4, 4, 2, 3, 6}
+
 type ColorTheme struct {
 	VFR        animation.Color
 	SVFR       animation.Color
@@ -23,15 +38,33 @@ type ColorTheme struct {
 	LIFR       animation.Color
 	Error      animation.Color
 	Brightness byte
+	// StaleBrightness scales the color of stations showing an aging report.
+	StaleBrightness byte
+}
+
+// WindConfig tunes the wind blink. Stations blink once their wind or gusts are above WindyThresholdKts.
+// The blink is once a second at BasePeriodKts and speeds up until MaxKts. Gusting stations stutter
+// GustStutterBlinks times instead.
+type WindConfig struct {
+	WindyThresholdKts float64
+	BasePeriodKts     float64
+	MaxKts            float64
+	GustStutterBlinks int
 }
 
 type MetarAnimationFactory struct {
-	theme *ColorTheme
+	theme    *ColorTheme
+	wind     *WindConfig
+	overlays *OverlayConfig
+	random   *rand.Rand
 }
 
-func CreateMetarAnimationFactory(theme *ColorTheme) *MetarAnimationFactory {
+func CreateMetarAnimationFactory(theme *ColorTheme, wind *WindConfig, overlays *OverlayConfig) *MetarAnimationFactory {
 	return &MetarAnimationFactory{
-		theme: theme,
+		theme:    theme,
+		wind:     wind,
+		overlays: overlays,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -44,6 +77,43 @@ func (f *MetarAnimationFactory) LoadingAnimation(channelCount int) animation.Ani
 	return animation.CreatePulseAnimation(time.Second*2, animation.ColorWhite, animation.ColorBlack, channels, MetarAnimationFPS)
 }
 
+// TransitionAnimation cross-fades to the next animation, keeping the unchanged channels in phase.
+func (f *MetarAnimationFactory) TransitionAnimation(from animation.Animation, to animation.Animation, duration time.Duration, unchanged map[int]bool) animation.Animation {
+	return animation.CreateTransitionAnimation(from, to, duration, unchanged, FadeInterpolation, MetarAnimationFPS)
+}
+
+// QuietAnimation blanks every channel for quiet hours. A heartbeatChannel of 0 or more gives that channel
+// a slow, dim double pulse to show the map is still running.
+func (f *MetarAnimationFactory) QuietAnimation(channelCount int, heartbeatChannel int) animation.Animation {
+	off, err := animation.CreateTrack(2, false, []animation.KeyFrame{{Position: 0, Value: animation.ColorBlack}})
+	if err != nil {
+		logger.LogError("failed to create quiet track: %s", err)
+		panic("aborting")
+	}
+
+	off.ChannelIDs = make([]int, 0, channelCount)
+	for i := 0; i < channelCount; i++ {
+		if i != heartbeatChannel {
+			off.ChannelIDs = append(off.ChannelIDs, i)
+		}
+	}
+	tracks := []*animation.Track{off}
+
+	if heartbeatChannel >= 0 && heartbeatChannel < channelCount {
+		heartbeat, err := f.heartbeatTrack()
+		if err != nil {
+			logger.LogError("failed to create heartbeat track: %s", err)
+			panic("aborting")
+		}
+		heartbeat.ChannelIDs = []int{heartbeatChannel}
+		tracks = append(tracks, heartbeat)
+	}
+
+	return animation.CreateTrackAnimation(tracks, MetarAnimationFPS)
+}
+
+// ConditionsAnimation colors the stations by their flight rules and blinks them with the wind.
+// Weather overlays are drawn on top, then stale stations are dimmed.
 func (f *MetarAnimationFactory) ConditionsAnimation(stations map[string]*stationrepo.Station) animation.Animation {
 	tracks := make([]*animation.Track, len(stations))
 	for _, s := range stations {
@@ -57,9 +127,115 @@ func (f *MetarAnimationFactory) ConditionsAnimation(stations map[string]*station
 		tracks[s.Ordinal] = track
 	}
 
-	trackAnim := animation.CreateTrackAnimation(tracks, MetarAnimationFPS)
+	layers := []*animation.Layer{
+		animation.CreateLayer(animation.CreateTrackAnimation(tracks, MetarAnimationFPS), animation.BlendReplace),
+	}
+
+	for _, overlay := range f.weatherOverlays(stations) {
+		layers = append(layers, animation.CreateLayer(overlay, animation.BlendMax))
+	}
 
-	return trackAnim
+	if stale := f.staleDimming(stations); stale != nil {
+		layers = append(layers, animation.CreateLayer(stale, animation.BlendMultiply))
+	}
+
+	return animation.CreateCompositeAnimation(layers...)
+}
+
+// staleDimming holds stale stations at the stale brightness, for multiplying over their colors.
+func (f *MetarAnimationFactory) staleDimming(stations map[string]*stationrepo.Station) animation.Animation {
+	level := f.theme.StaleBrightness
+	dimmed := animation.CreateColor(level, level, level)
+
+	tracks := make([]*animation.Track, 0)
+	for _, s := range stations {
+		if !s.Stale || s.FlightRules == common.FlightRuleError {
+			continue
+		}
+
+		track, err := animation.CreateTrack(2, false, []animation.KeyFrame{{Position: 0, Value: dimmed}})
+		if err != nil {
+			logger.LogError("failed to create stale track for '%s': %s", s.ID, err)
+			continue
+		}
+		track.ChannelIDs = []int{s.Ordinal}
+		tracks = append(tracks, track)
+	}
+
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	return animation.CreateTrackAnimation(tracks, MetarAnimationFPS)
+}
+
+// ForecastAnimation colors the stations by their forecast flight rules.
+// The colors are steady with a slow dip so they can be told apart from current conditions.
+func (f *MetarAnimationFactory) ForecastAnimation(stations map[string]*stationrepo.Station) animation.Animation {
+	tracks := make([]*animation.Track, len(stations))
+	for _, s := range stations {
+		track, err := f.trackForForecast(s)
+		if err != nil {
+			logger.LogError("failed to create animation track: %s", err)
+			panic("aborting")
+		}
+		track.ChannelIDs = []int{s.Ordinal}
+		tracks[s.Ordinal] = track
+	}
+
+	return animation.CreateTrackAnimation(tracks, MetarAnimationFPS)
+}
+
+// ConditionsStates gets a key for each channel that only changes when the station's conditions animation would.
+func (f *MetarAnimationFactory) ConditionsStates(stations map[string]*stationrepo.Station) map[int]string {
+	states := make(map[int]string, len(stations))
+	for _, s := range stations {
+		gustKts := 0.0
+		if s.Wind != nil && s.Wind.IsGusting() {
+			gustKts = s.Wind.GustKts
+		}
+
+		states[s.Ordinal] = fmt.Sprintf("conditions %s %g %g %t %t %t %t", s.FlightRules, s.WindSpeedKts, gustKts, s.Stale,
+			s.HasWeather("TS"), s.HasWeather("SN"), s.HasFreezingPrecipitation())
+	}
+
+	return states
+}
+
+// ForecastStates gets a key for each channel that only changes when the station's forecast animation would.
+func (f *MetarAnimationFactory) ForecastStates(stations map[string]*stationrepo.Station) map[int]string {
+	states := make(map[int]string, len(stations))
+	for _, s := range stations {
+		states[s.Ordinal] = "forecast " + s.ForecastFlightRules
+	}
+
+	return states
+}
+
+func (f *MetarAnimationFactory) trackForForecast(station *stationrepo.Station) (*animation.Track, error) {
+	if station.ForecastFlightRules == common.FlightRuleError || station.ForecastFlightRules == "" {
+		return f.stationErrorTrack()
+	}
+
+	color := f.colorForFlightRules(station.ForecastFlightRules)
+	dimmed := color.Scale(ForecastDimFactor)
+
+	track, err := animation.CreateTrack(ForecastFrameCount, true, []animation.KeyFrame{
+		{Position: 0, Value: color},
+		{Position: ForecastFrameCount - 40, Value: color},
+		{Position: ForecastFrameCount - 20, Value: dimmed},
+		{Position: ForecastFrameCount - 1, Value: color},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the dip even in brightness.
+	if err := track.SetInterpolation(FadeInterpolation); err != nil {
+		return nil, err
+	}
+
+	return track, nil
 }
 
 func (f *MetarAnimationFactory) trackForConditions(station *stationrepo.Station) (*animation.Track, error) {
@@ -67,38 +243,50 @@ func (f *MetarAnimationFactory) trackForConditions(station *stationrepo.Station)
 		return f.stationErrorTrack()
 	}
 
-	color := f.trackColorForFlightRules(station)
+	color := f.colorForFlightRules(station.FlightRules)
+
+	gusting := station.Wind != nil && station.Wind.IsGusting()
+	peakKts := station.WindSpeedKts
+	if gusting {
+		peakKts = station.Wind.GustKts
+	}
 
-	if station.WindSpeedKts <= float64(MinBlinkingWindSpeed) {
+	if peakKts <= f.wind.WindyThresholdKts {
 		// TODO support single frame animation
 		return animation.CreateTrack(2, false, []animation.KeyFrame{
-			{0, color},
+			{Position: 0, Value: color},
 		})
 
 	}
 
-	frameCount := f.frameCountForWindSpeed(station.WindSpeedKts)
+	frameCount := f.frameCountForWindSpeed(math.Max(station.WindSpeedKts, f.wind.WindyThresholdKts))
+	if gusting {
+		return f.gustTrack(color, frameCount, station.Ordinal)
+	}
+
+	// Fast blinks still need room for the blink's keyframes.
+	if frameCount < windBlinkMinFrameCount {
+		frameCount = windBlinkMinFrameCount
+	}
+
 	return animation.CreateTrack(frameCount, true, []animation.KeyFrame{
-		{5, color},
-		{10, animation.ColorBlack},
-		{15, animation.ColorBlack},
-		{20, color},
-		{frameCount - 1, color},
+		{Position: 5, Value: color},
+		{Position: 10, Value: animation.ColorBlack},
+		{Position: 15, Value: animation.ColorBlack},
+		{Position: 20, Value: color},
+		{Position: frameCount - 1, Value: color},
 	})
 }
 
 // Two quick blinks in 1 second, off for 1 second
 func (f *MetarAnimationFactory) stationErrorTrack() (*animation.Track, error) {
 	t, err := animation.CreateTrack(100, true, []animation.KeyFrame{
-		{0, animation.ColorBlack},
-		{4, animation.ColorRed},
-		{9, animation.ColorRed},
-		{19, animation.ColorBlack},
-		{24, animation.ColorBlack},
-		{29, animation.ColorRed},
-		{34, animation.ColorRed},
-		{39, animation.ColorBlack},
-		{99, animation.ColorBlack},
+		{Position: 0, Value: animation.ColorBlack},
+		{Position: 4, Value: animation.ColorRed, Easing: animation.EaseOutQuad},
+		{Position: 19, Value: animation.ColorBlack, Easing: animation.EaseInCubic},
+		{Position: 29, Value: animation.ColorRed, Easing: animation.EaseInCubic},
+		{Position: 39, Value: animation.ColorBlack, Easing: animation.EaseInCubic},
+		{Position: 99, Value: animation.ColorBlack},
 	})
 	if err != nil {
 		return nil, err
@@ -107,8 +295,20 @@ func (f *MetarAnimationFactory) stationErrorTrack() (*animation.Track, error) {
 	return t, nil
 }
 
-func (f *MetarAnimationFactory) trackColorForFlightRules(station *stationrepo.Station) animation.Color {
-	switch station.FlightRules {
+// Two soft beats then a long rest, every 4 seconds.
+func (f *MetarAnimationFactory) heartbeatTrack() (*animation.Track, error) {
+	return animation.CreateTrack(HeartbeatFrameCount, true, []animation.KeyFrame{
+		{Position: 0, Value: animation.ColorBlack},
+		{Position: 8, Value: heartbeatColor, Easing: animation.EaseOutQuad},
+		{Position: 20, Value: animation.ColorBlack, Easing: animation.EaseInOutSine},
+		{Position: 28, Value: heartbeatColor, Easing: animation.EaseOutQuad},
+		{Position: 50, Value: animation.ColorBlack, Easing: animation.EaseInOutSine},
+		{Position: HeartbeatFrameCount - 1, Value: animation.ColorBlack},
+	})
+}
+
+func (f *MetarAnimationFactory) colorForFlightRules(flightRules string) animation.Color {
+	switch flightRules {
 	case common.FlightRuleIFR:
 		return f.theme.IFR
 	case common.FlightRuleLIFR:
@@ -122,17 +322,42 @@ func (f *MetarAnimationFactory) trackColorForFlightRules(station *stationrepo.St
 	case common.FlightRuleMVFR:
 		return f.theme.SVFR
 	default:
-		logger.LogWarn("flight rule '%s' has no color", station.FlightRules)
+		logger.LogWarn("flight rule '%s' has no color", flightRules)
 		return animation.ColorRed
 	}
 }
 
 func (f *MetarAnimationFactory) frameCountForWindSpeed(windSpeedKts float64) int {
-	if windSpeedKts > MaxPeriodWindSpeed {
-		windSpeedKts = MaxPeriodWindSpeed
+	if windSpeedKts > f.wind.MaxKts {
+		windSpeedKts = f.wind.MaxKts
 	}
 
-	periodMultiplier := BasePeriodWindSpeed / windSpeedKts
+	periodMultiplier := f.wind.BasePeriodKts / windSpeedKts
 
 	return int(MetarAnimationFPS * periodMultiplier)
 }
+
+// gustTrack snaps off and on in an uneven burst once per period. The burst pattern is offset by the station's
+// ordinal so neighbouring gusting stations don't stutter in step.
+func (f *MetarAnimationFactory) gustTrack(color animation.Color, frameCount int, ordinal int) (*animation.Track, error) {
+	keyFrames := []animation.KeyFrame{{Position: 0, Value: color}}
+	start := 5
+	for i := 0; i < f.wind.GustStutterBlinks; i++ {
+		offFrames := gustStutterFrames[(ordinal+2*i)%len(gustStutterFrames)]
+		onFrames := gustStutterFrames[(ordinal+2*i+1)%len(gustStutterFrames)]
+
+		keyFrames = append(keyFrames,
+			animation.KeyFrame{Position: start + 1, Value: animation.ColorBlack, Easing: animation.EaseStep},
+			animation.KeyFrame{Position: start + 2 + offFrames, Value: color, Easing: animation.EaseStep},
+		)
+		start += 2 + offFrames + onFrames
+	}
+
+	// Leave a steady gap after the burst so it doesn't blur into the next one.
+	if minFrameCount := start + MetarAnimationFPS/2; frameCount < minFrameCount {
+		frameCount = minFrameCount
+	}
+	keyFrames = append(keyFrames, animation.KeyFrame{Position: frameCount - 1, Value: color})
+
+	return animation.CreateTrack(frameCount, true, keyFrames)
+}
@@ -0,0 +1,223 @@
+package metaranimation
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+func createTestFactory() *MetarAnimationFactory {
+	return CreateMetarAnimationFactory(&ColorTheme{VFR: animation.ColorGreen}, &WindConfig{
+		WindyThresholdKts: 10,
+		BasePeriodKts:     40,
+		MaxKts:            80,
+		GustStutterBlinks: 3,
+	}, &OverlayConfig{
+		Thunderstorm: WeatherOverlay{Enabled: true, Color: animation.ColorWhite},
+		Snow:         WeatherOverlay{Enabled: true, Color: animation.ColorBlue},
+		Freezing:     WeatherOverlay{Enabled: false, Color: animation.ColorMagenta},
+	})
+}
+
+func countBlinks(track *animation.Track) int {
+	blinks := 0
+	lit := true
+	for i := 0; i < track.GetLength(); i++ {
+		track.Seek(i)
+		if on := track.Value() != animation.ColorBlack; on != lit {
+			if !on {
+				blinks++
+			}
+			lit = on
+		}
+	}
+
+	return blinks
+}
+
+func TestWindTracks(t *testing.T) {
+	f := createTestFactory()
+
+	calm := &stationrepo.Station{FlightRules: common.FlightRuleVFR, WindSpeedKts: 8}
+	track, err := f.trackForConditions(calm)
+	if err != nil || track.IsLooping() || countBlinks(track) != 0 {
+		t.Error("expected steady track", err)
+	}
+
+	steady := &stationrepo.Station{FlightRules: common.FlightRuleVFR, WindSpeedKts: 20, Wind: &metarclient.Wind{SpeedKts: 20}}
+	track, err = f.trackForConditions(steady)
+	if err != nil || track.GetLength() != 100 || countBlinks(track) != 1 {
+		t.Error("expected a single blink every 2 seconds", err)
+	}
+
+	// Gusts above the threshold blink even when the sustained wind is light.
+	gusty := &stationrepo.Station{FlightRules: common.FlightRuleVFR, WindSpeedKts: 8, Wind: &metarclient.Wind{SpeedKts: 8, GustKts: 18}}
+	track, err = f.trackForConditions(gusty)
+	if err != nil || countBlinks(track) != 3 {
+		t.Error("expected a gust stutter", err)
+	}
+
+	gusty.Ordinal = 1
+	offset, _ := f.trackForConditions(gusty)
+	if offset.GetLength() != track.GetLength() || countBlinks(offset) != 3 {
+		t.Error("expected the same stutter with a different pattern")
+	}
+}
+
+func TestGustTrackFitsFastWind(t *testing.T) {
+	f := createTestFactory()
+	f.wind.GustStutterBlinks = common.MaxGustStutterBlinks
+
+	track, err := f.gustTrack(animation.ColorGreen, f.frameCountForWindSpeed(80), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if countBlinks(track) != common.MaxGustStutterBlinks {
+		t.Error("unnexpected blink count", countBlinks(track))
+	}
+}
+
+func TestWindTrackFitsMaxSpeed(t *testing.T) {
+	f := createTestFactory()
+	f.wind.BasePeriodKts = 10
+	f.wind.MaxKts = 50
+
+	windy := &stationrepo.Station{FlightRules: common.FlightRuleVFR, WindSpeedKts: 45, Wind: &metarclient.Wind{SpeedKts: 45}}
+	track, err := f.trackForConditions(windy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if track.GetLength() != windBlinkMinFrameCount || countBlinks(track) != 1 {
+		t.Error("expected a single blink in the shortest track", track.GetLength())
+	}
+}
+
+func TestWeatherOverlays(t *testing.T) {
+	f := createTestFactory()
+	f.random = rand.New(rand.NewSource(1))
+
+	stations := map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", Ordinal: 0, FlightRules: common.FlightRuleVFR, Weather: []*metarclient.WeatherPhenomenon{
+			{Descriptor: "TS", Phenomena: []string{"RA"}},
+		}},
+		"CYYC": {ID: "CYYC", Ordinal: 1, FlightRules: common.FlightRuleVFR, Weather: []*metarclient.WeatherPhenomenon{
+			{Intensity: metarclient.IntensityLight, Phenomena: []string{"SN"}},
+			{Descriptor: "FZ", Phenomena: []string{"DZ"}},
+		}},
+		"CYQF": {ID: "CYQF", Ordinal: 2, FlightRules: common.FlightRuleVFR, Weather: []*metarclient.WeatherPhenomenon{
+			{Intensity: metarclient.IntensityVicinity, Descriptor: "TS"},
+		}},
+	}
+
+	overlays := f.weatherOverlays(stations)
+	if len(overlays) != 2 {
+		t.Fatal("expected snow and lightning overlays with freezing disabled", len(overlays))
+	}
+
+	flashes := 0
+	for i := 0; i < LightningMaxFrames; i++ {
+		values := make(map[int]animation.Color)
+		for _, o := range overlays {
+			o.Start()
+			o.Step(values)
+		}
+
+		if _, ok := values[2]; ok {
+			t.Fatal("expected no overlay for vicinity weather")
+		}
+
+		if values[0] == animation.ColorWhite {
+			flashes++
+		}
+
+		if values[1] == animation.ColorBlack || values[1].B() > 0x80 || values[1].R() != 0 {
+			t.Fatal("expected a dim blue shimmer", values[1])
+		}
+	}
+
+	if flashes == 0 {
+		t.Error("expected lightning flashes")
+	}
+
+	f.overlays.Freezing.Enabled = true
+	if overlays := f.weatherOverlays(stations); len(overlays) != 3 {
+		t.Error("expected freezing overlay", len(overlays))
+	}
+}
+
+func TestConditionsAnimationDimsStaleStations(t *testing.T) {
+	f := createTestFactory()
+	f.theme.StaleBrightness = 0x40
+
+	anim := f.ConditionsAnimation(map[string]*stationrepo.Station{
+		"CYEG": {ID: "CYEG", Ordinal: 0, FlightRules: common.FlightRuleVFR},
+		"CYYC": {ID: "CYYC", Ordinal: 1, FlightRules: common.FlightRuleVFR, Stale: true},
+	})
+
+	values := make(map[int]animation.Color)
+	anim.GetValues(values)
+	if values[0] != animation.ColorGreen || values[1] != animation.ColorGreen.Scale(float64(0x40)/0xFF) {
+		t.Error("expected only the stale station dimmed", values)
+	}
+}
+
+func TestConditionsStates(t *testing.T) {
+	f := createTestFactory()
+	stations := map[string]*stationrepo.Station{
+		"CYEG": {Ordinal: 0, FlightRules: common.FlightRuleVFR, WindSpeedKts: 12, Wind: &metarclient.Wind{SpeedKts: 12}},
+		"CYYC": {Ordinal: 1, FlightRules: common.FlightRuleVFR},
+	}
+
+	before := f.ConditionsStates(stations)
+	stations["CYEG"].Wind.GustKts = 22
+	after := f.ConditionsStates(stations)
+
+	if before[0] == after[0] {
+		t.Error("expected gust to change the state")
+	}
+
+	if before[1] != after[1] {
+		t.Error("expected unchanged station to keep its state")
+	}
+
+	if f.ForecastStates(stations)[1] == after[1] {
+		t.Error("expected forecast and conditions states to differ")
+	}
+}
+
+func TestQuietAnimation(t *testing.T) {
+	f := createTestFactory()
+
+	anim := f.QuietAnimation(3, 1)
+	anim.Start()
+
+	values := map[int]animation.Color{0: animation.ColorWhite, 1: animation.ColorWhite, 2: animation.ColorWhite}
+	beat := false
+	for i := 0; i < HeartbeatFrameCount; i++ {
+		anim.Step(values)
+		if values[0] != animation.ColorBlack || values[2] != animation.ColorBlack {
+			t.Fatal("expected other channels off", values)
+		}
+		if values[1].R() > heartbeatColor.R() {
+			t.Fatal("expected heartbeat to stay dim", values[1])
+		}
+		beat = beat || values[1] != animation.ColorBlack
+	}
+
+	if !beat {
+		t.Error("expected heartbeat channel to pulse")
+	}
+
+	anim = f.QuietAnimation(3, -1)
+	anim.Start()
+	anim.Step(values)
+	if values[1] != animation.ColorBlack {
+		t.Error("expected every channel off without a heartbeat", values)
+	}
+}
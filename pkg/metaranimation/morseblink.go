@@ -25,8 +25,8 @@ func CreateMorseAnimation(str string) (*animation.Track, error) {
 
 	position := InterWordSpace
 	keyFrames := []animation.KeyFrame{
-		{0, off},
-		{position, off},
+		{Position: 0, Value: off},
+		{Position: position, Value: off},
 	}
 
 	morseChars := stringToMorseChars(str)
@@ -34,7 +34,7 @@ func CreateMorseAnimation(str string) (*animation.Track, error) {
 	for _, m := range morseChars {
 		if m == "" {
 			position += InterWordSpace
-			keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+			keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 			continue
 		}
 
@@ -42,33 +42,33 @@ func CreateMorseAnimation(str string) (*animation.Track, error) {
 			switch c {
 			case '*':
 				position++
-				keyFrames = append(keyFrames, animation.KeyFrame{position, on})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: on})
 				position += DitLength
-				keyFrames = append(keyFrames, animation.KeyFrame{position, on})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: on})
 				position++
-				keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 				position += IntraCharSpace
-				keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 			case '-':
 				position++
-				keyFrames = append(keyFrames, animation.KeyFrame{position, on})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: on})
 				position += DahLength
-				keyFrames = append(keyFrames, animation.KeyFrame{position, on})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: on})
 				position++
-				keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 				position += IntraCharSpace
-				keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+				keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 			default:
 				return nil, fmt.Errorf("unsuported morse char: %s", m)
 			}
 		}
 
 		position += InterCharSpace - IntraCharSpace
-		keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+		keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 	}
 
 	position += InterWordSpace * 4
-	keyFrames = append(keyFrames, animation.KeyFrame{position, off})
+	keyFrames = append(keyFrames, animation.KeyFrame{Position: position, Value: off})
 
 	return animation.CreateTrack(position+1, true, keyFrames)
 }
@@ -171,7 +171,7 @@ func charToMorseString(c rune) morseChar {
 	case ' ':
 		return ""
 	default:
-		logger.LogError("morse char not supported '%s'", c)
+		logger.LogError("morse char not supported '%c'", c)
 		return ""
 	}
 }
@@ -0,0 +1,123 @@
+package metaranimation
+
+import (
+	"github.com/ataboo/go-metar-blink/pkg/animation"
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+const (
+	LightningMinFrames = MetarAnimationFPS * 3
+	LightningMaxFrames = MetarAnimationFPS * 7
+	ShimmerFrameCount  = MetarAnimationFPS * 3
+	ShimmerStepFrames  = 15
+	FreezingFrameCount = MetarAnimationFPS * 2
+)
+
+// WeatherOverlay is drawn over the flight category color of stations reporting the weather when enabled.
+type WeatherOverlay struct {
+	Enabled bool
+	Color   animation.Color
+}
+
+// OverlayConfig sets the overlays for thunderstorms, snow, and freezing rain or drizzle.
+type OverlayConfig struct {
+	Thunderstorm WeatherOverlay
+	Snow         WeatherOverlay
+	Freezing     WeatherOverlay
+}
+
+// weatherOverlays gets an animation for each overlay shown on at least one station.
+func (f *MetarAnimationFactory) weatherOverlays(stations map[string]*stationrepo.Station) []animation.Animation {
+	lightning := make([]*animation.Track, 0)
+	shimmer := make([]*animation.Track, 0)
+	freezing := make([]*animation.Track, 0)
+
+	for _, s := range stations {
+		if s.FlightRules == common.FlightRuleError {
+			continue
+		}
+
+		if f.overlays.Thunderstorm.Enabled && s.HasWeather("TS") {
+			lightning = f.appendOverlayTrack(lightning, s, f.lightningTrack)
+		}
+
+		if f.overlays.Snow.Enabled && s.HasWeather("SN") {
+			shimmer = f.appendOverlayTrack(shimmer, s, f.shimmerTrack)
+		}
+
+		if f.overlays.Freezing.Enabled && s.HasFreezingPrecipitation() {
+			freezing = f.appendOverlayTrack(freezing, s, f.freezingTrack)
+		}
+	}
+
+	overlays := make([]animation.Animation, 0)
+	for _, tracks := range [][]*animation.Track{shimmer, freezing, lightning} {
+		if len(tracks) > 0 {
+			overlays = append(overlays, animation.CreateTrackAnimation(tracks, MetarAnimationFPS))
+		}
+	}
+
+	return overlays
+}
+
+func (f *MetarAnimationFactory) appendOverlayTrack(tracks []*animation.Track, station *stationrepo.Station, create func() (*animation.Track, error)) []*animation.Track {
+	track, err := create()
+	if err != nil {
+		logger.LogError("failed to create overlay track for '%s': %s", station.ID, err)
+		return tracks
+	}
+	track.ChannelIDs = []int{station.Ordinal}
+
+	return append(tracks, track)
+}
+
+// lightningTrack flickers once or twice at random points in a loop of random length so stations don't flash together.
+func (f *MetarAnimationFactory) lightningTrack() (*animation.Track, error) {
+	color := f.overlays.Thunderstorm.Color
+	frameCount := LightningMinFrames + f.random.Intn(LightningMaxFrames-LightningMinFrames)
+
+	keyFrames := []animation.KeyFrame{{Position: 0, Value: animation.ColorBlack}}
+	flashCount := 1 + f.random.Intn(2)
+	span := frameCount / flashCount
+	for i := 0; i < flashCount; i++ {
+		start := i*span + 1 + f.random.Intn(span-8)
+		keyFrames = append(keyFrames,
+			animation.KeyFrame{Position: start, Value: animation.ColorBlack},
+			animation.KeyFrame{Position: start + 1, Value: color},
+			animation.KeyFrame{Position: start + 2, Value: color.Scale(0.3)},
+			animation.KeyFrame{Position: start + 3, Value: color},
+			animation.KeyFrame{Position: start + 5, Value: animation.ColorBlack},
+		)
+	}
+	keyFrames = append(keyFrames, animation.KeyFrame{Position: frameCount - 1, Value: animation.ColorBlack})
+
+	return animation.CreateTrack(frameCount, true, keyFrames)
+}
+
+// shimmerTrack drifts between random dim levels of the snow color.
+func (f *MetarAnimationFactory) shimmerTrack() (*animation.Track, error) {
+	color := f.overlays.Snow.Color
+
+	keyFrames := make([]animation.KeyFrame, 0, ShimmerFrameCount/ShimmerStepFrames)
+	for pos := 0; pos < ShimmerFrameCount; pos += ShimmerStepFrames {
+		keyFrames = append(keyFrames, animation.KeyFrame{Position: pos, Value: color.Scale(0.15 + 0.35*f.random.Float64())})
+	}
+
+	return animation.CreateTrack(ShimmerFrameCount, true, keyFrames)
+}
+
+// freezingTrack pulses strong then weak, then rests.
+func (f *MetarAnimationFactory) freezingTrack() (*animation.Track, error) {
+	color := f.overlays.Freezing.Color
+
+	return animation.CreateTrack(FreezingFrameCount, true, []animation.KeyFrame{
+		{Position: 0, Value: animation.ColorBlack},
+		{Position: 8, Value: color},
+		{Position: 16, Value: animation.ColorBlack},
+		{Position: 24, Value: color.Scale(0.5)},
+		{Position: 32, Value: animation.ColorBlack},
+		{Position: FreezingFrameCount - 1, Value: animation.ColorBlack},
+	})
+}
@@ -0,0 +1,454 @@
+package metarclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+var _ MetarClient = (*aviationWeatherAPIClient)(nil)
+var _ StationDiscoverer = (*aviationWeatherAPIClient)(nil)
+var _ StationSubsetReporter = (*aviationWeatherAPIClient)(nil)
+
+const AviationWeatherAPIEndPoint = "https://aviationweather.gov/api/data"
+
+// https://aviationweather.gov/data/api/#/Data/dataMetars
+type aviationWeatherAPIMetar struct {
+	StationID      string                     `json:"icaoId"`
+	ReportTime     string                     `json:"reportTime"`
+	ObsTime        int64                      `json:"obsTime"`
+	WindSpeedKts   *float64                   `json:"wspd"`
+	WindGustKts    *float64                   `json:"wgst"`
+	WindDirection  interface{}                `json:"wdir"`
+	FlightCategory string                     `json:"fltCat"`
+	RawText        string                     `json:"rawOb"`
+	Clouds         []*aviationWeatherAPICloud `json:"clouds"`
+	VertVisFt      *int                       `json:"vertVis"`
+}
+
+type aviationWeatherAPICloud struct {
+	Cover   string `json:"cover"`
+	BaseFt  *int   `json:"base"`
+	CloudID string `json:"type"`
+}
+
+// skyLayers gets every reported layer, with an obscured sky as vertical visibility.
+func (m *aviationWeatherAPIMetar) skyLayers() []*SkyLayer {
+	layers := make([]*SkyLayer, len(m.Clouds))
+	for i, c := range m.Clouds {
+		layers[i] = &SkyLayer{
+			Cover:     c.Cover,
+			CloudType: c.CloudID,
+		}
+
+		if c.BaseFt != nil {
+			layers[i].BaseFtAGL = *c.BaseFt
+			layers[i].HasBase = true
+		}
+	}
+
+	return providerSkyLayers(layers, m.VertVisFt)
+}
+
+// https://aviationweather.gov/data/api/#/Data/dataStationInfo
+type aviationWeatherAPIStation struct {
+	StationID string   `json:"icaoId"`
+	Site      string   `json:"site"`
+	Latitude  float64  `json:"lat"`
+	Longitude float64  `json:"lon"`
+	Elevation float64  `json:"elev"`
+	State     string   `json:"state"`
+	Country   string   `json:"country"`
+	SiteTypes []string `json:"siteType"`
+}
+
+// https://aviationweather.gov/data/api/#/Data/dataTaf
+type aviationWeatherAPITaf struct {
+	StationID string `json:"icaoId"`
+	RawText   string `json:"rawTAF"`
+}
+
+type aviationWeatherAPIError struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type aviationWeatherAPIClient struct {
+	settings *Settings
+	endPoint string
+	http     *httpFetcher
+	chunks   *chunkFetcher
+}
+
+func newAviationWeatherAPIClient(settings *Settings, endPoint string) MetarClient {
+	sort.Strings(settings.StationIDs)
+
+	if settings.FlightCategoryRules == nil {
+		settings.FlightCategoryRules = FAAFlightCategoryRules()
+	}
+
+	return &aviationWeatherAPIClient{
+		settings: settings,
+		endPoint: endPoint,
+		http:     newHTTPFetcher(settings),
+		chunks:   newChunkFetcher(settings),
+	}
+}
+
+func (c *aviationWeatherAPIClient) GetReports(ctx context.Context) (reports map[string]*MetarReport, err error) {
+	return c.GetReportsFor(ctx, c.settings.StationIDs)
+}
+
+func (c *aviationWeatherAPIClient) GetReportsFor(ctx context.Context, stationIDs []string) (reports map[string]*MetarReport, err error) {
+	metars := make(map[string]*aviationWeatherAPIMetar, len(stationIDs))
+
+	responses, err := c.fetchChunks(ctx, "metar", stationIDs, func(chunkIDs []string, responseBytes []byte) error {
+		chunkMetars, err := c.parseMetarBytes(responseBytes)
+		if err != nil {
+			return err
+		}
+
+		for _, stationID := range chunkIDs {
+			if m, ok := chunkMetars[stationID]; ok {
+				metars[stationID] = m
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes := chunkResponseBytes(responses, func() ([]byte, error) {
+		received := make([]*aviationWeatherAPIMetar, 0, len(metars))
+		for _, stationID := range stationIDs {
+			if m, ok := metars[stationID]; ok {
+				received = append(received, m)
+			}
+		}
+
+		return json.Marshal(received)
+	})
+	if responseBytes != nil {
+		common.CacheToFile("last_aviation_weather_api_response.json", responseBytes)
+		recordResponse(c.settings.RecordDir, common.AviationWeatherAPIMetarStrategy, "json", responseBytes)
+	}
+
+	return c.buildReports(stationIDs, metars), nil
+}
+
+func (c *aviationWeatherAPIClient) buildReports(stationIDs []string, metars map[string]*aviationWeatherAPIMetar) map[string]*MetarReport {
+	now := c.settings.clock().Now()
+	reports := make(map[string]*MetarReport, len(stationIDs))
+	for _, stationID := range stationIDs {
+		m, ok := metars[stationID]
+		if !ok {
+			logger.LogWarn("failed to receive data for station '%s'", stationID)
+			reports[stationID] = errorReport(stationID)
+			continue
+		}
+
+		report := &MetarReport{
+			StationID:   m.StationID,
+			FlightRules: m.FlightCategory,
+			RawText:     m.RawText,
+			Decoded:     decodeRawText(m.StationID, m.RawText),
+		}
+
+		resolveSkyLayers(report, m.skyLayers())
+		resolveWind(report, providerWind(m.WindSpeedKts, m.WindGustKts, providerWindDirection(m.WindDirection)))
+
+		if m.ObsTime > 0 {
+			report.ObservationTime = time.Unix(m.ObsTime, 0).UTC()
+		} else {
+			resolveObservationTime(report, m.ReportTime, now)
+		}
+
+		resolveFlightRules(report, c.settings.FlightCategoryRules)
+		reports[stationID] = report
+	}
+
+	return reports
+}
+
+func (c *aviationWeatherAPIClient) GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error) {
+	stations := make(map[string]*aviationWeatherAPIStation, len(c.settings.StationIDs))
+
+	_, err = c.fetchChunks(ctx, "stationinfo", c.settings.StationIDs, func(stationIDs []string, responseBytes []byte) error {
+		chunkStations, err := c.parseStationBytes(responseBytes)
+		if err != nil {
+			return err
+		}
+
+		for _, stationID := range stationIDs {
+			if s, ok := chunkStations[stationID]; ok {
+				stations[stationID] = s
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	positions = make(map[string]*MetarPosition, len(c.settings.StationIDs))
+	for _, stationID := range c.settings.StationIDs {
+		s, ok := stations[stationID]
+		if !ok {
+			logger.LogWarn("failed to receive station info for '%s'", stationID)
+			positions[stationID] = &MetarPosition{
+				Error:     true,
+				StationID: stationID,
+			}
+			continue
+		}
+
+		positions[stationID] = &MetarPosition{
+			StationID: s.StationID,
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Elevation: s.Elevation,
+		}
+	}
+
+	return positions, nil
+}
+
+func (c *aviationWeatherAPIClient) GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error) {
+	rawTexts := make(map[string]string, len(c.settings.StationIDs))
+	tafs := make([]*aviationWeatherAPITaf, 0, len(c.settings.StationIDs))
+
+	responses, err := c.fetchChunks(ctx, "taf", c.settings.StationIDs, func(stationIDs []string, responseBytes []byte) error {
+		chunkTafs, err := c.parseTafBytes(responseBytes)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range chunkTafs {
+			// Keep the first forecast as the api orders the most recent first.
+			if _, ok := rawTexts[t.StationID]; !ok {
+				rawTexts[t.StationID] = t.RawText
+				tafs = append(tafs, t)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes := chunkResponseBytes(responses, func() ([]byte, error) {
+		return json.Marshal(tafs)
+	})
+	if responseBytes != nil {
+		common.CacheToFile("last_aviation_weather_api_taf_response.json", responseBytes)
+	}
+
+	return buildTafReports(c.settings.StationIDs, rawTexts, c.settings.clock().Now(), c.settings.FlightCategoryRules), nil
+}
+
+func (c *aviationWeatherAPIClient) parseTafBytes(responseBytes []byte) ([]*aviationWeatherAPITaf, error) {
+	tafs := make([]*aviationWeatherAPITaf, 0)
+	if err := json.Unmarshal(responseBytes, &tafs); err != nil {
+		logger.LogError("failed to parse aviation weather api taf response")
+		return nil, err
+	}
+
+	return tafs, nil
+}
+
+// DiscoverStations gets the stations in the bounds of the area from the station info end point.
+func (c *aviationWeatherAPIClient) DiscoverStations(ctx context.Context, area *DiscoveryArea) ([]*DiscoveredStation, error) {
+	bounds, err := area.Bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]*DiscoveredStation, 0)
+	for _, box := range splitAtAntimeridian(bounds) {
+		u, err := url.Parse(strings.TrimSuffix(c.endPoint, "/") + "/stationinfo")
+		if err != nil {
+			return nil, err
+		}
+
+		q := u.Query()
+		q.Set("bbox", fmt.Sprintf("%.4f,%.4f,%.4f,%.4f", box.MinLatitude, box.MinLongitude, box.MaxLatitude, box.MaxLongitude))
+		q.Set("format", "json")
+		u.RawQuery = q.Encode()
+
+		response, err := c.http.get(ctx, u.String())
+		if err != nil {
+			return nil, err
+		}
+
+		responseBytes, err := c.readResponse(response)
+		if err != nil {
+			return nil, err
+		}
+
+		stations, err := c.parseStationBytes(responseBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range stations {
+			discovered = append(discovered, &DiscoveredStation{
+				StationID: s.StationID,
+				Name:      s.Site,
+				Latitude:  s.Latitude,
+				Longitude: s.Longitude,
+				Elevation: s.Elevation,
+				Country:   s.Country,
+				Region:    s.State,
+				SiteTypes: s.SiteTypes,
+			})
+		}
+	}
+
+	return discovered, nil
+}
+
+func (c *aviationWeatherAPIClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
+	go func() {
+		reports, err := c.GetReports(ctx)
+		handler(reports, err)
+	}()
+}
+
+func (c *aviationWeatherAPIClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
+	go func() {
+		positions, err := c.GetStationPositions(ctx)
+		handler(positions, err)
+	}()
+}
+
+func (c *aviationWeatherAPIClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	go func() {
+		forecasts, err := c.GetForecasts(ctx)
+		handler(forecasts, err)
+	}()
+}
+
+// fetchChunks requests the data type for each chunk of stations and passes the responses to the handler one at a time.
+// The raw responses are returned in chunk order, nil for those that failed.
+func (c *aviationWeatherAPIClient) fetchChunks(ctx context.Context, dataType string, stationIDs []string, handler func(stationIDs []string, responseBytes []byte) error) ([][]byte, error) {
+	chunks := c.chunks.split(stationIDs)
+	responses := make([][]byte, len(chunks))
+	lock := sync.Mutex{}
+
+	err := c.chunks.fetch(ctx, chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		endPoint, err := c.buildQueryURL(dataType, stationIDs)
+		if err != nil {
+			return err
+		}
+
+		response, err := c.http.get(ctx, endPoint.String())
+		if err != nil {
+			return err
+		}
+
+		responseBytes, err := c.readResponse(response)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if err := handler(stationIDs, responseBytes); err != nil {
+			return err
+		}
+		responses[chunk] = responseBytes
+
+		return nil
+	})
+
+	return responses, err
+}
+
+func (c *aviationWeatherAPIClient) buildQueryURL(dataType string, stationIDs []string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.endPoint, "/") + "/" + dataType)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("ids", strings.Join(stationIDs, ","))
+	q.Set("format", "json")
+	u.RawQuery = q.Encode()
+
+	return u, nil
+}
+
+func (c *aviationWeatherAPIClient) readResponse(response *http.Response) ([]byte, error) {
+	defer response.Body.Close()
+
+	responseBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return responseBytes, nil
+	case http.StatusNoContent:
+		// The API responds with no content when none of the stations have data.
+		return []byte("[]"), nil
+	default:
+		apiErr := aviationWeatherAPIError{}
+		if json.Unmarshal(responseBytes, &apiErr) == nil && apiErr.Error != "" {
+			logger.LogError("errors from aviation weather api: %s", apiErr.Error)
+			return nil, fmt.Errorf("invalid response %d: %s", response.StatusCode, apiErr.Error)
+		}
+
+		return nil, fmt.Errorf("invalid response %d", response.StatusCode)
+	}
+}
+
+func (c *aviationWeatherAPIClient) parseMetarBytes(responseBytes []byte) (map[string]*aviationWeatherAPIMetar, error) {
+	metars := make([]*aviationWeatherAPIMetar, 0)
+	err := json.Unmarshal(responseBytes, &metars)
+	if err != nil {
+		logger.LogError("failed to parse aviation weather api response")
+		return nil, err
+	}
+
+	metarMap := make(map[string]*aviationWeatherAPIMetar, len(metars))
+	for _, m := range metars {
+		// Keep the first report as the api orders the most recent first.
+		if _, ok := metarMap[m.StationID]; !ok {
+			metarMap[m.StationID] = m
+		}
+	}
+
+	return metarMap, nil
+}
+
+func (c *aviationWeatherAPIClient) parseStationBytes(responseBytes []byte) (map[string]*aviationWeatherAPIStation, error) {
+	stations := make([]*aviationWeatherAPIStation, 0)
+	err := json.Unmarshal(responseBytes, &stations)
+	if err != nil {
+		logger.LogError("failed to parse aviation weather api station response")
+		return nil, err
+	}
+
+	stationMap := make(map[string]*aviationWeatherAPIStation, len(stations))
+	for _, s := range stations {
+		stationMap[s.StationID] = s
+	}
+
+	return stationMap, nil
+}
@@ -0,0 +1,278 @@
+package metarclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+func TestAviationWeatherAPIUrlBuilding(t *testing.T) {
+	client, err := CreateMetarClient(&Settings{
+		StationIDs: []string{"CYYC", "CYEG"},
+		Strategy:   common.AviationWeatherAPIMetarStrategy,
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	apiClient := client.(*aviationWeatherAPIClient)
+
+	endPoint, err := apiClient.buildQueryURL("metar", apiClient.settings.StationIDs)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if endPoint.Path != "/api/data/metar" {
+		t.Error("unnexpected path", endPoint.Path)
+	}
+
+	if ids := endPoint.Query().Get("ids"); ids != "CYEG,CYYC" {
+		t.Error("unnexpected ids", ids)
+	}
+
+	if format := endPoint.Query().Get("format"); format != "json" {
+		t.Error("unnexpected format", format)
+	}
+}
+
+func TestAviationWeatherAPIGetReports(t *testing.T) {
+	server := createAviationWeatherAPITestServer(t)
+	defer server.Close()
+
+	client := newAviationWeatherAPIClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC", "CABC"},
+		Strategy:   common.AviationWeatherAPIMetarStrategy,
+	}, server.URL+"/api/data")
+
+	reports, err := client.GetReports(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 3 {
+		t.Error("unnexpected report count", len(reports))
+	}
+
+	cyeg := reports["CYEG"]
+	if cyeg.Error || cyeg.FlightRules != common.FlightRuleVFR || cyeg.WindSpeedKts != 8 {
+		t.Errorf("unnexpected CYEG report %+v", cyeg)
+	}
+
+	if cyeg.SkyCover != SkyCoverFew || cyeg.CloudBaseFtAGL != 3000 {
+		t.Errorf("unnexpected CYEG sky cover %+v", cyeg)
+	}
+
+	if len(cyeg.SkyLayers) != 2 || !cyeg.HasCeiling || cyeg.CeilingFtAGL != 20000 {
+		t.Errorf("unnexpected CYEG sky layers %+v", cyeg.SkyLayers)
+	}
+
+	if cyeg.Decoded == nil || cyeg.Decoded.Wind.DirectionDeg != 210 {
+		t.Error("expected decoded raw text")
+	}
+
+	if !cyeg.ObservationTime.Equal(time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)) {
+		t.Error("unnexpected observation time", cyeg.ObservationTime)
+	}
+
+	cyyc := reports["CYYC"]
+	if cyyc.Error || cyyc.FlightRules != common.FlightRuleIFR {
+		t.Errorf("expected computed IFR for CYYC %+v", cyyc)
+	}
+
+	if cyyc.Decoded == nil || cyyc.Decoded.ReportType != ReportTypeSPECI {
+		t.Error("expected decoded speci")
+	}
+
+	if cabc := reports["CABC"]; !cabc.Error || cabc.FlightRules != common.FlightRuleError {
+		t.Errorf("expected error report for CABC %+v", cabc)
+	}
+}
+
+func TestAviationWeatherAPIGetForecasts(t *testing.T) {
+	server := createAviationWeatherAPITestServer(t)
+	defer server.Close()
+
+	client := newAviationWeatherAPIClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC", "CABC"},
+		Strategy:   common.AviationWeatherAPIMetarStrategy,
+		Clock:      common.ClockFunc(func() time.Time { return time.Date(2021, 1, 10, 7, 5, 0, 0, time.UTC) }),
+	}, server.URL+"/api/data")
+
+	forecasts, err := client.GetForecasts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(forecasts) != 3 || !forecasts["CABC"].Error {
+		t.Error("unnexpected forecasts", forecasts)
+	}
+
+	cyyc := forecasts["CYYC"]
+	if cyyc.Error || !cyyc.Decoded.Amended || !cyyc.ValidTo.Equal(time.Date(2021, 1, 11, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unnexpected CYYC forecast %+v", cyyc)
+	}
+
+	if flightRules := cyyc.FlightRulesAt(time.Date(2021, 1, 10, 20, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleMVFR {
+		t.Error("unnexpected CYYC forecast flight rules", flightRules)
+	}
+}
+
+func TestAviationWeatherAPIGetStationPositions(t *testing.T) {
+	server := createAviationWeatherAPITestServer(t)
+	defer server.Close()
+
+	client := newAviationWeatherAPIClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC", "CABC"},
+		Strategy:   common.AviationWeatherAPIMetarStrategy,
+	}, server.URL+"/api/data")
+
+	positions, err := client.GetStationPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(positions) != 3 {
+		t.Error("unnexpected position count", len(positions))
+	}
+
+	cyeg := positions["CYEG"]
+	if cyeg.Error || cyeg.Latitude != 53.3097 || cyeg.Longitude != -113.5792 || cyeg.Elevation != 723 {
+		t.Errorf("unnexpected CYEG position %+v", cyeg)
+	}
+
+	if !positions["CABC"].Error {
+		t.Error("expected error position for CABC")
+	}
+}
+
+func TestAviationWeatherAPIGetReportsChunked(t *testing.T) {
+	metarRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-api-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	failIDs := "CYYC"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if ids := r.URL.Query().Get("ids"); ids == failIDs || failIDs == "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Write(metarRaw)
+	}))
+	defer server.Close()
+
+	client := newAviationWeatherAPIClient(&Settings{
+		StationIDs:            []string{"CYEG", "CYYC", "CABC"},
+		Strategy:              common.AviationWeatherAPIMetarStrategy,
+		ChunkSize:             1,
+		MaxConcurrentRequests: 2,
+	}, server.URL)
+
+	reports, err := client.GetReports(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if atomic.LoadInt32(&requests) != 3 {
+		t.Error("unnexpected request count", requests)
+	}
+
+	if reports["CYEG"].Error || !reports["CYYC"].Error || !reports["CABC"].Error {
+		t.Error("expected only the failed chunk and missing station to be errors")
+	}
+
+	failIDs = ""
+	if _, err := client.GetReports(context.Background()); err == nil {
+		t.Error("expected error when every chunk fails")
+	}
+}
+
+func TestAviationWeatherAPIErrorResponses(t *testing.T) {
+	table := []struct {
+		status     int
+		body       string
+		expectErr  bool
+		errorCount int
+	}{
+		{http.StatusBadRequest, `{"status": "error", "error": "Invalid ids"}`, true, 0},
+		{http.StatusInternalServerError, `<html>oops</html>`, true, 0},
+		{http.StatusOK, `not json`, true, 0},
+		{http.StatusNoContent, ``, false, 2},
+		{http.StatusOK, `[]`, false, 2},
+	}
+
+	for _, row := range table {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(row.status)
+			w.Write([]byte(row.body))
+		}))
+
+		client := newAviationWeatherAPIClient(&Settings{
+			StationIDs: []string{"CYEG", "CYYC"},
+			Strategy:   common.AviationWeatherAPIMetarStrategy,
+		}, server.URL)
+
+		reports, err := client.GetReports(context.Background())
+		server.Close()
+
+		if (err != nil) != row.expectErr {
+			t.Errorf("%d %s => unnexpected error result: %v", row.status, row.body, err)
+			continue
+		}
+
+		errorCount := 0
+		for _, r := range reports {
+			if r.Error {
+				errorCount++
+			}
+		}
+
+		if errorCount != row.errorCount {
+			t.Errorf("%d %s => unnexpected error report count: %d", row.status, row.body, errorCount)
+		}
+	}
+}
+
+func createAviationWeatherAPITestServer(t *testing.T) *httptest.Server {
+	metarRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-api-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stationRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-api-stationinfo-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tafRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-api-taf-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/data/metar", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("format") != "json" {
+			t.Error("unnexpected format", r.URL.Query().Get("format"))
+		}
+
+		w.Write(metarRaw)
+	})
+	mux.HandleFunc("/api/data/stationinfo", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(stationRaw)
+	})
+	mux.HandleFunc("/api/data/taf", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tafRaw)
+	})
+
+	return httptest.NewServer(mux)
+}
@@ -1,6 +1,7 @@
 package metarclient
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -8,30 +9,61 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 )
 
 var _ MetarClient = (*aviationWeatherClient)(nil)
+var _ StationDiscoverer = (*aviationWeatherClient)(nil)
+var _ StationSubsetReporter = (*aviationWeatherClient)(nil)
 
 const AviationWeatherEndPoint = "https://aviationweather.gov/adds/dataserver_current/httpparam"
 
 //https://aviationweather.gov/docs/dataserver/schema/metar1_2.xsd
 type aviationWeatherMetar struct {
 	Error           bool
-	StationID       string  `xml:"station_id"`
-	ObservationTime string  `xml:"observation_time"`
-	WindSpeedKts    float64 `xml:"wind_speed_kt"`
-	FlightCategory  string  `xml:"flight_category"`
-	Latitude        float64 `xml:"latitude"`
-	Longitude       float64 `xml:"longitude"`
-	Elevation       float64 `xml:"elevation_m"`
-	VisibilitySM    float64 `xml:"visibility_statute_mi"`
-	SkyCover        string  `xml:"sky_condition>sky_cover"`
-	CloudBaseFtAGL  int     `xml:"sky_condition>cloud_base_ft_agl"`
-	CloudType       string  `xml:"sky_condition>cloud_type"`
+	StationID       string                         `xml:"station_id"`
+	ObservationTime string                         `xml:"observation_time"`
+	WindSpeedKts    *float64                       `xml:"wind_speed_kt"`
+	WindGustKts     *float64                       `xml:"wind_gust_kt"`
+	WindDirection   string                         `xml:"wind_dir_degrees"`
+	FlightCategory  string                         `xml:"flight_category"`
+	Latitude        float64                        `xml:"latitude"`
+	Longitude       float64                        `xml:"longitude"`
+	Elevation       float64                        `xml:"elevation_m"`
+	VisibilitySM    float64                        `xml:"visibility_statute_mi"`
+	SkyConditions   []*aviationWeatherSkyCondition `xml:"sky_condition"`
+	VertVisFt       *int                           `xml:"vert_vis_ft"`
+	RawText         string                         `xml:"raw_text"`
+}
+
+type aviationWeatherSkyCondition struct {
+	SkyCover       string `xml:"sky_cover,attr"`
+	CloudBaseFtAGL *int   `xml:"cloud_base_ft_agl,attr"`
+	CloudType      string `xml:"cloud_type,attr"`
+}
+
+// skyLayers gets every reported layer, with an obscured sky as vertical visibility.
+func (a *aviationWeatherMetar) skyLayers() []*SkyLayer {
+	layers := make([]*SkyLayer, len(a.SkyConditions))
+	for i, s := range a.SkyConditions {
+		layers[i] = &SkyLayer{
+			Cover:     s.SkyCover,
+			CloudType: s.CloudType,
+		}
+
+		if s.CloudBaseFtAGL != nil {
+			layers[i].BaseFtAGL = *s.CloudBaseFtAGL
+			layers[i].HasBase = true
+		}
+	}
+
+	return providerSkyLayers(layers, a.VertVisFt)
 }
 
 type aviationWeatherData struct {
@@ -39,9 +71,41 @@ type aviationWeatherData struct {
 	Errors []string                `xml:"errors>error"`
 }
 
+type aviationWeatherTaf struct {
+	StationID string `xml:"station_id"`
+	RawText   string `xml:"raw_text"`
+}
+
+type aviationWeatherTafData struct {
+	Tafs   []*aviationWeatherTaf `xml:"data>TAF"`
+	Errors []string              `xml:"errors>error"`
+}
+
+type aviationWeatherStation struct {
+	StationID string  `xml:"station_id"`
+	Latitude  float64 `xml:"latitude"`
+	Longitude float64 `xml:"longitude"`
+	Elevation float64 `xml:"elevation_m"`
+	Site      string  `xml:"site"`
+	State     string  `xml:"state"`
+	Country   string  `xml:"country"`
+	SiteType  struct {
+		Types []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"site_type"`
+}
+
+type aviationWeatherStationData struct {
+	Stations []*aviationWeatherStation `xml:"data>Station"`
+	Errors   []string                  `xml:"errors>error"`
+}
+
 type aviationWeatherClient struct {
 	settings *Settings
 	endPoint string
+	http     *httpFetcher
+	chunks   *chunkFetcher
 }
 
 type rawMetarHandler func([]*aviationWeatherMetar, error)
@@ -49,47 +113,56 @@ type rawMetarHandler func([]*aviationWeatherMetar, error)
 func newAviationWeatherClient(settings *Settings, endPoint string) MetarClient {
 	sort.Strings(settings.StationIDs)
 
+	if settings.FlightCategoryRules == nil {
+		settings.FlightCategoryRules = FAAFlightCategoryRules()
+	}
+
 	return &aviationWeatherClient{
 		settings: settings,
 		endPoint: endPoint,
+		http:     newHTTPFetcher(settings),
+		chunks:   newChunkFetcher(settings),
 	}
 }
 
-func (c *aviationWeatherClient) GetReports() (reports map[string]*MetarReport, err error) {
-	endPoint, err := c.buildQueryURL(false)
-	if err != nil {
-		return nil, err
-	}
+func (c *aviationWeatherClient) GetReports(ctx context.Context) (reports map[string]*MetarReport, err error) {
+	return c.GetReportsFor(ctx, c.settings.StationIDs)
+}
 
-	awm, err := c.getRawMetarData(endPoint)
+func (c *aviationWeatherClient) GetReportsFor(ctx context.Context, stationIDs []string) (reports map[string]*MetarReport, err error) {
+	awm, err := c.getRawMetarData(ctx, stationIDs, false)
 	if err != nil {
 		return nil, err
 	}
 
-	reports = make(map[string]*MetarReport, len(awm))
+	return c.buildReports(awm), nil
+}
+
+func (c *aviationWeatherClient) buildReports(awm map[string]*aviationWeatherMetar) map[string]*MetarReport {
+	now := c.settings.clock().Now()
+	reports := make(map[string]*MetarReport, len(awm))
 	for _, a := range awm {
 		reports[a.StationID] = &MetarReport{
-			Error:           a.Error,
-			StationID:       a.StationID,
-			ObservationTime: a.ObservationTime,
-			FlightRules:     a.FlightCategory,
-			WindSpeedKts:    a.WindSpeedKts,
-			SkyCover:        a.SkyCover,
-			CloudBaseFtAGL:  a.CloudBaseFtAGL,
-			CloudType:       a.CloudType,
+			Error:       a.Error,
+			StationID:   a.StationID,
+			FlightRules: a.FlightCategory,
+			RawText:     a.RawText,
+			Decoded:     decodeRawText(a.StationID, a.RawText),
+		}
+
+		if !a.Error {
+			resolveObservationTime(reports[a.StationID], a.ObservationTime, now)
+			resolveSkyLayers(reports[a.StationID], a.skyLayers())
+			resolveWind(reports[a.StationID], providerWind(a.WindSpeedKts, a.WindGustKts, a.WindDirection))
 		}
+		resolveFlightRules(reports[a.StationID], c.settings.FlightCategoryRules)
 	}
 
-	return reports, nil
+	return reports
 }
 
-func (c *aviationWeatherClient) GetStationPositions() (positions map[string]*MetarPosition, err error) {
-	endPoint, err := c.buildQueryURL(true)
-	if err != nil {
-		return nil, err
-	}
-
-	awm, err := c.getRawMetarData(endPoint)
+func (c *aviationWeatherClient) GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error) {
+	awm, err := c.getRawMetarData(ctx, c.settings.StationIDs, true)
 	if err != nil {
 		return nil, err
 	}
@@ -108,21 +181,196 @@ func (c *aviationWeatherClient) GetStationPositions() (positions map[string]*Met
 	return reports, nil
 }
 
-func (c *aviationWeatherClient) Fetch(handler MetarResponseHandler) {
+func (c *aviationWeatherClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
 	go func() {
-		reports, err := c.GetReports()
+		reports, err := c.GetReports(ctx)
 		handler(reports, err)
 	}()
 }
 
-func (c *aviationWeatherClient) FetchStationPositions(handler MetarPositionResponseHandler) {
+func (c *aviationWeatherClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
 	go func() {
-		positions, err := c.GetStationPositions()
+		positions, err := c.GetStationPositions(ctx)
 		handler(positions, err)
 	}()
 }
 
-func (c *aviationWeatherClient) buildQueryURL(getPosition bool) (*url.URL, error) {
+func (c *aviationWeatherClient) GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error) {
+	forecasts = make(map[string]*TafReport, len(c.settings.StationIDs))
+	tafs := make([]*aviationWeatherTaf, 0, len(c.settings.StationIDs))
+
+	responses, err := c.fetchChunks(ctx, c.settings.StationIDs, c.buildTafQueryURL, func(stationIDs []string, responseBytes []byte) error {
+		chunkForecasts, chunkTafs, err := c.parseTafResponseBytes(responseBytes, stationIDs)
+		if err != nil {
+			return err
+		}
+
+		for id, f := range chunkForecasts {
+			forecasts[id] = f
+		}
+		tafs = append(tafs, chunkTafs...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stationID := range c.settings.StationIDs {
+		if _, ok := forecasts[stationID]; !ok {
+			forecasts[stationID] = errorTafReport(stationID)
+		}
+	}
+
+	responseBytes := chunkResponseBytes(responses, func() ([]byte, error) {
+		return xml.Marshal(aviationWeatherTafData{Tafs: tafs})
+	})
+	if responseBytes != nil {
+		common.CacheToFile("last_aviation_weather_taf_response.xml", responseBytes)
+	}
+
+	return forecasts, nil
+}
+
+func (c *aviationWeatherClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	go func() {
+		forecasts, err := c.GetForecasts(ctx)
+		handler(forecasts, err)
+	}()
+}
+
+func (c *aviationWeatherClient) parseTafResponseBytes(responseBytes []byte, stationIDs []string) (map[string]*TafReport, []*aviationWeatherTaf, error) {
+	data := aviationWeatherTafData{}
+
+	err := xml.Unmarshal(responseBytes, &data)
+	if err != nil {
+		logger.LogError("failed to parse aviation weather taf response")
+		return nil, nil, err
+	}
+
+	if len(data.Errors) > 0 {
+		logger.LogError("errors from aviation weather: %s", strings.Join(data.Errors, ", "))
+		return nil, nil, errors.New("received errors from aviation weather")
+	}
+
+	rawTexts := make(map[string]string, len(data.Tafs))
+	for _, t := range data.Tafs {
+		rawTexts[t.StationID] = t.RawText
+	}
+
+	return buildTafReports(stationIDs, rawTexts, c.settings.clock().Now(), c.settings.FlightCategoryRules), data.Tafs, nil
+}
+
+// DiscoverStations gets the stations in the bounds of the area from the stations data source.
+func (c *aviationWeatherClient) DiscoverStations(ctx context.Context, area *DiscoveryArea) ([]*DiscoveredStation, error) {
+	bounds, err := area.Bounds()
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]*DiscoveredStation, 0)
+	for _, box := range splitAtAntimeridian(bounds) {
+		endPoint, err := c.buildStationQueryURL(box)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := c.http.get(ctx, endPoint.String())
+		if err != nil {
+			return nil, err
+		}
+
+		responseBytes, err := c.readResponse(response)
+		if err != nil {
+			return nil, err
+		}
+
+		stations, err := c.parseStationResponseBytes(responseBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		discovered = append(discovered, stations...)
+	}
+
+	return discovered, nil
+}
+
+func (c *aviationWeatherClient) parseStationResponseBytes(responseBytes []byte) ([]*DiscoveredStation, error) {
+	data := aviationWeatherStationData{}
+
+	err := xml.Unmarshal(responseBytes, &data)
+	if err != nil {
+		logger.LogError("failed to parse aviation weather station response")
+		return nil, err
+	}
+
+	if len(data.Errors) > 0 {
+		logger.LogError("errors from aviation weather: %s", strings.Join(data.Errors, ", "))
+		return nil, errors.New("received errors from aviation weather")
+	}
+
+	stations := make([]*DiscoveredStation, len(data.Stations))
+	for i, s := range data.Stations {
+		siteTypes := make([]string, len(s.SiteType.Types))
+		for j, t := range s.SiteType.Types {
+			siteTypes[j] = t.XMLName.Local
+		}
+
+		stations[i] = &DiscoveredStation{
+			StationID: s.StationID,
+			Name:      s.Site,
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Elevation: s.Elevation,
+			Country:   s.Country,
+			Region:    s.State,
+			SiteTypes: siteTypes,
+		}
+	}
+
+	return stations, nil
+}
+
+func (c *aviationWeatherClient) buildStationQueryURL(box *geo.BoundingBox) (*url.URL, error) {
+	u, err := url.Parse(c.endPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("dataSource", "stations")
+	q.Set("requestType", "retrieve")
+	q.Set("format", "xml")
+	q.Set("minLat", strconv.FormatFloat(box.MinLatitude, 'f', 4, 64))
+	q.Set("minLon", strconv.FormatFloat(box.MinLongitude, 'f', 4, 64))
+	q.Set("maxLat", strconv.FormatFloat(box.MaxLatitude, 'f', 4, 64))
+	q.Set("maxLon", strconv.FormatFloat(box.MaxLongitude, 'f', 4, 64))
+	u.RawQuery = q.Encode()
+
+	return u, nil
+}
+
+func (c *aviationWeatherClient) buildTafQueryURL(stationIDs []string) (*url.URL, error) {
+	u, err := url.Parse(c.endPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("dataSource", "tafs")
+	q.Set("requestType", "retrieve")
+	q.Set("format", "xml")
+	q.Set("stationString", strings.Join(stationIDs, ","))
+	q.Set("hoursBeforeNow", "6")
+	q.Set("mostRecentForEachStation", "constraint")
+	q.Set("fields", "raw_text,station_id")
+	u.RawQuery = q.Encode()
+
+	return u, nil
+}
+
+func (c *aviationWeatherClient) buildQueryURL(stationIDs []string, getPosition bool) (*url.URL, error) {
 	u, err := url.Parse(c.endPoint)
 	if err != nil {
 		return nil, err
@@ -139,13 +387,16 @@ func (c *aviationWeatherClient) buildQueryURL(getPosition bool) (*url.URL, error
 		}
 	} else {
 		fields = []string{
+			"raw_text",
 			"station_id",
 			"observation_time",
+			"wind_dir_degrees",
 			"wind_speed_kt",
+			"wind_gust_kt",
 			"flight_category",
 			"visibility_statute_mi",
-			"sky_cover",
-			"cloud_base_ft_agl",
+			"sky_condition",
+			"vert_vis_ft",
 		}
 	}
 
@@ -153,7 +404,7 @@ func (c *aviationWeatherClient) buildQueryURL(getPosition bool) (*url.URL, error
 	q.Set("dataSource", "metars")
 	q.Set("requestType", "retrieve")
 	q.Set("format", "xml")
-	q.Set("stationString", strings.Join(c.settings.StationIDs, ","))
+	q.Set("stationString", strings.Join(stationIDs, ","))
 	q.Set("hoursBeforeNow", "6")
 	q.Set("mostRecentForEachStation", "constraint")
 	q.Set("fields", strings.Join(fields, ","))
@@ -162,32 +413,111 @@ func (c *aviationWeatherClient) buildQueryURL(getPosition bool) (*url.URL, error
 	return u, nil
 }
 
-func (c *aviationWeatherClient) getRawMetarData(endPoint *url.URL) (map[string]*aviationWeatherMetar, error) {
-	response, err := http.Get(endPoint.String())
+// getRawMetarData requests the stations in chunks and merges the results. Stations in failed chunks are marked as errors.
+func (c *aviationWeatherClient) getRawMetarData(ctx context.Context, stationIDs []string, getPosition bool) (map[string]*aviationWeatherMetar, error) {
+	awm := make(map[string]*aviationWeatherMetar, len(stationIDs))
+	buildURL := func(stationIDs []string) (*url.URL, error) {
+		return c.buildQueryURL(stationIDs, getPosition)
+	}
+
+	responses, err := c.fetchChunks(ctx, stationIDs, buildURL, func(chunkIDs []string, responseBytes []byte) error {
+		chunkMetars, err := c.parseResponseBytes(responseBytes, chunkIDs)
+		if err != nil {
+			return err
+		}
+
+		for id, m := range chunkMetars {
+			awm[id] = m
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return c.parseResponse(response)
+	received := make([]*aviationWeatherMetar, 0, len(awm))
+	for _, stationID := range stationIDs {
+		if m, ok := awm[stationID]; !ok {
+			awm[stationID] = errorAviationWeatherMetar(stationID)
+		} else if !m.Error {
+			received = append(received, m)
+		}
+	}
+
+	responseBytes := chunkResponseBytes(responses, func() ([]byte, error) {
+		return xml.Marshal(aviationWeatherData{Metars: received})
+	})
+	// Only reports are recorded as the replay strategy reads positions from its own file.
+	if responseBytes != nil && !getPosition {
+		common.CacheToFile("last_aviation_weather_response.xml", responseBytes)
+		recordResponse(c.settings.RecordDir, common.AviationWeatherMetarStrategy, "xml", responseBytes)
+	}
+
+	return awm, nil
 }
 
-func (c *aviationWeatherClient) parseResponse(response *http.Response) (map[string]*aviationWeatherMetar, error) {
-	defer response.Body.Close()
+// fetchChunks requests each chunk of stations and passes the responses to the handler one at a time.
+// The raw responses are returned in chunk order, nil for those that failed.
+func (c *aviationWeatherClient) fetchChunks(ctx context.Context, stationIDs []string, buildURL func([]string) (*url.URL, error), handler func(stationIDs []string, responseBytes []byte) error) ([][]byte, error) {
+	chunks := c.chunks.split(stationIDs)
+	responses := make([][]byte, len(chunks))
+	lock := sync.Mutex{}
+
+	err := c.chunks.fetch(ctx, chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		endPoint, err := buildURL(stationIDs)
+		if err != nil {
+			return err
+		}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("invalid response %d", response.StatusCode)
-	}
+		response, err := c.http.get(ctx, endPoint.String())
+		if err != nil {
+			return err
+		}
 
-	data := aviationWeatherData{}
+		responseBytes, err := c.readResponse(response)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if err := handler(stationIDs, responseBytes); err != nil {
+			return err
+		}
+		responses[chunk] = responseBytes
+
+		return nil
+	})
+
+	return responses, err
+}
 
-	responseBytes, err := ioutil.ReadAll(response.Body)
+func (c *aviationWeatherClient) parseResponse(response *http.Response, stationIDs []string) (map[string]*aviationWeatherMetar, error) {
+	responseBytes, err := c.readResponse(response)
 	if err != nil {
 		return nil, err
 	}
 
-	common.CacheToFile("last_aviation_weather_response.xml", responseBytes)
+	return c.parseResponseBytes(responseBytes, stationIDs)
+}
+
+func (c *aviationWeatherClient) readResponse(response *http.Response) ([]byte, error) {
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response %d", response.StatusCode)
+	}
 
-	err = xml.Unmarshal(responseBytes, &data)
+	return ioutil.ReadAll(response.Body)
+}
+
+// parseResponseBytes gets the metars for the stations, marking those missing from the response as errors.
+func (c *aviationWeatherClient) parseResponseBytes(responseBytes []byte, stationIDs []string) (map[string]*aviationWeatherMetar, error) {
+	data := aviationWeatherData{}
+
+	err := xml.Unmarshal(responseBytes, &data)
 	if err != nil {
 		logger.LogError("failed to parse aviation weather response")
 		return nil, err
@@ -201,29 +531,15 @@ func (c *aviationWeatherClient) parseResponse(response *http.Response) (map[stri
 	inputMap := make(map[string]*aviationWeatherMetar)
 	for _, m := range data.Metars {
 		inputMap[m.StationID] = m
-
-		if m.FlightCategory == "" {
-			logger.LogWarn("station %s has no flight rule", m.StationID)
-			m.FlightCategory = common.FlightRuleUnknown
-		}
 	}
 
 	outputMap := make(map[string]*aviationWeatherMetar)
 
-	for _, stationID := range c.settings.StationIDs {
+	for _, stationID := range stationIDs {
 		if m, ok := inputMap[stationID]; ok {
 			outputMap[stationID] = m
 		} else {
-			outputMap[stationID] = &aviationWeatherMetar{
-				Error:           true,
-				StationID:       stationID,
-				ObservationTime: "",
-				FlightCategory:  common.FlightRuleError,
-				WindSpeedKts:    0,
-				Latitude:        0,
-				Longitude:       0,
-				Elevation:       0,
-			}
+			outputMap[stationID] = errorAviationWeatherMetar(stationID)
 
 			logger.LogWarn("failed to receive data for station '%s'", stationID)
 		}
@@ -231,3 +547,15 @@ func (c *aviationWeatherClient) parseResponse(response *http.Response) (map[stri
 
 	return outputMap, nil
 }
+
+func errorAviationWeatherMetar(stationID string) *aviationWeatherMetar {
+	return &aviationWeatherMetar{
+		Error:           true,
+		StationID:       stationID,
+		ObservationTime: "",
+		FlightCategory:  common.FlightRuleError,
+		Latitude:        0,
+		Longitude:       0,
+		Elevation:       0,
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
 	"strings"
 	"testing"
@@ -23,7 +25,7 @@ func TestAviationWeatherUrlBuilding(t *testing.T) {
 
 	aviationClient := client.(*aviationWeatherClient)
 
-	endPoint, err := aviationClient.buildQueryURL(false)
+	endPoint, err := aviationClient.buildQueryURL(aviationClient.settings.StationIDs, false)
 	if err != nil {
 		t.Error(err)
 	}
@@ -55,14 +57,14 @@ func TestAviationWeatherParseResponse(t *testing.T) {
 		Body:       ioutil.NopCloser(strings.NewReader(string(exampleRaw))),
 	}
 
-	_, err = aviationClient.parseResponse(&response)
+	_, err = aviationClient.parseResponse(&response, aviationClient.settings.StationIDs)
 	if err == nil {
 		t.Error("expected 404 error")
 	}
 
 	response.StatusCode = http.StatusOK
 
-	reports, err := aviationClient.parseResponse(&response)
+	reports, err := aviationClient.parseResponse(&response, aviationClient.settings.StationIDs)
 	if err != nil {
 		t.Error("expected 404 error")
 	}
@@ -79,11 +81,111 @@ func TestAviationWeatherParseResponse(t *testing.T) {
 		t.Error("unnexpected station ID")
 	}
 
-	if reports["CYEG"].WindSpeedKts != 8 {
+	if reports["CYEG"].WindSpeedKts == nil || *reports["CYEG"].WindSpeedKts != 8 {
 		t.Error("unnexpected wind speed")
 	}
 }
 
+func TestAviationWeatherParseSkyConditions(t *testing.T) {
+	client := newAviationWeatherClient(&Settings{
+		StationIDs: []string{"CYEG", "CYQF", "CYYC"},
+		Strategy:   common.AviationWeatherMetarStrategy,
+	}, AviationWeatherEndPoint).(*aviationWeatherClient)
+
+	exampleRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-sky-example.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awm, err := client.parseResponseBytes(exampleRaw, client.settings.StationIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports := client.buildReports(awm)
+
+	cyeg := reports["CYEG"]
+	expected := []SkyLayer{
+		{SkyCoverFew, 3000, true, ""},
+		{SkyCoverScattered, 8000, true, ""},
+		{SkyCoverBroken, 12000, true, ""},
+		{SkyCoverOvercast, 20000, true, ""},
+	}
+	if len(cyeg.SkyLayers) != len(expected) {
+		t.Fatalf("unnexpected CYEG layers %+v", cyeg.SkyLayers)
+	}
+	for i, l := range expected {
+		if *cyeg.SkyLayers[i] != l {
+			t.Errorf("unnexpected layer %d %+v", i, cyeg.SkyLayers[i])
+		}
+	}
+
+	if !cyeg.HasCeiling || cyeg.CeilingFtAGL != 12000 || cyeg.SkyCover != SkyCoverFew || cyeg.CloudBaseFtAGL != 3000 {
+		t.Errorf("unnexpected CYEG ceiling %+v", cyeg)
+	}
+
+	cyyc := reports["CYYC"]
+	if len(cyyc.SkyLayers) != 1 || cyyc.SkyLayers[0].Cover != SkyCoverClear || cyyc.SkyLayers[0].HasBase || cyyc.HasCeiling {
+		t.Errorf("unnexpected CYYC sky %+v", cyyc)
+	}
+
+	cyqf := reports["CYQF"]
+	if len(cyqf.SkyLayers) != 1 || cyqf.SkyLayers[0].Cover != SkyCoverVertVis || !cyqf.HasCeiling || cyqf.CeilingFtAGL != 200 {
+		t.Errorf("unnexpected CYQF sky %+v", cyqf)
+	}
+
+	if cyqf.FlightRules != common.FlightRuleLIFR {
+		t.Error("unnexpected CYQF flight rules", cyqf.FlightRules)
+	}
+}
+
+func TestProviderSkyLayersVertVisOnly(t *testing.T) {
+	vertVis := 300
+	layers := providerSkyLayers(nil, &vertVis)
+	if len(layers) != 1 || layers[0].Cover != SkyCoverVertVis || layers[0].BaseFtAGL != 300 || !layers[0].HasBase {
+		t.Error("unnexpected layers", layers)
+	}
+
+	layers = providerSkyLayers([]*SkyLayer{{Cover: skyCoverObscured, HasBase: true}}, nil)
+	if len(layers) != 1 || layers[0].Cover != SkyCoverVertVis || layers[0].HasBase {
+		t.Error("expected unknown vertical visibility", layers[0])
+	}
+}
+
+func TestAviationWeatherParseTafResponse(t *testing.T) {
+	client := newAviationWeatherClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC", "CABC"},
+		Strategy:   common.AviationWeatherMetarStrategy,
+		Clock:      common.ClockFunc(func() time.Time { return time.Date(2021, 1, 10, 7, 5, 0, 0, time.UTC) }),
+	}, AviationWeatherEndPoint).(*aviationWeatherClient)
+
+	endPoint, err := client.buildTafQueryURL(client.settings.StationIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if source := endPoint.Query().Get("dataSource"); source != "tafs" {
+		t.Error("unnexpected data source", source)
+	}
+
+	exampleRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-taf-example.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecasts, _, err := client.parseTafResponseBytes(exampleRaw, client.settings.StationIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if forecasts["CYEG"].Error || forecasts["CYYC"].Error || !forecasts["CABC"].Error {
+		t.Error("unnexpected forecast errors")
+	}
+
+	if flightRules := forecasts["CYEG"].FlightRulesAt(time.Date(2021, 1, 10, 8, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleMVFR {
+		t.Error("unnexpected CYEG forecast flight rules", flightRules)
+	}
+}
+
 func TestAviationWeatherParseResponseWrongStations(t *testing.T) {
 	client, err := CreateMetarClient(&Settings{
 		StationIDs: []string{"CABC"},
@@ -101,14 +203,14 @@ func TestAviationWeatherParseResponseWrongStations(t *testing.T) {
 		Body:       ioutil.NopCloser(strings.NewReader(string(exampleRaw))),
 	}
 
-	_, err = aviationClient.parseResponse(&response)
+	_, err = aviationClient.parseResponse(&response, aviationClient.settings.StationIDs)
 	if err == nil {
 		t.Error("expected 404 error")
 	}
 
 	response.StatusCode = http.StatusOK
 
-	reports, err := aviationClient.parseResponse(&response)
+	reports, err := aviationClient.parseResponse(&response, aviationClient.settings.StationIDs)
 	if err != nil {
 		t.Error("expected 404 error")
 	}
@@ -125,50 +227,41 @@ func TestAviationWeatherParseResponseWrongStations(t *testing.T) {
 		t.Error("unnexpected station ID")
 	}
 
-	if reports["CABC"].WindSpeedKts != 0 {
+	if reports["CABC"].WindSpeedKts != nil {
 		t.Error("unnexpected wind speed")
 	}
 }
 
 func TestClientFetchIntegrated(t *testing.T) {
-	http.DefaultServeMux = new(http.ServeMux)
-
-	client := newAviationWeatherClient(&Settings{
-		StationIDs: []string{"CYEG", "CYYC"},
-		Strategy:   common.AviationWeatherMetarStrategy,
-	}, "http://localhost:3000/go-metar-blink").(*aviationWeatherClient)
-
-	server := http.Server{
-		Addr:    ":3000",
-		Handler: nil,
-	}
-
 	exampleRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-example.xml"))
 	if err != nil {
 		t.Error(err)
 	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		expectedQuery, _ := client.buildQueryURL(false)
+	var client *aviationWeatherClient
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedQuery, _ := client.buildQueryURL(client.settings.StationIDs, false)
 		if expectedQuery.RawQuery != r.URL.RawQuery {
 			t.Error("Query mismatch: ", expectedQuery.RawQuery, r.URL.RawQuery)
 		}
 
 		w.Write(exampleRaw)
-	})
+	}))
+	defer server.Close()
 
-	go func() {
-		err := server.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			t.Error(err)
-		}
-	}()
+	client = newAviationWeatherClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC"},
+		Strategy:   common.AviationWeatherMetarStrategy,
+	}, server.URL+"/go-metar-blink").(*aviationWeatherClient)
 
 	doneServerChan := make(chan int, 0)
 
-	client.Fetch(func(reports map[string]*MetarReport, err error) {
+	client.Fetch(context.Background(), func(reports map[string]*MetarReport, err error) {
+		defer func() { doneServerChan <- 1 }()
+
 		if err != nil {
 			t.Error(err)
+			return
 		}
 		t.Logf("Got %d reports", len(reports))
 
@@ -183,64 +276,48 @@ func TestClientFetchIntegrated(t *testing.T) {
 		if reports["CYYC"].StationID != "CYYC" {
 			t.Error("unnexpected second station id")
 		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
-		defer cancel()
-
-		err = server.Shutdown(ctx)
-		if err != nil {
-			t.Error(err)
-		}
-
-		doneServerChan <- 1
 	})
 
 	select {
 	case <-doneServerChan:
-		server.Close()
 		//done
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reports")
 	}
 }
 
 func TestStationPositionIntegrated(t *testing.T) {
-	http.DefaultServeMux = new(http.ServeMux)
-
-	client := newAviationWeatherClient(&Settings{
-		StationIDs: []string{"CYEG", "CYYC"},
-		Strategy:   common.AviationWeatherMetarStrategy,
-	}, "http://localhost:3000/go-metar-blink").(*aviationWeatherClient)
-
-	server := http.Server{
-		Addr:    ":3000",
-		Handler: nil,
-	}
-
 	exampleRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-example.xml"))
 	if err != nil {
 		t.Error(err)
 	}
 
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		expectedQuery, _ := client.buildQueryURL(true)
+	var client *aviationWeatherClient
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedQuery, _ := client.buildQueryURL(client.settings.StationIDs, true)
 		if expectedQuery.RawQuery != r.URL.RawQuery {
 			t.Error("Query mismatch: ", expectedQuery.RawQuery, r.URL.RawQuery)
 		}
 
 		w.Write(exampleRaw)
-	})
+	}))
+	defer server.Close()
 
-	go func() {
-		err := server.ListenAndServe()
-		if err != nil && err != http.ErrServerClosed {
-			t.Error(err)
-		}
-	}()
+	recordDir := path.Join(t.TempDir(), "recordings")
+	client = newAviationWeatherClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC"},
+		Strategy:   common.AviationWeatherMetarStrategy,
+		RecordDir:  recordDir,
+	}, server.URL+"/go-metar-blink").(*aviationWeatherClient)
 
 	doneServerChan := make(chan int, 0)
 
-	client.FetchStationPositions(func(reports map[string]*MetarPosition, err error) {
+	client.FetchStationPositions(context.Background(), func(reports map[string]*MetarPosition, err error) {
+		defer func() { doneServerChan <- 1 }()
+
 		if err != nil {
 			t.Error(err)
+			return
 		}
 		t.Logf("Got %d reports", len(reports))
 
@@ -255,22 +332,46 @@ func TestStationPositionIntegrated(t *testing.T) {
 		if reports["CYYC"].StationID != "CYYC" {
 			t.Error("unnexpected second station id")
 		}
+	})
 
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*1)
-		defer cancel()
+	select {
+	case <-doneServerChan:
+		//done
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for positions")
+	}
 
-		err = server.Shutdown(ctx)
-		if err != nil {
-			t.Error(err)
-		}
+	if _, err := os.Stat(recordDir); !os.IsNotExist(err) {
+		t.Error("expected position responses not to be recorded")
+	}
+}
+
+func TestClientFetchCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := newAviationWeatherClient(&Settings{
+		StationIDs: []string{"CYEG"},
+		Strategy:   common.AviationWeatherMetarStrategy,
+	}, server.URL)
 
-		doneServerChan <- 1
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	client.Fetch(ctx, func(reports map[string]*MetarReport, err error) {
+		done <- err
 	})
 
+	cancel()
+
 	select {
-	case <-doneServerChan:
-		//done
-		server.Close()
+	case err := <-done:
+		if err != context.Canceled {
+			t.Error("expected cancelled error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetch wasn't cancelled")
 	}
 }
 
@@ -291,7 +392,7 @@ func TestParseResponseWithError(t *testing.T) {
 		Body:       ioutil.NopCloser(strings.NewReader(string(exampleRaw))),
 	}
 
-	_, err = aviationClient.parseResponse(&response)
+	_, err = aviationClient.parseResponse(&response, aviationClient.settings.StationIDs)
 	if err == nil {
 		t.Error("expected error")
 	}
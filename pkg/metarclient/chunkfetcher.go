@@ -0,0 +1,145 @@
+package metarclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+const (
+	DefaultChunkSize             = 100
+	DefaultMaxConcurrentRequests = 4
+)
+
+// ChunkError is the failure of the request for one chunk of stations.
+type ChunkError struct {
+	StationIDs []string
+	Err        error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("request for %s failed: %s", strings.Join(e.StationIDs, ","), e.Err)
+}
+
+// ChunkErrors are the failures of each chunk of a request.
+type ChunkErrors []*ChunkError
+
+func (e ChunkErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, chunkErr := range e {
+		messages[i] = chunkErr.Error()
+	}
+
+	return fmt.Sprintf("%d chunks failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+type chunkRequest func(ctx context.Context, chunk int, stationIDs []string) error
+
+// chunkFetcher splits station lists into chunks and requests them with a bounded number of workers.
+type chunkFetcher struct {
+	size    int
+	workers int
+}
+
+func newChunkFetcher(settings *Settings) *chunkFetcher {
+	size := settings.ChunkSize
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+
+	workers := settings.MaxConcurrentRequests
+	if workers <= 0 {
+		workers = DefaultMaxConcurrentRequests
+	}
+
+	return &chunkFetcher{
+		size:    size,
+		workers: workers,
+	}
+}
+
+// split divides the stations into chunks of at most the chunk size.
+func (f *chunkFetcher) split(stationIDs []string) [][]string {
+	chunks := make([][]string, 0, (len(stationIDs)+f.size-1)/f.size)
+	for start := 0; start < len(stationIDs); start += f.size {
+		end := start + f.size
+		if end > len(stationIDs) {
+			end = len(stationIDs)
+		}
+
+		chunks = append(chunks, stationIDs[start:end])
+	}
+
+	return chunks
+}
+
+// fetch calls the request for each chunk, which must be safe to call concurrently.
+// Failed chunks are logged, leaving their stations for the caller to mark as failed. ChunkErrors is only
+// returned when every chunk fails.
+func (f *chunkFetcher) fetch(ctx context.Context, chunks [][]string, request chunkRequest) error {
+	errs := make([]error, len(chunks))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+
+	workers := f.workers
+	if workers > len(chunks) {
+		workers = len(chunks)
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = request(ctx, i, chunks[i])
+			}
+		}()
+	}
+
+	for i := range chunks {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	chunkErrs := make(ChunkErrors, 0)
+	for i, err := range errs {
+		if err != nil {
+			chunkErr := &ChunkError{StationIDs: chunks[i], Err: err}
+			logger.LogError("%s", chunkErr)
+			chunkErrs = append(chunkErrs, chunkErr)
+		}
+	}
+
+	if len(chunks) > 0 && len(chunkErrs) == len(chunks) {
+		return chunkErrs
+	}
+
+	return nil
+}
+
+// chunkResponseBytes gets the response to cache or record. The raw response is used when there was only one chunk,
+// otherwise the merged results are marshalled so replays see every station in one file.
+func chunkResponseBytes(responses [][]byte, marshalMerged func() ([]byte, error)) []byte {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	responseBytes, err := marshalMerged()
+	if err != nil {
+		logger.LogWarn("failed to marshal merged response: %s", err)
+		return nil
+	}
+
+	return responseBytes
+}
@@ -0,0 +1,99 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChunkFetcherSplit(t *testing.T) {
+	fetcher := newChunkFetcher(&Settings{ChunkSize: 2})
+
+	chunks := fetcher.split([]string{"CYEG", "CYYC", "CYXD", "CYQL", "CYBW"})
+	if len(chunks) != 3 || len(chunks[0]) != 2 || len(chunks[2]) != 1 || chunks[2][0] != "CYBW" {
+		t.Error("unnexpected chunks", chunks)
+	}
+
+	if chunks := fetcher.split([]string{}); len(chunks) != 0 {
+		t.Error("expected no chunks", chunks)
+	}
+
+	if defaults := newChunkFetcher(&Settings{}); defaults.size != DefaultChunkSize || defaults.workers != DefaultMaxConcurrentRequests {
+		t.Error("unnexpected defaults", defaults.size, defaults.workers)
+	}
+}
+
+func TestChunkFetcherBoundsWorkers(t *testing.T) {
+	fetcher := newChunkFetcher(&Settings{ChunkSize: 1, MaxConcurrentRequests: 3})
+	chunks := fetcher.split([]string{"A", "B", "C", "D", "E", "F", "G", "H"})
+
+	var active, maxActive, calls int32
+	err := fetcher.fetch(context.Background(), chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		current := atomic.AddInt32(&active, 1)
+		for {
+			max := atomic.LoadInt32(&maxActive)
+			if current <= max || atomic.CompareAndSwapInt32(&maxActive, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&calls, 1)
+
+		return nil
+	})
+
+	if err != nil {
+		t.Error(err)
+	}
+
+	if calls != 8 || maxActive > 3 || maxActive < 2 {
+		t.Error("unnexpected calls or concurrency", calls, maxActive)
+	}
+}
+
+func TestChunkFetcherErrors(t *testing.T) {
+	fetcher := newChunkFetcher(&Settings{ChunkSize: 2})
+	chunks := fetcher.split([]string{"CYEG", "CYYC", "CYXD"})
+
+	err := fetcher.fetch(context.Background(), chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		if chunk == 1 {
+			return errors.New("bad chunk")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Error("expected partial failure to succeed", err)
+	}
+
+	err = fetcher.fetch(context.Background(), chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		return errors.New("bad chunk")
+	})
+
+	chunkErrs, ok := err.(ChunkErrors)
+	if !ok || len(chunkErrs) != 2 || chunkErrs[1].StationIDs[0] != "CYXD" {
+		t.Error("unnexpected chunk errors", err)
+	}
+}
+
+func TestChunkFetcherCancelled(t *testing.T) {
+	fetcher := newChunkFetcher(&Settings{ChunkSize: 1, MaxConcurrentRequests: 1})
+	chunks := fetcher.split([]string{"CYEG", "CYYC", "CYXD"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	err := fetcher.fetch(ctx, chunks, func(ctx context.Context, chunk int, stationIDs []string) error {
+		atomic.AddInt32(&calls, 1)
+		cancel()
+
+		return ctx.Err()
+	})
+
+	if err != context.Canceled || calls == 3 {
+		t.Error("expected cancel to stop the fetch", err, calls)
+	}
+}
@@ -1,30 +1,66 @@
 package metarclient
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
 )
 
 type MetarStrategy string
 
+// Settings configures a MetarClient.
 type Settings struct {
 	StationIDs []string
 	Strategy   MetarStrategy
+	// EndPoint overrides the strategy's default end point.
+	EndPoint string
+	// FlightCategoryRules computes flight rules locally, defaulting to the FAA's.
+	FlightCategoryRules *FlightCategoryRules
+	// RecordDir saves a timestamped copy of each provider's report response when set.
+	RecordDir string
+	// ReplaySpeed is the replay strategy's playback multiplier. 0 steps a file per request.
+	ReplaySpeed float64
+	// Providers are the strategies the failover strategy tries, in order.
+	Providers []*Settings
+	// Clock resolves observation times that only have a day and time, defaulting to the system clock.
+	Clock common.Clock
+	// HTTPTimeout limits each request, defaulting to DefaultHTTPTimeout.
+	HTTPTimeout time.Duration
+	// RetryCount is how many times a request is retried after a network error or 5xx response.
+	RetryCount int
+	// RetryBaseDelay is the first backoff delay, defaulting to DefaultRetryBaseDelay.
+	RetryBaseDelay time.Duration
+	// ChunkSize is how many stations are requested at once, defaulting to DefaultChunkSize.
+	ChunkSize int
+	// MaxConcurrentRequests limits the chunks requested at once, defaulting to DefaultMaxConcurrentRequests.
+	MaxConcurrentRequests int
 }
 
 type MetarResponseHandler func(reports map[string]*MetarReport, err error)
 type MetarPositionResponseHandler func(positions map[string]*MetarPosition, err error)
 
+// MetarReport is a station's latest observation. Wind is nil when the wind wasn't reported. SkyLayers are every
+// reported layer from lowest to highest and CeilingFtAGL is the lowest broken, overcast, or vertical visibility layer
+// when HasCeiling is set.
 type MetarReport struct {
 	Error           bool
 	StationID       string
-	ObservationTime string
+	ObservationTime time.Time
 	FlightRules     string
 	WindSpeedKts    float64
+	Wind            *Wind
 	SkyCover        string
 	CloudBaseFtAGL  int
 	CloudType       string
+	SkyLayers       []*SkyLayer
+	CeilingFtAGL    int
+	HasCeiling      bool
+	RawText         string
+	Decoded         *DecodedMetar
+	Provider        string
 }
 
 type MetarPosition struct {
@@ -36,17 +72,66 @@ type MetarPosition struct {
 }
 
 type MetarClient interface {
-	GetReports() (reports map[string]*MetarReport, err error)
-	GetStationPositions() (positions map[string]*MetarPosition, err error)
-	Fetch(handler MetarResponseHandler)
-	FetchStationPositions(handler MetarPositionResponseHandler)
+	GetReports(ctx context.Context) (reports map[string]*MetarReport, err error)
+	GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error)
+	GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error)
+	Fetch(ctx context.Context, handler MetarResponseHandler)
+	FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler)
+	FetchForecasts(ctx context.Context, handler TafResponseHandler)
 }
 
 func CreateMetarClient(settings *Settings) (MetarClient, error) {
 	switch settings.Strategy {
 	case common.AviationWeatherMetarStrategy:
-		return newAviationWeatherClient(settings, AviationWeatherEndPoint), nil
+		return newAviationWeatherClient(settings, settings.endPointOrDefault(AviationWeatherEndPoint)), nil
+	case common.AviationWeatherAPIMetarStrategy:
+		return newAviationWeatherAPIClient(settings, settings.endPointOrDefault(AviationWeatherAPIEndPoint)), nil
+	case common.NOAATextMetarStrategy:
+		return newNOAATextClient(settings, settings.endPointOrDefault(NOAATextEndPoint)), nil
+	case common.ReplayMetarStrategy:
+		return newReplayClient(settings, settings.endPointOrDefault(settings.RecordDir)), nil
+	case common.FailoverMetarStrategy:
+		return newFailoverClient(settings)
 	default:
 		return nil, fmt.Errorf("configured metar strategy not supported")
 	}
 }
+
+func (s *Settings) endPointOrDefault(defaultEndPoint string) string {
+	if s.EndPoint != "" {
+		return s.EndPoint
+	}
+
+	return defaultEndPoint
+}
+
+func (s *Settings) clock() common.Clock {
+	if s.Clock == nil {
+		return common.SystemClock{}
+	}
+
+	return s.Clock
+}
+
+func decodeRawText(stationID string, rawText string) *DecodedMetar {
+	if rawText == "" {
+		return nil
+	}
+
+	decoded, err := ParseMetar(rawText)
+	if err != nil {
+		logger.LogWarn("failed to decode metar for station '%s': %s", stationID, err)
+		return nil
+	}
+
+	return decoded
+}
+
+func errorReport(stationID string) *MetarReport {
+	return &MetarReport{
+		Error:        true,
+		StationID:    stationID,
+		FlightRules:  common.FlightRuleError,
+		WindSpeedKts: 0,
+	}
+}
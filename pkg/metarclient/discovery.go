@@ -0,0 +1,130 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+)
+
+const (
+	SiteTypeMETAR = "METAR"
+	SiteTypeTAF   = "TAF"
+)
+
+var ErrDiscoveryNotSupported = errors.New("metar strategy does not support station discovery")
+
+// DiscoveryArea is where to search for stations: the box or, when RadiusKm is set, the circle around the center.
+type DiscoveryArea struct {
+	Box      *geo.BoundingBox
+	Center   *geo.Coordinate
+	RadiusKm float64
+}
+
+// Bounds gets the box to search, containing the circle when the area has a radius.
+func (a *DiscoveryArea) Bounds() (*geo.BoundingBox, error) {
+	if a.RadiusKm > 0 {
+		if a.Center == nil {
+			return nil, errors.New("discovery radius needs a center")
+		}
+
+		return geo.BoundingBoxAround(a.Center, a.RadiusKm), nil
+	}
+
+	if a.Box == nil {
+		return nil, errors.New("discovery needs a bounding box or a center and radius")
+	}
+
+	return a.Box, a.Box.Validate()
+}
+
+// Contains checks if the coordinate is inside the area.
+func (a *DiscoveryArea) Contains(c *geo.Coordinate) bool {
+	if a.RadiusKm > 0 {
+		return a.Center.DistanceKm(c) <= a.RadiusKm
+	}
+
+	return a.Box.Contains(c)
+}
+
+// DiscoveredStation is a station found by searching an area.
+// Region is the state or province and SiteTypes are the products the station issues, like SiteTypeMETAR and SiteTypeTAF.
+type DiscoveredStation struct {
+	StationID string
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Elevation float64
+	Country   string
+	Region    string
+	SiteTypes []string
+}
+
+// ReportsMETAR checks if the station issues regular METARs.
+func (s *DiscoveredStation) ReportsMETAR() bool {
+	for _, t := range s.SiteTypes {
+		if t == SiteTypeMETAR {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StationDiscoverer is implemented by clients that can search for stations in an area.
+type StationDiscoverer interface {
+	DiscoverStations(ctx context.Context, area *DiscoveryArea) ([]*DiscoveredStation, error)
+}
+
+// DiscoverStations searches the area with the client, sorted by ID. Only stations that report METARs are kept
+// when metarOnly is set.
+func DiscoverStations(ctx context.Context, client MetarClient, area *DiscoveryArea, metarOnly bool) ([]*DiscoveredStation, error) {
+	discoverer, ok := client.(StationDiscoverer)
+	if !ok {
+		return nil, ErrDiscoveryNotSupported
+	}
+
+	if _, err := area.Bounds(); err != nil {
+		return nil, err
+	}
+
+	found, err := discoverer.DiscoverStations(ctx, area)
+	if err != nil {
+		return nil, err
+	}
+
+	stations := make([]*DiscoveredStation, 0, len(found))
+	for _, s := range found {
+		if s.StationID == "" || (metarOnly && !s.ReportsMETAR()) {
+			continue
+		}
+
+		// Providers search the bounds, so drop the corners outside a radius.
+		if !area.Contains(&geo.Coordinate{Latitude: s.Latitude, Longitude: s.Longitude}) {
+			continue
+		}
+
+		stations = append(stations, s)
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].StationID < stations[j].StationID
+	})
+
+	return stations, nil
+}
+
+// splitAtAntimeridian splits a box that wraps past 180 degrees into boxes providers can search.
+func splitAtAntimeridian(box *geo.BoundingBox) []*geo.BoundingBox {
+	if box.MinLongitude <= box.MaxLongitude {
+		return []*geo.BoundingBox{box}
+	}
+
+	east := *box
+	east.MaxLongitude = 180
+	west := *box
+	west.MinLongitude = -180
+
+	return []*geo.BoundingBox{&east, &west}
+}
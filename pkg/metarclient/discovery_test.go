@@ -0,0 +1,110 @@
+package metarclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+)
+
+func TestDiscoverStationsAPI(t *testing.T) {
+	stationRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-api-stationinfo-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/data/stationinfo" {
+			t.Error("unnexpected path", r.URL.Path)
+		}
+
+		if bbox := r.URL.Query().Get("bbox"); bbox != "50.0000,-115.0000,54.0000,-113.0000" {
+			t.Error("unnexpected bbox", bbox)
+		}
+
+		w.Write(stationRaw)
+	}))
+	defer server.Close()
+
+	client := newAviationWeatherAPIClient(&Settings{Strategy: common.AviationWeatherAPIMetarStrategy}, server.URL+"/api/data")
+	area := &DiscoveryArea{Box: &geo.BoundingBox{MinLatitude: 50, MinLongitude: -115, MaxLatitude: 54, MaxLongitude: -113}}
+
+	stations, err := DiscoverStations(context.Background(), client, area, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stations) != 3 || stations[0].StationID != "CWSE" || stations[1].Name != "Edmonton Intl" {
+		t.Error("unnexpected stations", stations)
+	}
+
+	stations, err = DiscoverStations(context.Background(), client, area, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(stations) != 2 || stations[0].StationID != "CYEG" || stations[1].StationID != "CYYC" {
+		t.Error("expected only metar stations", stations)
+	}
+}
+
+func TestDiscoverStationsXMLRadius(t *testing.T) {
+	stationRaw, err := ioutil.ReadFile(path.Join(common.GetProjectRoot(), "resources/dev/aviation-weather-stations-example.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if source := r.URL.Query().Get("dataSource"); source != "stations" {
+			t.Error("unnexpected data source", source)
+		}
+
+		w.Write(stationRaw)
+	}))
+	defer server.Close()
+
+	client := newAviationWeatherClient(&Settings{Strategy: common.AviationWeatherMetarStrategy}, server.URL)
+	area := &DiscoveryArea{Center: &geo.Coordinate{Latitude: 53.4, Longitude: -113.6}, RadiusKm: 50}
+
+	stations, err := DiscoverStations(context.Background(), client, area, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// CWSE doesn't report METARs and CYQF is outside the radius.
+	if len(stations) != 2 || stations[0].StationID != "CYEG" || stations[1].StationID != "CYXD" {
+		t.Error("unnexpected stations", stations)
+	}
+
+	if types := stations[0].SiteTypes; len(types) != 2 || types[0] != SiteTypeMETAR || types[1] != SiteTypeTAF {
+		t.Error("unnexpected site types", types)
+	}
+}
+
+func TestDiscoverStationsErrors(t *testing.T) {
+	noaa := newNOAATextClient(&Settings{Strategy: common.NOAATextMetarStrategy}, NOAATextEndPoint)
+	area := &DiscoveryArea{Center: &geo.Coordinate{Latitude: 53.4, Longitude: -113.6}, RadiusKm: 50}
+
+	if _, err := DiscoverStations(context.Background(), noaa, area, true); err != ErrDiscoveryNotSupported {
+		t.Error("expected not supported", err)
+	}
+
+	api := newAviationWeatherAPIClient(&Settings{Strategy: common.AviationWeatherAPIMetarStrategy}, AviationWeatherAPIEndPoint)
+	for _, invalid := range []*DiscoveryArea{{}, {RadiusKm: 10}, {Box: &geo.BoundingBox{MinLatitude: 10, MaxLatitude: 0}}} {
+		if _, err := DiscoverStations(context.Background(), api, invalid, true); err == nil {
+			t.Error("expected invalid area error", invalid)
+		}
+	}
+}
+
+func TestSplitAtAntimeridian(t *testing.T) {
+	boxes := splitAtAntimeridian(&geo.BoundingBox{MinLatitude: 50, MinLongitude: 170, MaxLatitude: 55, MaxLongitude: -170})
+	if len(boxes) != 2 || boxes[0].MaxLongitude != 180 || boxes[1].MinLongitude != -180 {
+		t.Error("unnexpected boxes", boxes)
+	}
+}
@@ -0,0 +1,334 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+var _ MetarClient = (*failoverClient)(nil)
+var _ ProviderHealthReporter = (*failoverClient)(nil)
+var _ StationDiscoverer = (*failoverClient)(nil)
+
+// ProviderHealth tracks how reliable a provider has been.
+type ProviderHealth struct {
+	Name                string
+	ConsecutiveFailures int
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	LastLatency         time.Duration
+	LastError           string
+}
+
+// StationSubsetReporter is implemented by clients that can request reports for some of their stations.
+type StationSubsetReporter interface {
+	GetReportsFor(ctx context.Context, stationIDs []string) (reports map[string]*MetarReport, err error)
+}
+
+// ProviderHealthReporter is implemented by clients that track the health of their providers.
+type ProviderHealthReporter interface {
+	ProviderHealth() []*ProviderHealth
+}
+
+type failoverProvider struct {
+	client MetarClient
+	health *ProviderHealth
+}
+
+// failoverClient asks an ordered list of providers for reports, filling any stations that fail from the next provider.
+// Providers that implement StationSubsetReporter are only asked for the stations still pending.
+type failoverClient struct {
+	settings  *Settings
+	providers []*failoverProvider
+	clock     common.Clock
+	lock      sync.Mutex
+}
+
+func newFailoverClient(settings *Settings) (MetarClient, error) {
+	if len(settings.Providers) == 0 {
+		return nil, errors.New("failover strategy needs at least one provider")
+	}
+
+	sort.Strings(settings.StationIDs)
+
+	c := &failoverClient{
+		settings:  settings,
+		providers: make([]*failoverProvider, len(settings.Providers)),
+		clock:     settings.clock(),
+	}
+
+	for i, p := range settings.Providers {
+		providerSettings := *p
+		providerSettings.StationIDs = append([]string{}, settings.StationIDs...)
+		if providerSettings.FlightCategoryRules == nil {
+			providerSettings.FlightCategoryRules = settings.FlightCategoryRules
+		}
+		if providerSettings.Clock == nil {
+			providerSettings.Clock = settings.Clock
+		}
+		if providerSettings.HTTPTimeout == 0 {
+			providerSettings.HTTPTimeout = settings.HTTPTimeout
+		}
+		if providerSettings.RetryCount == 0 {
+			providerSettings.RetryCount = settings.RetryCount
+		}
+		if providerSettings.RetryBaseDelay == 0 {
+			providerSettings.RetryBaseDelay = settings.RetryBaseDelay
+		}
+		if providerSettings.ChunkSize == 0 {
+			providerSettings.ChunkSize = settings.ChunkSize
+		}
+		if providerSettings.MaxConcurrentRequests == 0 {
+			providerSettings.MaxConcurrentRequests = settings.MaxConcurrentRequests
+		}
+
+		client, err := CreateMetarClient(&providerSettings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create provider %d: %s", i, err)
+		}
+
+		c.providers[i] = &failoverProvider{
+			client: client,
+			health: &ProviderHealth{Name: providerName(p)},
+		}
+	}
+
+	return c, nil
+}
+
+func (c *failoverClient) GetReports(ctx context.Context) (reports map[string]*MetarReport, err error) {
+	reports = make(map[string]*MetarReport, len(c.settings.StationIDs))
+	var lastErr error
+	succeeded := false
+
+	for _, p := range c.providers {
+		pending := c.pendingStations(reports)
+		if len(pending) == 0 {
+			break
+		}
+
+		start := c.clock.Now()
+		providerReports, err := c.getProviderReports(ctx, p, pending)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.recordResult(p, start, err)
+
+		if err != nil {
+			logger.LogWarn("provider %s failed: %s", p.health.Name, err)
+			lastErr = err
+			continue
+		}
+		succeeded = true
+
+		for _, stationID := range pending {
+			if r, ok := providerReports[stationID]; ok && !r.Error {
+				r.Provider = p.health.Name
+				reports[stationID] = r
+			}
+		}
+	}
+
+	if !succeeded {
+		return nil, fmt.Errorf("all providers failed, last error: %s", lastErr)
+	}
+
+	for _, stationID := range c.pendingStations(reports) {
+		logger.LogWarn("no provider had data for station '%s'", stationID)
+		reports[stationID] = errorReport(stationID)
+	}
+
+	return reports, nil
+}
+
+// getProviderReports asks the provider for only the pending stations when it supports subsets.
+func (c *failoverClient) getProviderReports(ctx context.Context, p *failoverProvider, pending []string) (map[string]*MetarReport, error) {
+	if subset, ok := p.client.(StationSubsetReporter); ok {
+		return subset.GetReportsFor(ctx, pending)
+	}
+
+	return p.client.GetReports(ctx)
+}
+
+func (c *failoverClient) GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error) {
+	positions = make(map[string]*MetarPosition, len(c.settings.StationIDs))
+	var lastErr error = ErrStationPositionsNotSupported
+
+	for _, p := range c.providers {
+		providerPositions, err := p.client.GetStationPositions(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			if err != ErrStationPositionsNotSupported {
+				logger.LogWarn("provider %s failed to get positions: %s", p.health.Name, err)
+				lastErr = err
+			}
+			continue
+		}
+
+		for id, pos := range providerPositions {
+			if existing, ok := positions[id]; !pos.Error && (!ok || existing.Error) {
+				positions[id] = pos
+			}
+		}
+	}
+
+	if len(positions) == 0 {
+		return nil, lastErr
+	}
+
+	for _, stationID := range c.settings.StationIDs {
+		if _, ok := positions[stationID]; !ok {
+			positions[stationID] = &MetarPosition{Error: true, StationID: stationID}
+		}
+	}
+
+	return positions, nil
+}
+
+func (c *failoverClient) GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error) {
+	forecasts = make(map[string]*TafReport, len(c.settings.StationIDs))
+	var lastErr error = ErrForecastsNotSupported
+
+	for _, p := range c.providers {
+		providerForecasts, err := p.client.GetForecasts(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			if err != ErrForecastsNotSupported {
+				logger.LogWarn("provider %s failed to get forecasts: %s", p.health.Name, err)
+				lastErr = err
+			}
+			continue
+		}
+
+		for id, f := range providerForecasts {
+			if existing, ok := forecasts[id]; !f.Error && (!ok || existing.Error) {
+				f.Provider = p.health.Name
+				forecasts[id] = f
+			}
+		}
+	}
+
+	if len(forecasts) == 0 {
+		return nil, lastErr
+	}
+
+	for _, stationID := range c.settings.StationIDs {
+		if _, ok := forecasts[stationID]; !ok {
+			forecasts[stationID] = errorTafReport(stationID)
+		}
+	}
+
+	return forecasts, nil
+}
+
+func (c *failoverClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
+	go func() {
+		reports, err := c.GetReports(ctx)
+		handler(reports, err)
+	}()
+}
+
+func (c *failoverClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
+	go func() {
+		positions, err := c.GetStationPositions(ctx)
+		handler(positions, err)
+	}()
+}
+
+func (c *failoverClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	go func() {
+		forecasts, err := c.GetForecasts(ctx)
+		handler(forecasts, err)
+	}()
+}
+
+// DiscoverStations searches with the first provider that supports discovery and succeeds.
+func (c *failoverClient) DiscoverStations(ctx context.Context, area *DiscoveryArea) ([]*DiscoveredStation, error) {
+	var lastErr error = ErrDiscoveryNotSupported
+
+	for _, p := range c.providers {
+		discoverer, ok := p.client.(StationDiscoverer)
+		if !ok {
+			continue
+		}
+
+		stations, err := discoverer.DiscoverStations(ctx, area)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if err != nil {
+			logger.LogWarn("provider %s failed to discover stations: %s", p.health.Name, err)
+			lastErr = err
+			continue
+		}
+
+		return stations, nil
+	}
+
+	return nil, lastErr
+}
+
+// ProviderHealth gets a copy of the health of each provider in order.
+func (c *failoverClient) ProviderHealth() []*ProviderHealth {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	health := make([]*ProviderHealth, len(c.providers))
+	for i, p := range c.providers {
+		h := *p.health
+		health[i] = &h
+	}
+
+	return health
+}
+
+func (c *failoverClient) pendingStations(reports map[string]*MetarReport) []string {
+	pending := make([]string, 0)
+	for _, stationID := range c.settings.StationIDs {
+		if r, ok := reports[stationID]; !ok || r.Error {
+			pending = append(pending, stationID)
+		}
+	}
+
+	return pending
+}
+
+func (c *failoverClient) recordResult(p *failoverProvider, start time.Time, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock.Now()
+	p.health.LastLatency = now.Sub(start)
+
+	if err != nil {
+		p.health.ConsecutiveFailures++
+		p.health.LastFailure = now
+		p.health.LastError = err.Error()
+		return
+	}
+
+	p.health.ConsecutiveFailures = 0
+	p.health.LastSuccess = now
+	p.health.LastError = ""
+}
+
+func providerName(settings *Settings) string {
+	if settings.EndPoint == "" {
+		return string(settings.Strategy)
+	}
+
+	return fmt.Sprintf("%s(%s)", settings.Strategy, settings.EndPoint)
+}
@@ -0,0 +1,270 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+var _ MetarClient = (*fakeMetarClient)(nil)
+
+type fakeMetarClient struct {
+	reports   map[string]*MetarReport
+	positions map[string]*MetarPosition
+	forecasts map[string]*TafReport
+	err       error
+	callCount int
+}
+
+func (c *fakeMetarClient) GetReports(ctx context.Context) (map[string]*MetarReport, error) {
+	c.callCount++
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	reports := make(map[string]*MetarReport, len(c.reports))
+	for id, r := range c.reports {
+		copied := *r
+		reports[id] = &copied
+	}
+
+	return reports, nil
+}
+
+func (c *fakeMetarClient) GetStationPositions(ctx context.Context) (map[string]*MetarPosition, error) {
+	if c.positions == nil {
+		return nil, ErrStationPositionsNotSupported
+	}
+
+	return c.positions, c.err
+}
+
+func (c *fakeMetarClient) GetForecasts(ctx context.Context) (map[string]*TafReport, error) {
+	if c.forecasts == nil {
+		return nil, ErrForecastsNotSupported
+	}
+
+	return c.forecasts, c.err
+}
+
+func (c *fakeMetarClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
+	handler(c.GetReports(ctx))
+}
+
+func (c *fakeMetarClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
+	handler(c.GetStationPositions(ctx))
+}
+
+func (c *fakeMetarClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	handler(c.GetForecasts(ctx))
+}
+
+// fakeSubsetMetarClient records the stations it's asked for.
+type fakeSubsetMetarClient struct {
+	fakeMetarClient
+	requested []string
+}
+
+func (c *fakeSubsetMetarClient) GetReportsFor(ctx context.Context, stationIDs []string) (map[string]*MetarReport, error) {
+	c.requested = append([]string{}, stationIDs...)
+
+	return c.GetReports(ctx)
+}
+
+func TestFailoverFillsMissingStations(t *testing.T) {
+	primary := &fakeMetarClient{reports: map[string]*MetarReport{
+		"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleVFR},
+		"CYYC": errorReport("CYYC"),
+	}}
+	secondary := &fakeMetarClient{reports: map[string]*MetarReport{
+		"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleIFR},
+		"CYYC": {StationID: "CYYC", FlightRules: common.FlightRuleMVFR},
+	}}
+	client := createTestFailoverClient([]string{"CYEG", "CYYC", "CYWG"}, primary, secondary)
+
+	reports, err := client.GetReports(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reports["CYEG"].FlightRules != common.FlightRuleVFR || reports["CYEG"].Provider != "primary" {
+		t.Errorf("expected CYEG from primary %+v", reports["CYEG"])
+	}
+
+	if reports["CYYC"].FlightRules != common.FlightRuleMVFR || reports["CYYC"].Provider != "secondary" {
+		t.Errorf("expected CYYC from secondary %+v", reports["CYYC"])
+	}
+
+	if !reports["CYWG"].Error || reports["CYWG"].Provider != "" {
+		t.Errorf("expected CYWG error %+v", reports["CYWG"])
+	}
+}
+
+func TestFailoverRequestsOnlyPendingStations(t *testing.T) {
+	primary := &fakeSubsetMetarClient{fakeMetarClient: fakeMetarClient{reports: map[string]*MetarReport{
+		"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleVFR},
+		"CYYC": errorReport("CYYC"),
+	}}}
+	secondary := &fakeSubsetMetarClient{fakeMetarClient: fakeMetarClient{reports: map[string]*MetarReport{
+		"CYYC": {StationID: "CYYC", FlightRules: common.FlightRuleMVFR},
+	}}}
+	client := createTestFailoverClient([]string{"CYEG", "CYWG", "CYYC"}, primary, secondary)
+
+	if _, err := client.GetReports(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(primary.requested) != 3 {
+		t.Error("expected primary to be asked for every station", primary.requested)
+	}
+
+	if len(secondary.requested) != 2 || secondary.requested[0] != "CYWG" || secondary.requested[1] != "CYYC" {
+		t.Error("expected secondary to only be asked for the pending stations", secondary.requested)
+	}
+}
+
+func TestFailoverSkipsFailedProvider(t *testing.T) {
+	primary := &fakeMetarClient{err: errors.New("timeout")}
+	secondary := &fakeMetarClient{reports: map[string]*MetarReport{
+		"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleVFR},
+	}}
+	tertiary := &fakeMetarClient{}
+	client := createTestFailoverClient([]string{"CYEG"}, primary, secondary, tertiary)
+
+	now := time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)
+	client.clock = common.ClockFunc(func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	})
+
+	for i := 0; i < 2; i++ {
+		reports, err := client.GetReports(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reports["CYEG"].Provider != "secondary" {
+			t.Error("expected secondary provider", reports["CYEG"].Provider)
+		}
+	}
+
+	if tertiary.callCount != 0 {
+		t.Error("expected tertiary to not be called once all stations are filled")
+	}
+
+	health := client.ProviderHealth()
+	if health[0].ConsecutiveFailures != 2 || health[0].LastError != "timeout" || !health[0].LastSuccess.IsZero() {
+		t.Errorf("unnexpected primary health %+v", health[0])
+	}
+
+	if health[1].ConsecutiveFailures != 0 || health[1].LastSuccess.IsZero() || health[1].LastLatency != time.Second {
+		t.Errorf("unnexpected secondary health %+v", health[1])
+	}
+
+	primary.err = nil
+	primary.reports = secondary.reports
+	client.GetReports(context.Background())
+
+	if health := client.ProviderHealth(); health[0].ConsecutiveFailures != 0 || health[0].LastError != "" {
+		t.Errorf("expected primary to recover %+v", health[0])
+	}
+}
+
+func TestFailoverAllProvidersFail(t *testing.T) {
+	client := createTestFailoverClient([]string{"CYEG"}, &fakeMetarClient{err: errors.New("a")}, &fakeMetarClient{err: errors.New("b")})
+
+	if _, err := client.GetReports(context.Background()); err == nil {
+		t.Error("expected error when all providers fail")
+	}
+}
+
+func TestFailoverStationPositions(t *testing.T) {
+	primary := &fakeMetarClient{}
+	secondary := &fakeMetarClient{positions: map[string]*MetarPosition{
+		"CYEG": {StationID: "CYEG", Latitude: 53.3},
+	}}
+	client := createTestFailoverClient([]string{"CYEG", "CYYC"}, primary, secondary)
+
+	positions, err := client.GetStationPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if positions["CYEG"].Latitude != 53.3 || !positions["CYYC"].Error {
+		t.Error("unnexpected positions")
+	}
+}
+
+func TestCreateFailoverClient(t *testing.T) {
+	if _, err := CreateMetarClient(&Settings{Strategy: common.FailoverMetarStrategy}); err == nil {
+		t.Error("expected error without providers")
+	}
+
+	client, err := CreateMetarClient(&Settings{
+		StationIDs: []string{"CYEG"},
+		Strategy:   common.FailoverMetarStrategy,
+		Providers: []*Settings{
+			{Strategy: common.AviationWeatherAPIMetarStrategy},
+			{Strategy: common.NOAATextMetarStrategy, EndPoint: "/tmp/metars"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	health := client.(ProviderHealthReporter).ProviderHealth()
+	if health[0].Name != "AviationWeatherAPI" || health[1].Name != "NOAAText(/tmp/metars)" {
+		t.Error("unnexpected provider names", health[0].Name, health[1].Name)
+	}
+}
+
+func TestFailoverForecastsSkipUnsupportedProviders(t *testing.T) {
+	unsupported := &fakeMetarClient{}
+	primary := &fakeMetarClient{forecasts: map[string]*TafReport{
+		"CYEG": {StationID: "CYEG"},
+		"CYYC": errorTafReport("CYYC"),
+	}}
+	secondary := &fakeMetarClient{forecasts: map[string]*TafReport{
+		"CYYC": {StationID: "CYYC"},
+	}}
+	client := createTestFailoverClient([]string{"CYEG", "CYYC", "CYWG"}, unsupported, primary, secondary)
+
+	forecasts, err := client.GetForecasts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if forecasts["CYEG"].Provider != "secondary" || forecasts["CYYC"].Provider != "tertiary" {
+		t.Error("unnexpected forecast providers", forecasts["CYEG"].Provider, forecasts["CYYC"].Provider)
+	}
+
+	if !forecasts["CYWG"].Error {
+		t.Error("expected error forecast for CYWG")
+	}
+
+	client = createTestFailoverClient([]string{"CYEG"}, unsupported)
+	if _, err := client.GetForecasts(context.Background()); err != ErrForecastsNotSupported {
+		t.Error("expected forecasts not supported", err)
+	}
+}
+
+func createTestFailoverClient(stationIDs []string, clients ...MetarClient) *failoverClient {
+	names := []string{"primary", "secondary", "tertiary"}
+	c := &failoverClient{
+		settings:  &Settings{StationIDs: stationIDs},
+		providers: make([]*failoverProvider, len(clients)),
+		clock:     common.SystemClock{},
+	}
+
+	for i, client := range clients {
+		c.providers[i] = &failoverProvider{
+			client: client,
+			health: &ProviderHealth{Name: names[i]},
+		}
+	}
+
+	return c
+}
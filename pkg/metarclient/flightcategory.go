@@ -0,0 +1,160 @@
+package metarclient
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+// FlightCategoryThreshold is the ceiling and visibility a station must be below to fall into a category.
+type FlightCategoryThreshold struct {
+	Category     string
+	CeilingFt    int
+	VisibilitySM float64
+	// Inclusive treats values at the threshold as matching the category.
+	Inclusive bool
+}
+
+// FlightCategoryRules derives a flight category from ceiling and visibility.
+// Thresholds are checked in order so the most restrictive category should come first.
+type FlightCategoryRules struct {
+	Thresholds []*FlightCategoryThreshold
+}
+
+// FAAFlightCategoryRules are the US FAA LIFR/IFR/MVFR/VFR definitions.
+func FAAFlightCategoryRules() *FlightCategoryRules {
+	return &FlightCategoryRules{
+		Thresholds: []*FlightCategoryThreshold{
+			{Category: common.FlightRuleLIFR, CeilingFt: 500, VisibilitySM: 1},
+			{Category: common.FlightRuleIFR, CeilingFt: 1000, VisibilitySM: 3},
+			{Category: common.FlightRuleMVFR, CeilingFt: 3000, VisibilitySM: 5, Inclusive: true},
+		},
+	}
+}
+
+// CanadaFlightCategoryRules follow NAV CANADA's GFA categories which have no LIFR.
+func CanadaFlightCategoryRules() *FlightCategoryRules {
+	return &FlightCategoryRules{
+		Thresholds: []*FlightCategoryThreshold{
+			{Category: common.FlightRuleIFR, CeilingFt: 1000, VisibilitySM: 3},
+			{Category: common.FlightRuleMVFR, CeilingFt: 3000, VisibilitySM: 5, Inclusive: true},
+		},
+	}
+}
+
+// FlightCategoryRulesFromSettings builds the rules from a preset, or from custom thresholds if any are set.
+func FlightCategoryRulesFromSettings(settings *common.FlightCategorySettings) (*FlightCategoryRules, error) {
+	if settings == nil {
+		return FAAFlightCategoryRules(), nil
+	}
+
+	if len(settings.Thresholds) > 0 {
+		rules := &FlightCategoryRules{
+			Thresholds: make([]*FlightCategoryThreshold, len(settings.Thresholds)),
+		}
+
+		for i, t := range settings.Thresholds {
+			rules.Thresholds[i] = &FlightCategoryThreshold{
+				Category:     t.Category,
+				CeilingFt:    t.CeilingFt,
+				VisibilitySM: t.VisibilitySM,
+				Inclusive:    t.Inclusive,
+			}
+		}
+
+		return rules, nil
+	}
+
+	switch settings.Preset {
+	case common.FlightCategoryPresetFAA, "":
+		return FAAFlightCategoryRules(), nil
+	case common.FlightCategoryPresetCanada:
+		return CanadaFlightCategoryRules(), nil
+	default:
+		return nil, fmt.Errorf("unsupported flight category preset '%s'", settings.Preset)
+	}
+}
+
+// Categorize gets the flight category for the ceiling and visibility.
+// An empty string is returned if there isn't enough information to be sure.
+func (r *FlightCategoryRules) Categorize(ceilingFt int, hasCeiling bool, visibilitySM float64, hasVisibility bool) string {
+	ceiling := math.Inf(1)
+	if hasCeiling {
+		ceiling = float64(ceilingFt)
+	}
+
+	for _, t := range r.Thresholds {
+		if belowThreshold(ceiling, float64(t.CeilingFt), t.Inclusive) {
+			return t.Category
+		}
+
+		if hasVisibility && belowThreshold(visibilitySM, t.VisibilitySM, t.Inclusive) {
+			return t.Category
+		}
+	}
+
+	if !hasVisibility {
+		return ""
+	}
+
+	return common.FlightRuleVFR
+}
+
+// CategorizeMetar gets the flight category for a decoded metar.
+func (r *FlightCategoryRules) CategorizeMetar(metar *DecodedMetar) string {
+	ceiling, hasCeiling := CeilingFtAGL(metar.SkyLayers)
+
+	if metar.Visibility == nil {
+		return r.Categorize(ceiling, hasCeiling, 0, false)
+	}
+
+	return r.Categorize(ceiling, hasCeiling, metar.Visibility.StatuteMiles, true)
+}
+
+// CeilingFtAGL gets the base of the lowest broken, overcast, or vertical visibility layer.
+func CeilingFtAGL(layers []*SkyLayer) (ceilingFt int, ok bool) {
+	for _, l := range layers {
+		switch l.Cover {
+		case SkyCoverBroken, SkyCoverOvercast, SkyCoverVertVis:
+			if !ok || l.BaseFtAGL < ceilingFt {
+				ceilingFt = l.BaseFtAGL
+				ok = true
+			}
+		}
+	}
+
+	return ceilingFt, ok
+}
+
+func belowThreshold(value float64, threshold float64, inclusive bool) bool {
+	if inclusive {
+		return value <= threshold
+	}
+
+	return value < threshold
+}
+
+// resolveFlightRules fills in a missing flight category from the decoded metar and logs any disagreement with the provider.
+func resolveFlightRules(report *MetarReport, rules *FlightCategoryRules) {
+	if report.Error {
+		return
+	}
+
+	computed := ""
+	if report.Decoded != nil {
+		computed = rules.CategorizeMetar(report.Decoded)
+	}
+
+	switch {
+	case report.FlightRules == "" && computed == "":
+		logger.LogWarn("station %s has no flight rule", report.StationID)
+		report.FlightRules = common.FlightRuleUnknown
+	case report.FlightRules == "":
+		logger.LogDebug("station %s flight rule computed as %s", report.StationID, computed)
+		report.FlightRules = computed
+	case computed != "" && computed != report.FlightRules:
+		logger.LogWarn("station %s provider flight rule %s disagrees with computed %s", report.StationID, report.FlightRules, computed)
+	}
+}
@@ -0,0 +1,132 @@
+package metarclient
+
+import (
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+func TestFAAFlightCategory(t *testing.T) {
+	rules := FAAFlightCategoryRules()
+
+	table := []struct {
+		raw      string
+		expected string
+	}{
+		{"KDEN 100700Z 21008KT 10SM FEW030 SCT050", common.FlightRuleVFR},
+		{"KDEN 100700Z 21008KT 10SM BKN031", common.FlightRuleVFR},
+		{"KDEN 100700Z 21008KT 10SM BKN030", common.FlightRuleMVFR},
+		{"KDEN 100700Z 21008KT 5SM FEW010", common.FlightRuleMVFR},
+		{"KDEN 100700Z 21008KT 10SM SCT005 OVC009", common.FlightRuleIFR},
+		{"KDEN 100700Z 21008KT 2 1/2SM BR", common.FlightRuleIFR},
+		{"KDEN 100700Z 21008KT 10SM BKN004", common.FlightRuleLIFR},
+		{"KDEN 100700Z 21008KT 1/2SM FG VV001", common.FlightRuleLIFR},
+		{"KDEN 100700Z 21008KT 6SM VV///", common.FlightRuleLIFR},
+		{"EGLL 100700Z 21008KT CAVOK", common.FlightRuleVFR},
+		{"EGLL 100700Z 21008KT 9999 FEW040", common.FlightRuleVFR},
+		{"KDEN 100700Z 21008KT BKN008", common.FlightRuleIFR},
+		{"KDEN 100700Z 21008KT FEW008", ""},
+	}
+
+	for _, row := range table {
+		m, err := ParseMetar(row.raw)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if category := rules.CategorizeMetar(m); category != row.expected {
+			t.Errorf("%s => '%s', expected '%s'", row.raw, category, row.expected)
+		}
+	}
+}
+
+func TestCanadaFlightCategory(t *testing.T) {
+	rules := CanadaFlightCategoryRules()
+
+	table := []struct {
+		raw      string
+		expected string
+	}{
+		{"CYWG 100700Z 21008KT 15SM FEW030", common.FlightRuleVFR},
+		{"CYWG 100700Z 21008KT 5SM BKN040", common.FlightRuleMVFR},
+		{"CYWG 100700Z 21008KT 2SM BR OVC009", common.FlightRuleIFR},
+		{"CYWG 100700Z 21008KT 1/4SM FG VV001", common.FlightRuleIFR},
+	}
+
+	for _, row := range table {
+		m, _ := ParseMetar(row.raw)
+		if category := rules.CategorizeMetar(m); category != row.expected {
+			t.Errorf("%s => '%s', expected '%s'", row.raw, category, row.expected)
+		}
+	}
+}
+
+func TestFlightCategoryRulesFromSettings(t *testing.T) {
+	rules, err := FlightCategoryRulesFromSettings(nil)
+	if err != nil || len(rules.Thresholds) != 3 {
+		t.Error("expected faa rules by default")
+	}
+
+	rules, err = FlightCategoryRulesFromSettings(&common.FlightCategorySettings{Preset: common.FlightCategoryPresetCanada})
+	if err != nil || len(rules.Thresholds) != 2 {
+		t.Error("expected canada rules")
+	}
+
+	rules, err = FlightCategoryRulesFromSettings(&common.FlightCategorySettings{
+		Preset: common.FlightCategoryPresetFAA,
+		Thresholds: []*common.FlightCategoryThresholdSettings{
+			{Category: common.FlightRuleIFR, CeilingFt: 1500, VisibilitySM: 2},
+		},
+	})
+	if err != nil || len(rules.Thresholds) != 1 || rules.Thresholds[0].CeilingFt != 1500 {
+		t.Error("expected custom thresholds to override preset")
+	}
+
+	if _, err = FlightCategoryRulesFromSettings(&common.FlightCategorySettings{Preset: "mars"}); err == nil {
+		t.Error("expected preset error")
+	}
+}
+
+func TestResolveFlightRules(t *testing.T) {
+	writer := logger.InitLoggersToTestWriter()
+	rules := FAAFlightCategoryRules()
+
+	report := &MetarReport{StationID: "KDEN", Decoded: mustParseMetar("KDEN 100700Z 21008KT 2SM BR OVC004", t)}
+	resolveFlightRules(report, rules)
+	if report.FlightRules != common.FlightRuleLIFR {
+		t.Error("expected computed flight rule to fill in blank", report.FlightRules)
+	}
+
+	report = &MetarReport{StationID: "KDEN", FlightRules: common.FlightRuleVFR, Decoded: mustParseMetar("KDEN 100700Z 21008KT 2SM BR OVC004", t)}
+	writer.Lines = nil
+	resolveFlightRules(report, rules)
+	if report.FlightRules != common.FlightRuleVFR {
+		t.Error("expected provider flight rule to be kept", report.FlightRules)
+	}
+	if len(writer.Lines) != 1 {
+		t.Error("expected disagreement warning")
+	}
+
+	report = &MetarReport{StationID: "KDEN"}
+	resolveFlightRules(report, rules)
+	if report.FlightRules != common.FlightRuleUnknown {
+		t.Error("expected unknown flight rule", report.FlightRules)
+	}
+
+	report = &MetarReport{StationID: "KDEN", Error: true, FlightRules: common.FlightRuleError}
+	resolveFlightRules(report, rules)
+	if report.FlightRules != common.FlightRuleError {
+		t.Error("expected error flight rule to be kept", report.FlightRules)
+	}
+}
+
+func mustParseMetar(raw string, t *testing.T) *DecodedMetar {
+	m, err := ParseMetar(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
@@ -0,0 +1,129 @@
+package metarclient
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+var ErrForecastsNotSupported = errors.New("metar strategy does not support forecasts")
+
+type TafResponseHandler func(forecasts map[string]*TafReport, err error)
+
+// TafReport is the latest forecast for a station.
+type TafReport struct {
+	Error     bool
+	StationID string
+	IssueTime time.Time
+	ValidFrom time.Time
+	ValidTo   time.Time
+	RawText   string
+	Decoded   *DecodedTAF
+	Provider  string
+}
+
+// FlightRulesAt gets the forecast flight category at the time.
+// Temporary and probable conditions are used when they are worse than the prevailing conditions.
+// An empty string is returned when the time is outside of the forecast.
+func (r *TafReport) FlightRulesAt(t time.Time) string {
+	if r.Error || r.Decoded == nil || t.Before(r.ValidFrom) || !t.Before(r.ValidTo) {
+		return ""
+	}
+
+	prevailing := ""
+	for _, p := range r.Decoded.Periods {
+		if p.Temporary() || t.Before(p.From) {
+			continue
+		}
+
+		prevailing = p.FlightRules
+	}
+
+	flightRules := prevailing
+	for _, p := range r.Decoded.Periods {
+		if p.Temporary() && !t.Before(p.From) && t.Before(p.To) && FlightRulesWorse(p.FlightRules, flightRules) {
+			flightRules = p.FlightRules
+		}
+	}
+
+	return flightRules
+}
+
+// FlightRulesWorse checks if the first flight category is more restrictive than the second.
+// Unknown categories are never worse.
+func FlightRulesWorse(a string, b string) bool {
+	rankA, okA := flightRulesRank(a)
+	rankB, okB := flightRulesRank(b)
+
+	return okA && (!okB || rankA < rankB)
+}
+
+func flightRulesRank(flightRules string) (int, bool) {
+	switch flightRules {
+	case common.FlightRuleLIFR:
+		return 0, true
+	case common.FlightRuleIFR:
+		return 1, true
+	case common.FlightRuleSVFR, common.FlightRuleMVFR:
+		return 2, true
+	case common.FlightRuleVFR:
+		return 3, true
+	default:
+		return 0, false
+	}
+}
+
+// buildTafReports builds a report for each station from the raw TAFs, with error reports for those missing.
+func buildTafReports(stationIDs []string, rawTexts map[string]string, reference time.Time, rules *FlightCategoryRules) map[string]*TafReport {
+	forecasts := make(map[string]*TafReport, len(stationIDs))
+	for _, stationID := range stationIDs {
+		rawText, ok := rawTexts[stationID]
+		if !ok || rawText == "" {
+			logger.LogWarn("failed to receive forecast for station '%s'", stationID)
+			forecasts[stationID] = errorTafReport(stationID)
+			continue
+		}
+
+		forecasts[stationID] = buildTafReport(stationID, rawText, reference, rules)
+	}
+
+	return forecasts
+}
+
+// buildTafReport decodes the raw TAF and resolves its periods against the reference time.
+func buildTafReport(stationID string, rawText string, reference time.Time, rules *FlightCategoryRules) *TafReport {
+	decoded, err := ParseTAF(rawText)
+	if err != nil {
+		logger.LogWarn("failed to decode taf for station '%s': %s", stationID, err)
+		return errorTafReport(stationID)
+	}
+
+	validFrom, validTo, err := decoded.Resolve(reference, rules)
+	if err != nil {
+		logger.LogWarn("failed to resolve taf times for station '%s': %s", stationID, err)
+		return errorTafReport(stationID)
+	}
+
+	report := &TafReport{
+		StationID: stationID,
+		ValidFrom: validFrom,
+		ValidTo:   validTo,
+		RawText:   rawText,
+		Decoded:   decoded,
+	}
+
+	if decoded.Issued != nil {
+		report.IssueTime, _ = resolveDayTime(*decoded.Issued, reference.Add(time.Hour))
+	}
+
+	return report
+}
+
+func errorTafReport(stationID string) *TafReport {
+	return &TafReport{
+		Error:     true,
+		StationID: stationID,
+	}
+}
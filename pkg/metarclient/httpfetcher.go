@@ -0,0 +1,110 @@
+package metarclient
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+const (
+	DefaultHTTPTimeout    = 20 * time.Second
+	DefaultRetryBaseDelay = 2 * time.Second
+	maxRetryDelay         = 30 * time.Second
+)
+
+// httpFetcher makes GET requests with a timeout, retrying network errors and 5xx responses with jittered exponential backoff.
+type httpFetcher struct {
+	client     *http.Client
+	retryCount int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	random     *rand.Rand
+	randomLock sync.Mutex
+}
+
+func newHTTPFetcher(settings *Settings) *httpFetcher {
+	timeout := settings.HTTPTimeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	baseDelay := settings.RetryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = DefaultRetryBaseDelay
+	}
+
+	retryCount := settings.RetryCount
+	if retryCount < 0 {
+		retryCount = 0
+	}
+
+	return &httpFetcher{
+		client:     &http.Client{Timeout: timeout},
+		retryCount: retryCount,
+		baseDelay:  baseDelay,
+		maxDelay:   maxRetryDelay,
+		random:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// get requests the url, retrying transient failures until the retries are used up or the context is done.
+// The last response is returned for the caller to check when it is a 5xx after the final retry.
+func (f *httpFetcher) get(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %s", err)
+	}
+	request = request.WithContext(ctx)
+
+	for attempt := 0; ; attempt++ {
+		response, err := f.client.Do(request)
+		if ctx.Err() != nil {
+			if response != nil {
+				response.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		transient := err != nil || response.StatusCode >= http.StatusInternalServerError
+		if !transient || attempt >= f.retryCount {
+			return response, err
+		}
+
+		if err != nil {
+			logger.LogWarn("request failed, attempt %d of %d: %s", attempt+1, f.retryCount+1, err)
+		} else {
+			logger.LogWarn("request failed, attempt %d of %d: status %d", attempt+1, f.retryCount+1, response.StatusCode)
+			response.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(f.retryDelay(attempt)):
+		}
+	}
+}
+
+// retryDelay doubles the base delay for each attempt up to the max, with the upper half jittered.
+func (f *httpFetcher) retryDelay(attempt int) time.Duration {
+	delay := f.baseDelay
+	for i := 0; i < attempt && delay < f.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > f.maxDelay {
+		delay = f.maxDelay
+	}
+
+	half := delay / 2
+
+	f.randomLock.Lock()
+	defer f.randomLock.Unlock()
+
+	return half + time.Duration(f.random.Int63n(int64(half)+1))
+}
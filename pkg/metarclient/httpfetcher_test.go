@@ -0,0 +1,167 @@
+package metarclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testFetcher(retryCount int, timeout time.Duration) *httpFetcher {
+	return newHTTPFetcher(&Settings{
+		HTTPTimeout:    timeout,
+		RetryCount:     retryCount,
+		RetryBaseDelay: time.Millisecond,
+	})
+}
+
+func TestHTTPFetcherRetriesServerErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	response, err := testFetcher(3, time.Second).get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || atomic.LoadInt32(&requests) != 3 {
+		t.Error("unnexpected result", response.StatusCode, requests)
+	}
+}
+
+func TestHTTPFetcherReturnsLastResponse(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	response, err := testFetcher(2, time.Second).get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusInternalServerError || atomic.LoadInt32(&requests) != 3 {
+		t.Error("unnexpected result", response.StatusCode, requests)
+	}
+}
+
+func TestHTTPFetcherDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	response, err := testFetcher(3, time.Second).get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusNotFound || atomic.LoadInt32(&requests) != 1 {
+		t.Error("unnexpected result", response.StatusCode, requests)
+	}
+}
+
+func TestHTTPFetcherRetriesDroppedConnections(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	response, err := testFetcher(1, time.Second).get(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || atomic.LoadInt32(&requests) != 2 {
+		t.Error("unnexpected result", response.StatusCode, requests)
+	}
+}
+
+func TestHTTPFetcherTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	if _, err := testFetcher(1, 20*time.Millisecond).get(context.Background(), server.URL); err == nil {
+		t.Error("expected timeout error")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Error("took too long to time out", elapsed)
+	}
+}
+
+func TestHTTPFetcherCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	fetcher := testFetcher(5, time.Second)
+	fetcher.baseDelay = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	if _, err := fetcher.get(ctx, server.URL); err != context.Canceled {
+		t.Error("expected cancelled error", err)
+	}
+}
+
+func TestHTTPFetcherRetryDelay(t *testing.T) {
+	fetcher := newHTTPFetcher(&Settings{RetryBaseDelay: time.Second})
+
+	table := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{10, maxRetryDelay / 2, maxRetryDelay},
+	}
+
+	for _, row := range table {
+		for i := 0; i < 20; i++ {
+			if delay := fetcher.retryDelay(row.attempt); delay < row.min || delay > row.max {
+				t.Error("unnexpected delay", row.attempt, delay)
+			}
+		}
+	}
+}
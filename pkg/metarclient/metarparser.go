@@ -0,0 +1,493 @@
+package metarclient
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReportTypeMETAR = "METAR"
+	ReportTypeSPECI = "SPECI"
+
+	SkyCoverFew       = "FEW"
+	SkyCoverScattered = "SCT"
+	SkyCoverBroken    = "BKN"
+	SkyCoverOvercast  = "OVC"
+	SkyCoverVertVis   = "VV"
+	SkyCoverClear     = "CLR"
+	SkyCoverSkyClear  = "SKC"
+	SkyCoverNSC       = "NSC"
+	SkyCoverNCD       = "NCD"
+
+	IntensityLight    = "-"
+	IntensityModerate = ""
+	IntensityHeavy    = "+"
+	IntensityVicinity = "VC"
+
+	metersPerStatuteMile = 1609.344
+	kmhPerKt             = 1.852
+	mpsPerKt             = 0.514444
+	inHgPerHPa           = 0.0295300
+)
+
+var (
+	stationPattern      = regexp.MustCompile(`^[A-Z][A-Z0-9]{3}$`)
+	timePattern         = regexp.MustCompile(`^(\d{2})(\d{2})(\d{2})Z$`)
+	windPattern         = regexp.MustCompile(`^(\d{3}|VRB|///)(\d{2,3}|//)(?:G(\d{2,3}))?(KT|MPS|KMH)$`)
+	windVariablePattern = regexp.MustCompile(`^(\d{3})V(\d{3})$`)
+	visWholePattern     = regexp.MustCompile(`^\d{1,2}$`)
+	visSMPattern        = regexp.MustCompile(`^(M|P)?(?:(\d{1,2})|(\d)/(\d{1,2}))SM$`)
+	visMetricPattern    = regexp.MustCompile(`^(\d{4})(NDV)?$`)
+	rvrPattern          = regexp.MustCompile(`^R(\d{2}[LCR]?)/([MP])?(\d{4})(?:V([MP])?(\d{4}))?(FT)?/?([UDN])?$`)
+	weatherPattern      = regexp.MustCompile(`^(-|\+|VC)?(MI|PR|BC|DR|BL|SH|TS|FZ)?((?:DZ|RA|SN|SG|IC|PL|GR|GS|UP|BR|FG|FU|VA|DU|SA|HZ|PY|PO|SQ|FC|SS|DS)*)$`)
+	cloudPattern        = regexp.MustCompile(`^(FEW|SCT|BKN|OVC|VV)(\d{3}|///)(CB|TCU|///)?$`)
+	clearSkyPattern     = regexp.MustCompile(`^(SKC|CLR|NSC|NCD)$`)
+	temperaturePattern  = regexp.MustCompile(`^(M)?(\d{2})/(?:(M)?(\d{2}))?$`)
+	altimeterPattern    = regexp.MustCompile(`^([AQ])(\d{4})$`)
+	slpPattern          = regexp.MustCompile(`^SLP(\d{3})$`)
+	preciseTempPattern  = regexp.MustCompile(`^T([01])(\d{3})(?:([01])(\d{3}))?$`)
+	trendFromPattern    = regexp.MustCompile(`^FM\d{4}$`)
+)
+
+// DecodedMetar is a METAR report decoded from its raw text.
+// Trend is the raw forecast trend after the observation, like "TEMPO 3000 SHRA" or "NOSIG", and isn't decoded.
+type DecodedMetar struct {
+	RawText            string
+	ReportType         string
+	StationID          string
+	Day                int
+	Hour               int
+	Minute             int
+	Auto               bool
+	Corrected          bool
+	Nil                bool
+	Wind               *Wind
+	Visibility         *Visibility
+	RunwayVisualRanges []*RunwayVisualRange
+	Weather            []*WeatherPhenomenon
+	SkyLayers          []*SkyLayer
+	Temperature        *Temperature
+	AltimeterInHg      float64
+	Remarks            *Remarks
+	Trend              string
+	Unparsed           []string
+}
+
+// Wind is the surface wind group.
+type Wind struct {
+	DirectionDeg     int
+	Variable         bool
+	SpeedKts         float64
+	GustKts          float64
+	HasVariableRange bool
+	VariableFromDeg  int
+	VariableToDeg    int
+}
+
+// Visibility is the prevailing visibility group.
+type Visibility struct {
+	StatuteMiles float64
+	Meters       float64
+	LessThan     bool
+	GreaterThan  bool
+	CAVOK        bool
+}
+
+// RunwayVisualRange is a single RVR group.
+type RunwayVisualRange struct {
+	Runway      string
+	Min         int
+	Max         int
+	Unit        string
+	LessThan    bool
+	GreaterThan bool
+	Trend       string
+}
+
+// WeatherPhenomenon is a present weather group such as "-SHRA" or "+TSRAGR".
+type WeatherPhenomenon struct {
+	Raw        string
+	Intensity  string
+	Descriptor string
+	Phenomena  []string
+}
+
+// SkyLayer is a single cloud layer or clear sky group.
+type SkyLayer struct {
+	Cover     string
+	BaseFtAGL int
+	HasBase   bool
+	CloudType string
+}
+
+// Temperature is the temperature and dewpoint group.
+type Temperature struct {
+	TemperatureC float64
+	DewpointC    float64
+	HasDewpoint  bool
+}
+
+// Remarks holds the commonly used parts of the RMK section.
+type Remarks struct {
+	Raw                   string
+	StationType           string
+	SeaLevelPressureHPa   float64
+	HasPreciseTemperature bool
+	PreciseTemperatureC   float64
+	PreciseDewpointC      float64
+}
+
+// Has checks if the phenomenon or descriptor code (ex: "TS", "RA") is in the group.
+func (w *WeatherPhenomenon) Has(code string) bool {
+	if w.Descriptor == code {
+		return true
+	}
+
+	for _, p := range w.Phenomena {
+		if p == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsFreezingPrecipitation checks if the group is freezing rain or drizzle.
+func (w *WeatherPhenomenon) IsFreezingPrecipitation() bool {
+	return w.Descriptor == "FZ" && (w.Has("RA") || w.Has("DZ"))
+}
+
+// HasWeather checks if any present weather group contains the code.
+func (m *DecodedMetar) HasWeather(code string) bool {
+	for _, w := range m.Weather {
+		if w.Intensity != IntensityVicinity && w.Has(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseMetar decodes the raw text of a METAR or SPECI report.
+func ParseMetar(raw string) (*DecodedMetar, error) {
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "="))
+	tokens := strings.Fields(text)
+	if len(tokens) == 0 {
+		return nil, errors.New("empty metar")
+	}
+
+	m := &DecodedMetar{
+		RawText:    raw,
+		ReportType: ReportTypeMETAR,
+	}
+
+	idx := 0
+	if tokens[idx] == ReportTypeMETAR || tokens[idx] == ReportTypeSPECI {
+		m.ReportType = tokens[idx]
+		idx++
+	}
+
+	if idx < len(tokens) && tokens[idx] == "COR" {
+		m.Corrected = true
+		idx++
+	}
+
+	if idx >= len(tokens) || !stationPattern.MatchString(tokens[idx]) {
+		return nil, fmt.Errorf("failed to find station in metar '%s'", raw)
+	}
+	m.StationID = tokens[idx]
+	idx++
+
+	if idx >= len(tokens) {
+		return nil, fmt.Errorf("failed to find time in metar '%s'", raw)
+	}
+	timeMatch := timePattern.FindStringSubmatch(tokens[idx])
+	if timeMatch == nil {
+		return nil, fmt.Errorf("failed to parse time in metar '%s'", raw)
+	}
+	m.Day, _ = strconv.Atoi(timeMatch[1])
+	m.Hour, _ = strconv.Atoi(timeMatch[2])
+	m.Minute, _ = strconv.Atoi(timeMatch[3])
+	idx++
+
+	for ; idx < len(tokens); idx++ {
+		token := tokens[idx]
+
+		switch {
+		case token == "RMK":
+			m.Remarks = parseRemarks(tokens[idx+1:])
+			return m, nil
+		case isTrendToken(token):
+			// The trend is a forecast so it's kept out of the observation.
+			end := idx
+			for end < len(tokens) && tokens[end] != "RMK" {
+				end++
+			}
+			m.Trend = strings.Join(tokens[idx:end], " ")
+			idx = end - 1
+		case token == "NIL":
+			m.Nil = true
+		case token == "AUTO":
+			m.Auto = true
+		case token == "COR":
+			m.Corrected = true
+		case token == "CAVOK":
+			m.Visibility = &Visibility{StatuteMiles: 10000 / metersPerStatuteMile, Meters: 10000, GreaterThan: true, CAVOK: true}
+		case m.Wind == nil && windPattern.MatchString(token):
+			m.Wind = parseWind(token)
+		case m.Wind != nil && !m.Wind.HasVariableRange && windVariablePattern.MatchString(token):
+			match := windVariablePattern.FindStringSubmatch(token)
+			m.Wind.HasVariableRange = true
+			m.Wind.VariableFromDeg, _ = strconv.Atoi(match[1])
+			m.Wind.VariableToDeg, _ = strconv.Atoi(match[2])
+		case m.Visibility == nil && visWholePattern.MatchString(token) && idx+1 < len(tokens) && visSMPattern.MatchString(tokens[idx+1]):
+			m.Visibility = parseVisibilitySM(token, tokens[idx+1])
+			idx++
+		case m.Visibility == nil && visSMPattern.MatchString(token):
+			m.Visibility = parseVisibilitySM("", token)
+		case m.Visibility == nil && visMetricPattern.MatchString(token):
+			m.Visibility = parseVisibilityMetric(token)
+		case rvrPattern.MatchString(token):
+			m.RunwayVisualRanges = append(m.RunwayVisualRanges, parseRVR(token))
+		case clearSkyPattern.MatchString(token):
+			m.SkyLayers = append(m.SkyLayers, &SkyLayer{Cover: token})
+		case cloudPattern.MatchString(token):
+			m.SkyLayers = append(m.SkyLayers, parseSkyLayer(token))
+		case m.Temperature == nil && temperaturePattern.MatchString(token):
+			m.Temperature = parseTemperature(token)
+		case altimeterPattern.MatchString(token):
+			m.AltimeterInHg = parseAltimeter(token)
+		case isWeatherToken(token):
+			m.Weather = append(m.Weather, parseWeather(token))
+		default:
+			m.Unparsed = append(m.Unparsed, token)
+		}
+	}
+
+	return m, nil
+}
+
+// isTrendToken checks if the token starts a trend group, like the change groups of a TAF.
+func isTrendToken(token string) bool {
+	switch token {
+	case "NOSIG", ForecastChangeTemporary, ForecastChangeBecoming:
+		return true
+	}
+
+	return tafProbPattern.MatchString(token) || trendFromPattern.MatchString(token)
+}
+
+func parseWind(token string) *Wind {
+	match := windPattern.FindStringSubmatch(token)
+	wind := &Wind{}
+
+	if match[1] == "VRB" {
+		wind.Variable = true
+	} else {
+		wind.DirectionDeg, _ = strconv.Atoi(match[1])
+	}
+
+	speed, _ := strconv.ParseFloat(match[2], 64)
+	gust, _ := strconv.ParseFloat(match[3], 64)
+
+	wind.SpeedKts = speedToKts(speed, match[4])
+	wind.GustKts = speedToKts(gust, match[4])
+
+	return wind
+}
+
+func speedToKts(speed float64, unit string) float64 {
+	switch unit {
+	case "MPS":
+		return speed / mpsPerKt
+	case "KMH":
+		return speed / kmhPerKt
+	default:
+		return speed
+	}
+}
+
+func parseVisibilitySM(whole string, token string) *Visibility {
+	match := visSMPattern.FindStringSubmatch(token)
+	vis := &Visibility{
+		LessThan:    match[1] == "M",
+		GreaterThan: match[1] == "P",
+	}
+
+	if whole != "" {
+		wholeVal, _ := strconv.ParseFloat(whole, 64)
+		vis.StatuteMiles += wholeVal
+	}
+
+	if match[2] != "" {
+		wholeVal, _ := strconv.ParseFloat(match[2], 64)
+		vis.StatuteMiles += wholeVal
+	} else {
+		numerator, _ := strconv.ParseFloat(match[3], 64)
+		denominator, _ := strconv.ParseFloat(match[4], 64)
+		if denominator > 0 {
+			vis.StatuteMiles += numerator / denominator
+		}
+	}
+
+	vis.Meters = vis.StatuteMiles * metersPerStatuteMile
+
+	return vis
+}
+
+func parseVisibilityMetric(token string) *Visibility {
+	match := visMetricPattern.FindStringSubmatch(token)
+	meters, _ := strconv.ParseFloat(match[1], 64)
+
+	vis := &Visibility{
+		Meters: meters,
+	}
+
+	if meters == 9999 {
+		vis.Meters = 10000
+		vis.GreaterThan = true
+	}
+	vis.StatuteMiles = vis.Meters / metersPerStatuteMile
+
+	return vis
+}
+
+func parseRVR(token string) *RunwayVisualRange {
+	match := rvrPattern.FindStringSubmatch(token)
+
+	rvr := &RunwayVisualRange{
+		Runway:      match[1],
+		LessThan:    match[2] == "M",
+		GreaterThan: match[2] == "P",
+		Unit:        "M",
+		Trend:       match[7],
+	}
+
+	rvr.Min, _ = strconv.Atoi(match[3])
+	rvr.Max = rvr.Min
+	if match[5] != "" {
+		rvr.Max, _ = strconv.Atoi(match[5])
+		rvr.GreaterThan = match[4] == "P"
+	}
+
+	if match[6] == "FT" {
+		rvr.Unit = "FT"
+	}
+
+	return rvr
+}
+
+func isWeatherToken(token string) bool {
+	match := weatherPattern.FindStringSubmatch(token)
+	if match == nil {
+		return false
+	}
+
+	// A descriptor on its own is only valid for thunderstorms or vicinity showers.
+	return match[3] != "" || match[2] == "TS" || (match[1] == IntensityVicinity && match[2] != "")
+}
+
+func parseWeather(token string) *WeatherPhenomenon {
+	match := weatherPattern.FindStringSubmatch(token)
+
+	weather := &WeatherPhenomenon{
+		Raw:        token,
+		Intensity:  match[1],
+		Descriptor: match[2],
+		Phenomena:  make([]string, 0, len(match[3])/2),
+	}
+
+	for i := 0; i+1 < len(match[3]); i += 2 {
+		weather.Phenomena = append(weather.Phenomena, match[3][i:i+2])
+	}
+
+	return weather
+}
+
+func parseSkyLayer(token string) *SkyLayer {
+	match := cloudPattern.FindStringSubmatch(token)
+
+	layer := &SkyLayer{
+		Cover: match[1],
+	}
+
+	if base, err := strconv.Atoi(match[2]); err == nil {
+		layer.BaseFtAGL = base * 100
+		layer.HasBase = true
+	}
+
+	if match[3] != "///" {
+		layer.CloudType = match[3]
+	}
+
+	return layer
+}
+
+func parseTemperature(token string) *Temperature {
+	match := temperaturePattern.FindStringSubmatch(token)
+
+	temp := &Temperature{
+		TemperatureC: signedValue(match[1] == "M", match[2], 1),
+	}
+
+	if match[4] != "" {
+		temp.HasDewpoint = true
+		temp.DewpointC = signedValue(match[3] == "M", match[4], 1)
+	}
+
+	return temp
+}
+
+func parseAltimeter(token string) float64 {
+	match := altimeterPattern.FindStringSubmatch(token)
+	value, _ := strconv.ParseFloat(match[2], 64)
+
+	if match[1] == "Q" {
+		return value * inHgPerHPa
+	}
+
+	return value / 100
+}
+
+func parseRemarks(tokens []string) *Remarks {
+	remarks := &Remarks{
+		Raw: strings.Join(tokens, " "),
+	}
+
+	for _, token := range tokens {
+		switch {
+		case token == "AO1" || token == "AO2":
+			remarks.StationType = token
+		case slpPattern.MatchString(token):
+			value, _ := strconv.ParseFloat(slpPattern.FindStringSubmatch(token)[1], 64)
+			if value >= 500 {
+				remarks.SeaLevelPressureHPa = 900 + value/10
+			} else {
+				remarks.SeaLevelPressureHPa = 1000 + value/10
+			}
+		case preciseTempPattern.MatchString(token):
+			match := preciseTempPattern.FindStringSubmatch(token)
+			remarks.HasPreciseTemperature = true
+			remarks.PreciseTemperatureC = signedValue(match[1] == "1", match[2], 10)
+			if match[4] != "" {
+				remarks.PreciseDewpointC = signedValue(match[3] == "1", match[4], 10)
+			}
+		}
+	}
+
+	return remarks
+}
+
+func signedValue(negative bool, digits string, divisor float64) float64 {
+	value, _ := strconv.ParseFloat(digits, 64)
+	value /= divisor
+
+	if negative {
+		return -value
+	}
+
+	return value
+}
@@ -0,0 +1,299 @@
+package metarclient
+
+import (
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+func TestParseMetarHeader(t *testing.T) {
+	m, err := ParseMetar("SPECI COR CYWG 101523Z AUTO 27015KT 10SM CLR M20/M24 A3012=")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.ReportType != ReportTypeSPECI || !m.Corrected || !m.Auto {
+		t.Error("unnexpected header flags", m.ReportType, m.Corrected, m.Auto)
+	}
+
+	if m.StationID != "CYWG" || m.Day != 10 || m.Hour != 15 || m.Minute != 23 {
+		t.Error("unnexpected station or time", m.StationID, m.Day, m.Hour, m.Minute)
+	}
+
+	if len(m.Unparsed) > 0 {
+		t.Error("unnexpected unparsed tokens", m.Unparsed)
+	}
+}
+
+func TestParseMetarErrors(t *testing.T) {
+	table := []string{
+		"",
+		"METAR",
+		"cyeg 100700Z",
+		"CYEG",
+		"CYEG 1007Z",
+	}
+
+	for _, raw := range table {
+		if _, err := ParseMetar(raw); err == nil {
+			t.Errorf("expected error parsing '%s'", raw)
+		}
+	}
+}
+
+func TestParseMetarWind(t *testing.T) {
+	table := []struct {
+		raw       string
+		direction int
+		variable  bool
+		speed     float64
+		gust      float64
+		from      int
+		to        int
+	}{
+		{"CYEG 100700Z 21008KT", 210, false, 8, 0, 0, 0},
+		{"CYEG 100700Z 00000KT", 0, false, 0, 0, 0, 0},
+		{"CYEG 100700Z VRB03KT", 0, true, 3, 0, 0, 0},
+		{"CYEG 100700Z 31022G35KT 280V340", 310, false, 22, 35, 280, 340},
+		{"EGLL 100700Z 24010MPS", 240, false, 10 / mpsPerKt, 0, 0, 0},
+		{"UUEE 100700Z 18036KMH", 180, false, 36 / kmhPerKt, 0, 0, 0},
+		{"KDEN 100700Z 090105G120KT", 90, false, 105, 120, 0, 0},
+	}
+
+	for _, row := range table {
+		m, err := ParseMetar(row.raw)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		w := m.Wind
+		if w == nil {
+			t.Errorf("%s => no wind", row.raw)
+			continue
+		}
+
+		if w.DirectionDeg != row.direction || w.Variable != row.variable || !common.Similar(w.SpeedKts, row.speed) || !common.Similar(w.GustKts, row.gust) {
+			t.Errorf("%s => %+v", row.raw, w)
+		}
+
+		if w.VariableFromDeg != row.from || w.VariableToDeg != row.to || w.HasVariableRange != (row.from != 0) {
+			t.Errorf("%s => variable range %+v", row.raw, w)
+		}
+	}
+}
+
+func TestParseMetarVisibility(t *testing.T) {
+	table := []struct {
+		raw         string
+		sm          float64
+		lessThan    bool
+		greaterThan bool
+	}{
+		{"CYEG 100700Z 21008KT 15SM", 15, false, false},
+		{"CYEG 100700Z 21008KT 1/2SM", 0.5, false, false},
+		{"CYEG 100700Z 21008KT 1 1/2SM", 1.5, false, false},
+		{"CYEG 100700Z 21008KT 2 3/4SM", 2.75, false, false},
+		{"KDEN 100700Z 21008KT M1/4SM", 0.25, true, false},
+		{"KDEN 100700Z 21008KT P6SM", 6, false, true},
+		{"EGLL 100700Z 21008KT 0800", 800 / metersPerStatuteMile, false, false},
+		{"EGLL 100700Z 21008KT 9999", 10000 / metersPerStatuteMile, false, true},
+		{"EGLL 100700Z 21008KT CAVOK", 10000 / metersPerStatuteMile, false, true},
+	}
+
+	for _, row := range table {
+		m, err := ParseMetar(row.raw)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		v := m.Visibility
+		if v == nil {
+			t.Errorf("%s => no visibility", row.raw)
+			continue
+		}
+
+		if !common.Similar(v.StatuteMiles, row.sm) || v.LessThan != row.lessThan || v.GreaterThan != row.greaterThan {
+			t.Errorf("%s => %+v", row.raw, v)
+		}
+	}
+}
+
+func TestParseMetarRunwayVisualRange(t *testing.T) {
+	m, err := ParseMetar("KORD 100700Z 21008KT 1/4SM R10L/1200V1800FT/U R28R/M0600FT R04/P6000FT FG VV002 02/02 A2990")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.RunwayVisualRanges) != 3 {
+		t.Fatal("unnexpected rvr count", len(m.RunwayVisualRanges))
+	}
+
+	first := m.RunwayVisualRanges[0]
+	if first.Runway != "10L" || first.Min != 1200 || first.Max != 1800 || first.Unit != "FT" || first.Trend != "U" {
+		t.Errorf("unnexpected first rvr %+v", first)
+	}
+
+	if second := m.RunwayVisualRanges[1]; !second.LessThan || second.Min != 600 || second.Max != 600 {
+		t.Errorf("unnexpected second rvr %+v", second)
+	}
+
+	if third := m.RunwayVisualRanges[2]; !third.GreaterThan || third.Min != 6000 {
+		t.Errorf("unnexpected third rvr %+v", third)
+	}
+}
+
+func TestParseMetarWeather(t *testing.T) {
+	m, err := ParseMetar("CYYC 100700Z 21008KT 3SM -SHRA +TSRAGR FZDZ BR VCSH VCTS SCT020CB OVC040 10/09 A2990")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		raw        string
+		intensity  string
+		descriptor string
+		phenomena  []string
+	}{
+		{"-SHRA", IntensityLight, "SH", []string{"RA"}},
+		{"+TSRAGR", IntensityHeavy, "TS", []string{"RA", "GR"}},
+		{"FZDZ", IntensityModerate, "FZ", []string{"DZ"}},
+		{"BR", IntensityModerate, "", []string{"BR"}},
+		{"VCSH", IntensityVicinity, "SH", []string{}},
+		{"VCTS", IntensityVicinity, "TS", []string{}},
+	}
+
+	if len(m.Weather) != len(expected) {
+		t.Fatal("unnexpected weather count", len(m.Weather))
+	}
+
+	for i, row := range expected {
+		w := m.Weather[i]
+		if w.Raw != row.raw || w.Intensity != row.intensity || w.Descriptor != row.descriptor || len(w.Phenomena) != len(row.phenomena) {
+			t.Errorf("%s => %+v", row.raw, w)
+			continue
+		}
+
+		for j, p := range row.phenomena {
+			if w.Phenomena[j] != p {
+				t.Errorf("%s => unnexpected phenomena %v", row.raw, w.Phenomena)
+			}
+		}
+	}
+
+	if !m.HasWeather("TS") || !m.HasWeather("GR") || m.HasWeather("SN") {
+		t.Error("unnexpected HasWeather result")
+	}
+}
+
+func TestParseMetarSkyLayers(t *testing.T) {
+	m, err := ParseMetar("CYVR 100700Z 21008KT 6SM FEW008 SCT025TCU BKN040CB OVC///")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SkyLayer{
+		{SkyCoverFew, 800, true, ""},
+		{SkyCoverScattered, 2500, true, "TCU"},
+		{SkyCoverBroken, 4000, true, "CB"},
+		{SkyCoverOvercast, 0, false, ""},
+	}
+
+	if len(m.SkyLayers) != len(expected) {
+		t.Fatal("unnexpected layer count", len(m.SkyLayers))
+	}
+
+	for i, layer := range expected {
+		if *m.SkyLayers[i] != layer {
+			t.Errorf("unnexpected layer %+v, expected %+v", *m.SkyLayers[i], layer)
+		}
+	}
+
+	m, err = ParseMetar("CYYC 100700Z 25003KT 20SM SKC M05/M12 A2983")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.SkyLayers) != 1 || m.SkyLayers[0].Cover != SkyCoverSkyClear {
+		t.Error("expected clear sky layer")
+	}
+}
+
+func TestParseMetarTemperatureAndPressure(t *testing.T) {
+	m, err := ParseMetar("CYEG 100700Z 21008KT 15SM FEW030 M07/M11 A2977 RMK SC1AC4 AO2 SLP143 T10721106")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Temperature == nil || m.Temperature.TemperatureC != -7 || m.Temperature.DewpointC != -11 || !m.Temperature.HasDewpoint {
+		t.Errorf("unnexpected temperature %+v", m.Temperature)
+	}
+
+	if !common.Similar(m.AltimeterInHg, 29.77) {
+		t.Error("unnexpected altimeter", m.AltimeterInHg)
+	}
+
+	r := m.Remarks
+	if r == nil {
+		t.Fatal("expected remarks")
+	}
+
+	if r.Raw != "SC1AC4 AO2 SLP143 T10721106" || r.StationType != "AO2" || !common.Similar(r.SeaLevelPressureHPa, 1014.3) {
+		t.Errorf("unnexpected remarks %+v", r)
+	}
+
+	if !r.HasPreciseTemperature || !common.Similar(r.PreciseTemperatureC, -7.2) || !common.Similar(r.PreciseDewpointC, -10.6) {
+		t.Errorf("unnexpected precise temperature %+v", r)
+	}
+
+	m, err = ParseMetar("EGLL 100700Z 21008KT 9999 SCT030 05/ Q1013 RMK SLP987")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Temperature.TemperatureC != 5 || m.Temperature.HasDewpoint {
+		t.Errorf("unnexpected temperature %+v", m.Temperature)
+	}
+
+	if !common.Similar(m.AltimeterInHg, 1013*inHgPerHPa) {
+		t.Error("unnexpected altimeter", m.AltimeterInHg)
+	}
+
+	if !common.Similar(m.Remarks.SeaLevelPressureHPa, 998.7) {
+		t.Error("unnexpected slp", m.Remarks.SeaLevelPressureHPa)
+	}
+}
+
+func TestParseMetarTrend(t *testing.T) {
+	table := []struct {
+		raw     string
+		trend   string
+		remarks bool
+	}{
+		{"EGLL 100720Z 24010KT 9999 FEW040 12/08 Q1012 TEMPO 3000 SHRA BKN008", "TEMPO 3000 SHRA BKN008", false},
+		{"EGLL 100720Z 24010KT 9999 FEW040 12/08 Q1012 NOSIG", "NOSIG", false},
+		{"LFPG 100730Z 20008KT 9999 FEW040 12/08 Q1012 BECMG FM0900 BKN012 RMK AO2", "BECMG FM0900 BKN012", true},
+		{"EDDF 100720Z 24010KT 9999 FEW040 12/08 Q1012 PROB30 TSRA", "PROB30 TSRA", false},
+	}
+
+	for _, row := range table {
+		m, err := ParseMetar(row.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if m.Trend != row.trend || (m.Remarks != nil) != row.remarks {
+			t.Errorf("unnexpected trend '%s' for '%s'", m.Trend, row.raw)
+		}
+
+		if len(m.SkyLayers) != 1 || m.SkyLayers[0].Cover != SkyCoverFew || len(m.Weather) > 0 || len(m.Unparsed) > 0 {
+			t.Errorf("expected the trend kept out of the observation for '%s'", row.raw)
+		}
+	}
+
+	m, _ := ParseMetar("EGLL 100720Z 24010KT 9999 FEW040 12/08 Q1012 TEMPO 3000 SHRA BKN008")
+	if _, hasCeiling := CeilingFtAGL(m.SkyLayers); hasCeiling {
+		t.Error("expected no ceiling from the trend")
+	}
+}
@@ -0,0 +1,343 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+var _ MetarClient = (*noaaTextClient)(nil)
+var _ StationSubsetReporter = (*noaaTextClient)(nil)
+
+const (
+	NOAATextEndPoint    = "https://tgftp.nws.noaa.gov/data/observations/metar/stations"
+	NOAATextTafEndPoint = "https://tgftp.nws.noaa.gov/data/forecasts/taf/stations"
+	noaaTextDateLayout  = "2006/01/02 15:04"
+	noaaTextTafDir      = "taf"
+
+	// NOAATextMaxConnectionFailures is how many station requests in a row can fail to connect before the
+	// rest of the stations are given up on.
+	NOAATextMaxConnectionFailures = 5
+)
+
+var ErrStationPositionsNotSupported = errors.New("metar strategy does not support station positions")
+
+// noaaTextClient reads the per-station observation files published by NOAA.
+// Each file has a date line followed by the raw METAR:
+//
+//	2021/01/10 07:00
+//	CYEG 100700Z 21008KT 15SM FEW030 BKN200 M07/M11 A2977 RMK SC1AC4 SLP143
+//
+// The source may be a base URL or a local directory of files. Stations are requested one per chunk so they share
+// the bounded workers of the chunked clients.
+// Forecasts use the same layout, read from NOAATextTafEndPoint or the "taf" sub directory of a local source.
+type noaaTextClient struct {
+	settings *Settings
+	source   string
+	http     *httpFetcher
+	stations *chunkFetcher
+}
+
+// noaaConnectionError is a station request that failed without a response.
+type noaaConnectionError struct {
+	err error
+}
+
+func (e *noaaConnectionError) Error() string {
+	return e.err.Error()
+}
+
+func newNOAATextClient(settings *Settings, source string) MetarClient {
+	sort.Strings(settings.StationIDs)
+
+	if settings.FlightCategoryRules == nil {
+		settings.FlightCategoryRules = FAAFlightCategoryRules()
+	}
+
+	stations := newChunkFetcher(settings)
+	stations.size = 1
+
+	return &noaaTextClient{
+		settings: settings,
+		source:   source,
+		http:     newHTTPFetcher(settings),
+		stations: stations,
+	}
+}
+
+func (c *noaaTextClient) GetReports(ctx context.Context) (reports map[string]*MetarReport, err error) {
+	return c.GetReportsFor(ctx, c.settings.StationIDs)
+}
+
+func (c *noaaTextClient) GetReportsFor(ctx context.Context, stationIDs []string) (reports map[string]*MetarReport, err error) {
+	reports = make(map[string]*MetarReport, len(stationIDs))
+	lock := sync.Mutex{}
+
+	err = c.fetchStations(ctx, stationIDs, func(ctx context.Context, stationID string) error {
+		report, err := c.getStationReport(ctx, stationID)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		reports[stationID] = report
+		lock.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stationID := range stationIDs {
+		if _, ok := reports[stationID]; !ok {
+			reports[stationID] = errorReport(stationID)
+		}
+	}
+
+	return reports, nil
+}
+
+func (c *noaaTextClient) GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error) {
+	return nil, ErrStationPositionsNotSupported
+}
+
+func (c *noaaTextClient) GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error) {
+	source, ok := c.tafSource()
+	if !ok {
+		return nil, ErrForecastsNotSupported
+	}
+
+	forecasts = make(map[string]*TafReport, len(c.settings.StationIDs))
+	lock := sync.Mutex{}
+
+	err = c.fetchStations(ctx, c.settings.StationIDs, func(ctx context.Context, stationID string) error {
+		forecast, err := c.getStationForecast(ctx, source, stationID)
+		if err != nil {
+			return err
+		}
+
+		lock.Lock()
+		forecasts[stationID] = forecast
+		lock.Unlock()
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stationID := range c.settings.StationIDs {
+		if _, ok := forecasts[stationID]; !ok {
+			forecasts[stationID] = errorTafReport(stationID)
+		}
+	}
+
+	return forecasts, nil
+}
+
+func (c *noaaTextClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
+	go func() {
+		reports, err := c.GetReports(ctx)
+		handler(reports, err)
+	}()
+}
+
+func (c *noaaTextClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
+	go func() {
+		positions, err := c.GetStationPositions(ctx)
+		handler(positions, err)
+	}()
+}
+
+func (c *noaaTextClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	go func() {
+		forecasts, err := c.GetForecasts(ctx)
+		handler(forecasts, err)
+	}()
+}
+
+// fetchStations reads each station with the bounded workers, which must be safe to call concurrently.
+// Failed stations are logged and left for the caller to mark as failed. After NOAATextMaxConnectionFailures
+// connection failures in a row the source is taken as down and the stations not yet read are skipped.
+// An error is only returned when no station could be read.
+func (c *noaaTextClient) fetchStations(ctx context.Context, stationIDs []string, read func(ctx context.Context, stationID string) error) error {
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lock := sync.Mutex{}
+	connectionFailures := 0
+	succeeded := 0
+
+	err := c.stations.fetch(fetchCtx, c.stations.split(stationIDs), func(ctx context.Context, chunk int, stationIDs []string) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err := read(ctx, stationIDs[0])
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if _, ok := err.(*noaaConnectionError); ok {
+			connectionFailures++
+			if connectionFailures == NOAATextMaxConnectionFailures {
+				logger.LogError("giving up on '%s' after %d connection failures in a row", c.source, connectionFailures)
+				cancel()
+			}
+		} else if err == nil {
+			connectionFailures = 0
+			succeeded++
+		}
+
+		return err
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if succeeded == 0 && len(stationIDs) > 0 {
+		if err == context.Canceled {
+			err = errors.New("failed to read any noaa station files")
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// tafSource gets where to read forecasts from. Only the default end point and local directories have them.
+func (c *noaaTextClient) tafSource() (string, bool) {
+	if c.source == NOAATextEndPoint {
+		return NOAATextTafEndPoint, true
+	}
+
+	if isLocalSource(c.source) {
+		return path.Join(strings.TrimPrefix(c.source, "file://"), noaaTextTafDir), true
+	}
+
+	return "", false
+}
+
+func (c *noaaTextClient) getStationForecast(ctx context.Context, source string, stationID string) (*TafReport, error) {
+	raw, err := c.readTextFile(ctx, source, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	issueTime, rawText, err := splitNOAAText(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := buildTafReport(stationID, rawText, issueTime, c.settings.FlightCategoryRules)
+	if forecast.Error {
+		return nil, errors.New("failed to decode forecast")
+	}
+
+	return forecast, nil
+}
+
+func (c *noaaTextClient) getStationReport(ctx context.Context, stationID string) (*MetarReport, error) {
+	raw, err := c.readTextFile(ctx, c.source, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := parseNOAAText(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.StationID != stationID {
+		return nil, fmt.Errorf("file contains report for '%s'", report.StationID)
+	}
+
+	resolveFlightRules(report, c.settings.FlightCategoryRules)
+
+	return report, nil
+}
+
+func (c *noaaTextClient) readTextFile(ctx context.Context, source string, stationID string) ([]byte, error) {
+	fileName := stationID + ".TXT"
+
+	if isLocalSource(source) {
+		return ioutil.ReadFile(path.Join(strings.TrimPrefix(source, "file://"), fileName))
+	}
+
+	response, err := c.http.get(ctx, strings.TrimSuffix(source, "/")+"/"+fileName)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		return nil, &noaaConnectionError{err: err}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("invalid response %d", response.StatusCode)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+func isLocalSource(source string) bool {
+	return !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://")
+}
+
+// splitNOAAText splits a file into the time on its date line and the report on the following lines.
+func splitNOAAText(raw []byte) (time.Time, string, error) {
+	lines := make([]string, 0, 2)
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) < 2 {
+		return time.Time{}, "", errors.New("expected a date line and a report")
+	}
+
+	t, err := time.Parse(noaaTextDateLayout, lines[0])
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("failed to parse date line '%s'", lines[0])
+	}
+
+	return t.UTC(), strings.Join(lines[1:], " "), nil
+}
+
+func parseNOAAText(raw []byte) (*MetarReport, error) {
+	observationTime, rawText, err := splitNOAAText(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := ParseMetar(rawText)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MetarReport{
+		StationID:       decoded.StationID,
+		ObservationTime: observationTime,
+		RawText:         rawText,
+		Decoded:         decoded,
+	}
+
+	resolveWind(report, nil)
+	resolveSkyLayers(report, decoded.SkyLayers)
+
+	return report, nil
+}
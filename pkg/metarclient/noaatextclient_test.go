@@ -0,0 +1,207 @@
+package metarclient
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+func TestParseNOAAText(t *testing.T) {
+	report, err := parseNOAAText([]byte("2021/01/10 07:00\nCYEG 100700Z 21008KT 15SM FEW030 BKN200 M07/M11 A2977 RMK SC1AC4 SLP143\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.StationID != "CYEG" || !report.ObservationTime.Equal(time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("unnexpected report %+v", report)
+	}
+
+	if report.WindSpeedKts != 8 || report.SkyCover != SkyCoverFew || report.CloudBaseFtAGL != 3000 {
+		t.Errorf("unnexpected decoded values %+v", report)
+	}
+
+	table := []string{
+		"",
+		"2021/01/10 07:00\n",
+		"yesterday\nCYEG 100700Z 21008KT 15SM",
+		"2021/01/10 07:00\nnot a metar",
+	}
+
+	for _, raw := range table {
+		if _, err := parseNOAAText([]byte(raw)); err == nil {
+			t.Errorf("expected error parsing '%s'", raw)
+		}
+	}
+}
+
+func TestNOAATextClientDirectory(t *testing.T) {
+	client, err := CreateMetarClient(&Settings{
+		StationIDs: []string{"CYYC", "CYEG", "CABC"},
+		Strategy:   common.NOAATextMetarStrategy,
+		EndPoint:   path.Join(common.GetResourcesRoot(), "dev", "noaa-text"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := client.GetReports(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 3 {
+		t.Error("unnexpected report count", len(reports))
+	}
+
+	if reports["CYEG"].FlightRules != common.FlightRuleVFR {
+		t.Error("unnexpected CYEG flight rules", reports["CYEG"].FlightRules)
+	}
+
+	if reports["CYYC"].FlightRules != common.FlightRuleLIFR {
+		t.Error("unnexpected CYYC flight rules", reports["CYYC"].FlightRules)
+	}
+
+	if !reports["CABC"].Error || reports["CABC"].FlightRules != common.FlightRuleError {
+		t.Error("expected CABC error report")
+	}
+
+	if _, err := client.GetStationPositions(context.Background()); err != ErrStationPositionsNotSupported {
+		t.Error("expected positions not supported")
+	}
+}
+
+func TestNOAATextClientForecasts(t *testing.T) {
+	client, err := CreateMetarClient(&Settings{
+		StationIDs: []string{"CYYC", "CYEG", "CABC"},
+		Strategy:   common.NOAATextMetarStrategy,
+		EndPoint:   path.Join(common.GetResourcesRoot(), "dev", "noaa-text"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forecasts, err := client.GetForecasts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cyeg := forecasts["CYEG"]
+	if cyeg.Error || !cyeg.IssueTime.Equal(time.Date(2021, 1, 10, 5, 40, 0, 0, time.UTC)) {
+		t.Errorf("unnexpected CYEG forecast %+v", cyeg)
+	}
+
+	if flightRules := cyeg.FlightRulesAt(time.Date(2021, 1, 11, 2, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleLIFR {
+		t.Error("unnexpected CYEG forecast flight rules", flightRules)
+	}
+
+	if forecasts["CYYC"].Error || !forecasts["CABC"].Error {
+		t.Error("unnexpected forecast errors")
+	}
+
+	client, _ = CreateMetarClient(&Settings{
+		StationIDs: []string{"CYEG"},
+		Strategy:   common.NOAATextMetarStrategy,
+		EndPoint:   "https://metars.example.com",
+	})
+	if _, err := client.GetForecasts(context.Background()); err != ErrForecastsNotSupported {
+		t.Error("expected forecasts not supported", err)
+	}
+}
+
+func TestNOAATextClientMismatchedStation(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(path.Join(dir, "CYWG.TXT"), []byte("2021/01/10 07:00\nCYEG 100700Z 21008KT 15SM FEW030\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := newNOAATextClient(&Settings{StationIDs: []string{"CYWG"}}, dir)
+
+	if _, err := client.GetReports(context.Background()); err == nil {
+		t.Error("expected error when all stations fail")
+	}
+}
+
+func TestNOAATextClientHTTP(t *testing.T) {
+	requested := make(map[string]bool)
+	lock := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requested[r.URL.Path] = true
+		lock.Unlock()
+
+		raw, err := ioutil.ReadFile(path.Join(common.GetResourcesRoot(), "dev", "noaa-text", path.Base(r.URL.Path)))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write(raw)
+	}))
+	defer server.Close()
+
+	client := newNOAATextClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC", "CABC"},
+	}, server.URL+"/data/observations/metar/stations/")
+
+	reports, err := client.GetReports(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"/data/observations/metar/stations/CYEG.TXT", "/data/observations/metar/stations/CYYC.TXT", "/data/observations/metar/stations/CABC.TXT"} {
+		if !requested[p] {
+			t.Error("expected request for", p)
+		}
+	}
+
+	if reports["CYEG"].Error || reports["CYYC"].Error || !reports["CABC"].Error {
+		t.Error("unnexpected error flags")
+	}
+
+	if reports["CYYC"].Decoded == nil || len(reports["CYYC"].Decoded.SkyLayers) != 1 {
+		t.Error("expected decoded CYYC report")
+	}
+}
+
+func TestNOAATextClientGivesUpOnConnectionFailures(t *testing.T) {
+	requestCount := 0
+	lock := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		requestCount++
+		lock.Unlock()
+
+		// Drop the connection without a response.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := newNOAATextClient(&Settings{
+		StationIDs:            []string{"CYEG", "CYYC", "CYBW", "CYOD", "CYPE", "CYQF", "CYQL", "CYQU", "CYXD", "CYZH"},
+		MaxConcurrentRequests: 1,
+	}, server.URL)
+
+	if _, err := client.GetReports(context.Background()); err == nil {
+		t.Error("expected error when all stations fail")
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	if requestCount != NOAATextMaxConnectionFailures {
+		t.Errorf("expected %d requests before giving up, got %d", NOAATextMaxConnectionFailures, requestCount)
+	}
+}
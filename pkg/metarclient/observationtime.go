@@ -0,0 +1,67 @@
+package metarclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+)
+
+// Layouts providers use for observation times, tried in order.
+var observationTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseObservationTime parses a provider's observation time as UTC when it has no zone.
+func ParseObservationTime(value string) (time.Time, error) {
+	for _, layout := range observationTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported observation time '%s'", value)
+}
+
+// DecodedObservationTime resolves the day, hour and minute of a decoded METAR to the most recent matching
+// time at or before the reference, allowing for a little clock skew.
+func DecodedObservationTime(decoded *DecodedMetar, reference time.Time) (time.Time, error) {
+	if decoded == nil || decoded.Day < 1 || decoded.Day > 31 {
+		return time.Time{}, fmt.Errorf("metar has no observation time")
+	}
+
+	return resolveDayTime(ForecastDayTime{Day: decoded.Day, Hour: decoded.Hour, Minute: decoded.Minute}, reference.Add(time.Hour))
+}
+
+// resolveObservationTime uses the provider's time when set, falling back on the time in the raw METAR.
+func resolveObservationTime(report *MetarReport, providerTime string, reference time.Time) {
+	if providerTime != "" {
+		t, err := ParseObservationTime(providerTime)
+		if err == nil {
+			report.ObservationTime = t
+			return
+		}
+
+		logger.LogWarn("station '%s': %s", report.StationID, err)
+	}
+
+	t, err := DecodedObservationTime(report.Decoded, reference)
+	if err != nil {
+		logger.LogWarn("station '%s' has no observation time: %s", report.StationID, err)
+		return
+	}
+
+	report.ObservationTime = t
+}
+
+// ObservationAge is how long ago the report was observed or 0 when the time is unknown.
+func (r *MetarReport) ObservationAge(now time.Time) time.Duration {
+	if r.ObservationTime.IsZero() {
+		return 0
+	}
+
+	return now.Sub(r.ObservationTime)
+}
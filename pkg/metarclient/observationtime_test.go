@@ -0,0 +1,79 @@
+package metarclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseObservationTime(t *testing.T) {
+	expected := time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{"2021-01-10T07:00:00Z", "2021-01-10 07:00:00", "2021-01-10T00:00:00-07:00", "2021-01-10 07:00"} {
+		parsed, err := ParseObservationTime(value)
+		if err != nil {
+			t.Error(value, err)
+			continue
+		}
+
+		if !parsed.Equal(expected) || parsed.Location() != time.UTC {
+			t.Error("unnexpected time", value, parsed)
+		}
+	}
+
+	if _, err := ParseObservationTime("yesterday"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDecodedObservationTime(t *testing.T) {
+	table := []struct {
+		day       int
+		hour      int
+		reference time.Time
+		expected  time.Time
+	}{
+		{10, 7, time.Date(2021, 1, 10, 7, 20, 0, 0, time.UTC), time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)},
+		{10, 7, time.Date(2021, 1, 10, 6, 30, 0, 0, time.UTC), time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)},
+		{31, 23, time.Date(2021, 2, 1, 0, 10, 0, 0, time.UTC), time.Date(2021, 1, 31, 23, 0, 0, 0, time.UTC)},
+		{31, 23, time.Date(2021, 3, 2, 0, 10, 0, 0, time.UTC), time.Date(2021, 1, 31, 23, 0, 0, 0, time.UTC)},
+		{31, 23, time.Date(2021, 1, 1, 0, 10, 0, 0, time.UTC), time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC)},
+	}
+
+	for _, row := range table {
+		resolved, err := DecodedObservationTime(&DecodedMetar{Day: row.day, Hour: row.hour}, row.reference)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if !resolved.Equal(row.expected) {
+			t.Error("unnexpected time", resolved, row.expected)
+		}
+	}
+
+	if _, err := DecodedObservationTime(&DecodedMetar{}, time.Now()); err == nil {
+		t.Error("expected error for missing day")
+	}
+}
+
+func TestResolveObservationTimeFallsBackOnRawText(t *testing.T) {
+	decoded, err := ParseMetar("CYEG 100700Z 21008KT 15SM FEW030 M07/M11 A2977")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := &MetarReport{StationID: "CYEG", Decoded: decoded}
+	resolveObservationTime(report, "", time.Date(2021, 1, 10, 8, 0, 0, 0, time.UTC))
+
+	if !report.ObservationTime.Equal(time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC)) {
+		t.Error("unnexpected observation time", report.ObservationTime)
+	}
+
+	if age := report.ObservationAge(time.Date(2021, 1, 10, 8, 35, 0, 0, time.UTC)); age != 95*time.Minute {
+		t.Error("unnexpected age", age)
+	}
+
+	if age := (&MetarReport{}).ObservationAge(time.Now()); age != 0 {
+		t.Error("expected 0 age for unknown observation time", age)
+	}
+}
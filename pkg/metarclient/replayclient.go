@@ -0,0 +1,278 @@
+package metarclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+)
+
+var _ MetarClient = (*replayClient)(nil)
+
+const (
+	ReplayFileTimeLayout      = "2006-01-02T15-04-05.999999999Z"
+	ReplayPositionsFileName   = "station_positions.json"
+	defaultReplayFrameSpacing = time.Minute
+)
+
+type replayFrame struct {
+	filePath string
+	offset   time.Duration
+}
+
+// replayClient serves provider responses recorded to a directory.
+// Files are named by their recorded time (see ReplayFileTimeLayout), optionally followed by "_" and the provider,
+// and the extension picks the parser: ".xml" for AviationWeather and ".json" for AviationWeatherAPI.
+// With a speed of 0 each call to GetReports steps to the next file, otherwise the files are played back
+// against the clock with the speed as a multiplier. Playback loops at the end.
+type replayClient struct {
+	settings     *Settings
+	dir          string
+	speed        float64
+	frames       []*replayFrame
+	loopDuration time.Duration
+	index        int
+	startTime    time.Time
+	clock        common.Clock
+	lock         sync.Mutex
+	xmlClient    *aviationWeatherClient
+	apiClient    *aviationWeatherAPIClient
+}
+
+func newReplayClient(settings *Settings, dir string) MetarClient {
+	sort.Strings(settings.StationIDs)
+
+	if settings.FlightCategoryRules == nil {
+		settings.FlightCategoryRules = FAAFlightCategoryRules()
+	}
+
+	return &replayClient{
+		settings:  settings,
+		dir:       dir,
+		speed:     settings.ReplaySpeed,
+		clock:     settings.clock(),
+		xmlClient: newAviationWeatherClient(settings, "").(*aviationWeatherClient),
+		apiClient: newAviationWeatherAPIClient(settings, "").(*aviationWeatherAPIClient),
+	}
+}
+
+func (c *replayClient) GetReports(ctx context.Context) (reports map[string]*MetarReport, err error) {
+	frame, err := c.nextFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	logger.LogDebug("replaying '%s'", frame.filePath)
+
+	responseBytes, err := ioutil.ReadFile(frame.filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(path.Ext(frame.filePath)) {
+	case ".xml":
+		awm, err := c.xmlClient.parseResponseBytes(responseBytes, c.settings.StationIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.xmlClient.buildReports(awm), nil
+	case ".json":
+		metars, err := c.apiClient.parseMetarBytes(responseBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		return c.apiClient.buildReports(c.settings.StationIDs, metars), nil
+	default:
+		return nil, fmt.Errorf("unsupported replay file '%s'", frame.filePath)
+	}
+}
+
+func (c *replayClient) GetStationPositions(ctx context.Context) (positions map[string]*MetarPosition, err error) {
+	bytes, err := ioutil.ReadFile(path.Join(c.dir, ReplayPositionsFileName))
+	if os.IsNotExist(err) {
+		return nil, ErrStationPositionsNotSupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	coordinates := map[string]*struct {
+		Latitude  float64
+		Longitude float64
+		Altitude  float64
+	}{}
+
+	if err := json5.Unmarshal(bytes, &coordinates); err != nil {
+		return nil, err
+	}
+
+	positions = make(map[string]*MetarPosition, len(c.settings.StationIDs))
+	for _, stationID := range c.settings.StationIDs {
+		coord, ok := coordinates[stationID]
+		if !ok {
+			positions[stationID] = &MetarPosition{Error: true, StationID: stationID}
+			continue
+		}
+
+		positions[stationID] = &MetarPosition{
+			StationID: stationID,
+			Latitude:  coord.Latitude,
+			Longitude: coord.Longitude,
+			Elevation: coord.Altitude,
+		}
+	}
+
+	return positions, nil
+}
+
+// GetForecasts isn't supported as forecasts aren't recorded.
+func (c *replayClient) GetForecasts(ctx context.Context) (forecasts map[string]*TafReport, err error) {
+	return nil, ErrForecastsNotSupported
+}
+
+func (c *replayClient) Fetch(ctx context.Context, handler MetarResponseHandler) {
+	go func() {
+		reports, err := c.GetReports(ctx)
+		handler(reports, err)
+	}()
+}
+
+func (c *replayClient) FetchStationPositions(ctx context.Context, handler MetarPositionResponseHandler) {
+	go func() {
+		positions, err := c.GetStationPositions(ctx)
+		handler(positions, err)
+	}()
+}
+
+func (c *replayClient) FetchForecasts(ctx context.Context, handler TafResponseHandler) {
+	go func() {
+		forecasts, err := c.GetForecasts(ctx)
+		handler(forecasts, err)
+	}()
+}
+
+func (c *replayClient) nextFrame() (*replayFrame, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.frames == nil {
+		if err := c.loadFrames(); err != nil {
+			return nil, err
+		}
+
+		c.startTime = c.clock.Now()
+		c.index = 0
+
+		return c.frames[0], nil
+	}
+
+	if c.speed <= 0 {
+		c.index = (c.index + 1) % len(c.frames)
+
+		return c.frames[c.index], nil
+	}
+
+	elapsed := time.Duration(float64(c.clock.Now().Sub(c.startTime)) * c.speed)
+	elapsed %= c.loopDuration
+
+	c.index = 0
+	for i, f := range c.frames {
+		if f.offset <= elapsed {
+			c.index = i
+		}
+	}
+
+	return c.frames[c.index], nil
+}
+
+func (c *replayClient) loadFrames() error {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+
+	type timedFile struct {
+		filePath string
+		time     time.Time
+	}
+
+	timedFiles := make([]timedFile, 0, len(files))
+	for _, f := range files {
+		ext := strings.ToLower(path.Ext(f.Name()))
+		if f.IsDir() || (ext != ".xml" && ext != ".json") || f.Name() == ReplayPositionsFileName {
+			continue
+		}
+
+		timestamp := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
+		if i := strings.Index(timestamp, "_"); i >= 0 {
+			timestamp = timestamp[:i]
+		}
+
+		fileTime, err := time.Parse(ReplayFileTimeLayout, timestamp)
+		if err != nil {
+			logger.LogDebug("replay file '%s' has no timestamp, using modified time", f.Name())
+			fileTime = f.ModTime()
+		}
+
+		timedFiles = append(timedFiles, timedFile{path.Join(c.dir, f.Name()), fileTime})
+	}
+
+	if len(timedFiles) == 0 {
+		return errors.New("no replay files found in " + c.dir)
+	}
+
+	sort.SliceStable(timedFiles, func(i, j int) bool {
+		return timedFiles[i].time.Before(timedFiles[j].time)
+	})
+
+	c.frames = make([]*replayFrame, len(timedFiles))
+	for i, f := range timedFiles {
+		c.frames[i] = &replayFrame{
+			filePath: f.filePath,
+			offset:   f.time.Sub(timedFiles[0].time),
+		}
+	}
+
+	// Hold the last frame for as long as the gap before it before looping.
+	lastGap := defaultReplayFrameSpacing
+	if len(c.frames) > 1 {
+		lastGap = c.frames[len(c.frames)-1].offset - c.frames[len(c.frames)-2].offset
+	}
+	c.loopDuration = c.frames[len(c.frames)-1].offset + lastGap
+	if c.loopDuration <= 0 {
+		c.loopDuration = defaultReplayFrameSpacing
+	}
+
+	logger.LogInfo("loaded %d replay files from '%s'", len(c.frames), c.dir)
+
+	return nil
+}
+
+// recordResponse saves a timestamped copy of a provider's response for the replay strategy.
+func recordResponse(recordDir string, provider string, extension string, responseBytes []byte) {
+	if recordDir == "" {
+		return
+	}
+
+	if err := os.MkdirAll(recordDir, common.CacheDirPermission); err != nil {
+		logger.LogWarn("failed to create record dir: %s", err)
+		return
+	}
+
+	fileName := time.Now().UTC().Format(ReplayFileTimeLayout) + "_" + provider + "." + extension
+	if err := ioutil.WriteFile(path.Join(recordDir, fileName), responseBytes, common.CacheFilePermission); err != nil {
+		logger.LogWarn("failed to record response: %s", err)
+	}
+}
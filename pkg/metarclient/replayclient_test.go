@@ -0,0 +1,175 @@
+package metarclient
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+func TestReplayClientStepping(t *testing.T) {
+	dir := createReplayTestDir(t)
+
+	client, err := CreateMetarClient(&Settings{
+		StationIDs: []string{"CYEG", "CYYC"},
+		Strategy:   common.ReplayMetarStrategy,
+		EndPoint:   dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		common.FlightRuleVFR,
+		common.FlightRuleIFR,
+		common.FlightRuleVFR,
+		common.FlightRuleIFR,
+	}
+
+	for i, flightRule := range expected {
+		reports, err := client.GetReports(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reports["CYYC"].FlightRules != flightRule {
+			t.Errorf("step %d => %s, expected %s", i, reports["CYYC"].FlightRules, flightRule)
+		}
+	}
+}
+
+func TestReplayClientRealTime(t *testing.T) {
+	dir := createReplayTestDir(t)
+
+	now := time.Date(2021, 2, 1, 12, 0, 0, 0, time.UTC)
+	client := newReplayClient(&Settings{
+		StationIDs:  []string{"CYEG", "CYYC"},
+		ReplaySpeed: 60,
+	}, dir).(*replayClient)
+	client.clock = common.ClockFunc(func() time.Time { return now })
+
+	table := []struct {
+		elapsed  time.Duration
+		expected string
+	}{
+		{0, common.FlightRuleVFR},
+		{5 * time.Second, common.FlightRuleVFR},
+		{10 * time.Second, common.FlightRuleIFR},
+		{19 * time.Second, common.FlightRuleIFR},
+		{20 * time.Second, common.FlightRuleVFR},
+		{31 * time.Second, common.FlightRuleIFR},
+	}
+
+	start := now
+	for _, row := range table {
+		now = start.Add(row.elapsed)
+
+		reports, err := client.GetReports(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reports["CYYC"].FlightRules != row.expected {
+			t.Errorf("%s => %s, expected %s", row.elapsed, reports["CYYC"].FlightRules, row.expected)
+		}
+	}
+}
+
+func TestReplayClientPositions(t *testing.T) {
+	dir := createReplayTestDir(t)
+	client := newReplayClient(&Settings{StationIDs: []string{"CYEG", "CABC"}}, dir)
+
+	if _, err := client.GetStationPositions(context.Background()); err != ErrStationPositionsNotSupported {
+		t.Error("expected positions not supported without positions file")
+	}
+
+	err := ioutil.WriteFile(path.Join(dir, ReplayPositionsFileName), []byte(`{"CYEG": {"Latitude": 53.3, "Longitude": -113.58, "Altitude": 723}}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	positions, err := client.GetStationPositions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if positions["CYEG"].Latitude != 53.3 || positions["CYEG"].Elevation != 723 {
+		t.Errorf("unnexpected CYEG position %+v", positions["CYEG"])
+	}
+
+	if !positions["CABC"].Error {
+		t.Error("expected CABC position error")
+	}
+}
+
+func TestReplayClientEmptyDir(t *testing.T) {
+	client := newReplayClient(&Settings{StationIDs: []string{"CYEG"}}, t.TempDir())
+
+	if _, err := client.GetReports(context.Background()); err == nil {
+		t.Error("expected error with no replay files")
+	}
+}
+
+func TestRecordResponse(t *testing.T) {
+	dir := path.Join(t.TempDir(), "recordings")
+
+	recordResponse("", common.AviationWeatherMetarStrategy, "xml", []byte("<response/>"))
+	recordResponse(dir, common.AviationWeatherMetarStrategy, "xml", []byte("<response/>"))
+	recordResponse(dir, common.AviationWeatherMetarStrategy, "xml", []byte("<response/>"))
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 2 || path.Ext(files[0].Name()) != ".xml" {
+		t.Fatal("expected two uniquely named xml files")
+	}
+
+	suffix := "_" + common.AviationWeatherMetarStrategy + ".xml"
+	if !strings.HasSuffix(files[0].Name(), suffix) {
+		t.Error("expected provider in file name", files[0].Name())
+	}
+
+	if _, err := time.Parse(ReplayFileTimeLayout, strings.TrimSuffix(files[0].Name(), suffix)); err != nil {
+		t.Error("expected timestamped file name", files[0].Name())
+	}
+
+	client := newReplayClient(&Settings{StationIDs: []string{"CYEG"}}, dir).(*replayClient)
+	if err := client.loadFrames(); err != nil {
+		t.Fatal(err)
+	}
+
+	if client.frames[1].offset <= 0 {
+		t.Error("expected frames offset by their recorded time", client.frames[1].offset)
+	}
+}
+
+// createReplayTestDir records a VFR xml response followed 10 minutes later by a json response with CYYC at IFR.
+func createReplayTestDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	xmlRaw, err := ioutil.ReadFile(path.Join(common.GetResourcesRoot(), "dev", "aviation-weather-example.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonRaw, err := ioutil.ReadFile(path.Join(common.GetResourcesRoot(), "dev", "aviation-weather-api-example.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path.Join(dir, "2021-01-10T07-00-00Z.xml"), xmlRaw, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path.Join(dir, "2021-01-10T07-10-00Z.json"), jsonRaw, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
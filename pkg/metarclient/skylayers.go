@@ -0,0 +1,45 @@
+package metarclient
+
+// skyCoverObscured is how providers report a sky obscured by vertical visibility, with the height given separately.
+const skyCoverObscured = "OVX"
+
+// providerSkyLayers converts obscured layers to vertical visibility at vertVisFt, adding the layer when a provider
+// only reports the height.
+func providerSkyLayers(layers []*SkyLayer, vertVisFt *int) []*SkyLayer {
+	hasVertVis := false
+	for _, l := range layers {
+		if l.Cover != skyCoverObscured && l.Cover != SkyCoverVertVis {
+			continue
+		}
+
+		l.Cover = SkyCoverVertVis
+		l.BaseFtAGL, l.HasBase = 0, false
+		if vertVisFt != nil {
+			l.BaseFtAGL, l.HasBase = *vertVisFt, true
+		}
+		hasVertVis = true
+	}
+
+	if vertVisFt != nil && !hasVertVis {
+		layers = append(layers, &SkyLayer{Cover: SkyCoverVertVis, BaseFtAGL: *vertVisFt, HasBase: true})
+	}
+
+	return layers
+}
+
+// resolveSkyLayers sets the report's layers and ceiling, falling back on the layers in the raw METAR.
+// SkyCover, CloudBaseFtAGL and CloudType are set from the lowest layer.
+func resolveSkyLayers(report *MetarReport, layers []*SkyLayer) {
+	if len(layers) == 0 && report.Decoded != nil {
+		layers = report.Decoded.SkyLayers
+	}
+
+	report.SkyLayers = layers
+	report.CeilingFtAGL, report.HasCeiling = CeilingFtAGL(layers)
+
+	if len(layers) > 0 {
+		report.SkyCover = layers[0].Cover
+		report.CloudBaseFtAGL = layers[0].BaseFtAGL
+		report.CloudType = layers[0].CloudType
+	}
+}
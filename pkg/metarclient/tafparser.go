@@ -0,0 +1,306 @@
+package metarclient
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ForecastChangeBase        = "BASE"
+	ForecastChangeFrom        = "FM"
+	ForecastChangeBecoming    = "BECMG"
+	ForecastChangeTemporary   = "TEMPO"
+	ForecastChangeProbability = "PROB"
+)
+
+var (
+	tafValidPattern = regexp.MustCompile(`^(\d{2})(\d{2})/(\d{2})(\d{2})$`)
+	tafFromPattern  = regexp.MustCompile(`^FM(\d{2})(\d{2})(\d{2})$`)
+	tafProbPattern  = regexp.MustCompile(`^PROB(\d{2})$`)
+)
+
+// DecodedTAF is a terminal aerodrome forecast decoded from its raw text.
+type DecodedTAF struct {
+	RawText     string
+	StationID   string
+	Amended     bool
+	Corrected   bool
+	Issued      *ForecastDayTime
+	ValidFrom   ForecastDayTime
+	ValidTo     ForecastDayTime
+	Periods     []*ForecastPeriod
+	RemarksText string
+}
+
+// ForecastDayTime is a day of the month and UTC time. The hour may be 24 for the end of a day.
+type ForecastDayTime struct {
+	Day    int
+	Hour   int
+	Minute int
+}
+
+// ForecastPeriod is the base forecast or a change group.
+// From, To, and FlightRules are set once the TAF is resolved.
+type ForecastPeriod struct {
+	Change               string
+	Probability          int
+	Start                ForecastDayTime
+	End                  *ForecastDayTime
+	Wind                 *Wind
+	Visibility           *Visibility
+	Weather              []*WeatherPhenomenon
+	NoSignificantWeather bool
+	SkyLayers            []*SkyLayer
+	Unparsed             []string
+	From                 time.Time
+	To                   time.Time
+	FlightRules          string
+}
+
+// Temporary checks if the period only describes temporary or possible conditions.
+func (p *ForecastPeriod) Temporary() bool {
+	return p.Change == ForecastChangeTemporary || p.Change == ForecastChangeProbability
+}
+
+// ParseTAF decodes the raw text of a TAF.
+func ParseTAF(raw string) (*DecodedTAF, error) {
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "="))
+	tokens := strings.Fields(text)
+
+	idx := 0
+	if idx < len(tokens) && tokens[idx] == "TAF" {
+		idx++
+	}
+
+	taf := &DecodedTAF{RawText: raw}
+	for ; idx < len(tokens); idx++ {
+		if tokens[idx] == "AMD" {
+			taf.Amended = true
+		} else if tokens[idx] == "COR" {
+			taf.Corrected = true
+		} else {
+			break
+		}
+	}
+
+	if idx >= len(tokens) || !stationPattern.MatchString(tokens[idx]) {
+		return nil, fmt.Errorf("failed to find station in taf '%s'", raw)
+	}
+	taf.StationID = tokens[idx]
+	idx++
+
+	if idx < len(tokens) && timePattern.MatchString(tokens[idx]) {
+		match := timePattern.FindStringSubmatch(tokens[idx])
+		taf.Issued = &ForecastDayTime{Day: atoi(match[1]), Hour: atoi(match[2]), Minute: atoi(match[3])}
+		idx++
+	}
+
+	if idx >= len(tokens) || !tafValidPattern.MatchString(tokens[idx]) {
+		return nil, fmt.Errorf("failed to find valid period in taf '%s'", raw)
+	}
+	taf.ValidFrom, taf.ValidTo = parseTAFRange(tokens[idx])
+	idx++
+
+	period := &ForecastPeriod{Change: ForecastChangeBase, Start: taf.ValidFrom}
+	taf.Periods = append(taf.Periods, period)
+
+	for ; idx < len(tokens); idx++ {
+		token := tokens[idx]
+
+		switch {
+		case token == "RMK":
+			taf.RemarksText = strings.Join(tokens[idx+1:], " ")
+			return taf, nil
+		case tafFromPattern.MatchString(token):
+			match := tafFromPattern.FindStringSubmatch(token)
+			period = &ForecastPeriod{
+				Change: ForecastChangeFrom,
+				Start:  ForecastDayTime{Day: atoi(match[1]), Hour: atoi(match[2]), Minute: atoi(match[3])},
+			}
+			taf.Periods = append(taf.Periods, period)
+		case token == ForecastChangeBecoming || token == ForecastChangeTemporary || tafProbPattern.MatchString(token):
+			period = &ForecastPeriod{Change: token}
+			if match := tafProbPattern.FindStringSubmatch(token); match != nil {
+				period.Change = ForecastChangeProbability
+				period.Probability = atoi(match[1])
+				// "PROB30 TEMPO" is treated the same as "PROB30".
+				if idx+1 < len(tokens) && tokens[idx+1] == ForecastChangeTemporary {
+					idx++
+				}
+			}
+
+			if idx+1 >= len(tokens) || !tafValidPattern.MatchString(tokens[idx+1]) {
+				return nil, fmt.Errorf("expected a time range after '%s' in taf '%s'", token, raw)
+			}
+			idx++
+			start, end := parseTAFRange(tokens[idx])
+			period.Start = start
+			period.End = &end
+			taf.Periods = append(taf.Periods, period)
+		default:
+			idx += parseForecastGroup(period, tokens[idx:])
+		}
+	}
+
+	return taf, nil
+}
+
+// parseForecastGroup parses the group at the start of the tokens into the period and returns how many extra tokens it used.
+func parseForecastGroup(p *ForecastPeriod, tokens []string) int {
+	token := tokens[0]
+
+	switch {
+	case token == "CAVOK":
+		p.Visibility = &Visibility{StatuteMiles: 10000 / metersPerStatuteMile, Meters: 10000, GreaterThan: true, CAVOK: true}
+		p.SkyLayers = append(p.SkyLayers, &SkyLayer{Cover: SkyCoverNSC})
+	case token == "NSW":
+		p.NoSignificantWeather = true
+	case p.Wind == nil && windPattern.MatchString(token):
+		p.Wind = parseWind(token)
+	case p.Visibility == nil && visWholePattern.MatchString(token) && len(tokens) > 1 && visSMPattern.MatchString(tokens[1]):
+		p.Visibility = parseVisibilitySM(token, tokens[1])
+		return 1
+	case p.Visibility == nil && visSMPattern.MatchString(token):
+		p.Visibility = parseVisibilitySM("", token)
+	case p.Visibility == nil && visMetricPattern.MatchString(token):
+		p.Visibility = parseVisibilityMetric(token)
+	case clearSkyPattern.MatchString(token):
+		p.SkyLayers = append(p.SkyLayers, &SkyLayer{Cover: token})
+	case cloudPattern.MatchString(token):
+		p.SkyLayers = append(p.SkyLayers, parseSkyLayer(token))
+	case isWeatherToken(token):
+		p.Weather = append(p.Weather, parseWeather(token))
+	default:
+		p.Unparsed = append(p.Unparsed, token)
+	}
+
+	return 0
+}
+
+func parseTAFRange(token string) (start ForecastDayTime, end ForecastDayTime) {
+	match := tafValidPattern.FindStringSubmatch(token)
+
+	return ForecastDayTime{Day: atoi(match[1]), Hour: atoi(match[2])}, ForecastDayTime{Day: atoi(match[3]), Hour: atoi(match[4])}
+}
+
+// Resolve sets the times of the periods against the reference and categorizes them with the rules.
+// Becoming groups change the prevailing conditions from their start and temporary groups are categorized
+// with the conditions they change.
+func (t *DecodedTAF) Resolve(reference time.Time, rules *FlightCategoryRules) (validFrom time.Time, validTo time.Time, err error) {
+	issued := reference
+	if t.Issued != nil {
+		if issued, err = resolveDayTime(*t.Issued, reference.Add(time.Hour)); err != nil {
+			return validFrom, validTo, err
+		}
+	}
+
+	// Periods start within about a day of being issued, so resolve them as the latest match before then.
+	latest := issued.Add(36 * time.Hour)
+	if validFrom, err = resolveDayTime(t.ValidFrom, latest); err != nil {
+		return validFrom, validTo, err
+	}
+	if validTo, err = resolveDayTime(t.ValidTo, latest); err != nil {
+		return validFrom, validTo, err
+	}
+
+	prevailing := &ForecastPeriod{}
+	for i, p := range t.Periods {
+		if p.From, err = resolveDayTime(p.Start, latest); err != nil {
+			return validFrom, validTo, err
+		}
+
+		if p.End != nil {
+			if p.To, err = resolveDayTime(*p.End, latest); err != nil {
+				return validFrom, validTo, err
+			}
+		} else {
+			p.To = validTo
+			for _, next := range t.Periods[i+1:] {
+				if next.Change == ForecastChangeFrom {
+					next.From, _ = resolveDayTime(next.Start, latest)
+					p.To = next.From
+					break
+				}
+			}
+		}
+
+		switch p.Change {
+		case ForecastChangeBase, ForecastChangeFrom:
+			prevailing = p
+			p.FlightRules = categorizeForecast(p, rules)
+		case ForecastChangeBecoming:
+			prevailing = mergeForecastPeriods(prevailing, p)
+			p.FlightRules = categorizeForecast(prevailing, rules)
+		default:
+			p.FlightRules = categorizeForecast(mergeForecastPeriods(prevailing, p), rules)
+		}
+	}
+
+	return validFrom, validTo, nil
+}
+
+// mergeForecastPeriods applies the groups of the change to the base.
+func mergeForecastPeriods(base *ForecastPeriod, change *ForecastPeriod) *ForecastPeriod {
+	merged := *base
+	if change.Wind != nil {
+		merged.Wind = change.Wind
+	}
+	if change.Visibility != nil {
+		merged.Visibility = change.Visibility
+	}
+	if len(change.Weather) > 0 || change.NoSignificantWeather {
+		merged.Weather = change.Weather
+	}
+	if len(change.SkyLayers) > 0 {
+		merged.SkyLayers = change.SkyLayers
+	}
+
+	return &merged
+}
+
+func categorizeForecast(p *ForecastPeriod, rules *FlightCategoryRules) string {
+	ceiling, hasCeiling := CeilingFtAGL(p.SkyLayers)
+
+	if p.Visibility == nil {
+		return rules.Categorize(ceiling, hasCeiling, 0, false)
+	}
+
+	return rules.Categorize(ceiling, hasCeiling, p.Visibility.StatuteMiles, true)
+}
+
+// resolveDayTime gets the latest time matching the day and time that isn't after the latest time.
+func resolveDayTime(dayTime ForecastDayTime, latest time.Time) (time.Time, error) {
+	if dayTime.Day < 1 || dayTime.Day > 31 {
+		return time.Time{}, errors.New("invalid day of the month")
+	}
+
+	latest = latest.UTC()
+
+	// Step back through the months to skip days the month doesn't have.
+	for monthOffset := 0; monthOffset < 3; monthOffset++ {
+		year, month, _ := latest.AddDate(0, -monthOffset, 1-latest.Day()).Date()
+		day := time.Date(year, month, dayTime.Day, 0, 0, 0, 0, time.UTC)
+		if day.Month() != month {
+			continue
+		}
+
+		t := day.Add(time.Duration(dayTime.Hour)*time.Hour + time.Duration(dayTime.Minute)*time.Minute)
+		if t.After(latest) {
+			continue
+		}
+
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("failed to resolve day %d", dayTime.Day)
+}
+
+func atoi(value string) int {
+	i, _ := strconv.Atoi(value)
+
+	return i
+}
@@ -0,0 +1,186 @@
+package metarclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+)
+
+const (
+	testTafCYEG = "TAF CYEG 100540Z 1006/1106 21010KT P6SM FEW030 BKN200 TEMPO 1006/1010 3SM -SN BKN015 FM101500 24012G22KT P6SM SCT040 BKN120 BECMG 1020/1022 VRB03KT PROB30 1100/1106 1SM BR OVC004 RMK NXT FCST BY 101200Z"
+	testTafCYYC = "TAF AMD CYYC 100700Z 1007/1112 18008KT 1 1/2SM -SN OVC008 FM101800 27010KT P6SM BKN030 FM110300 VRB03KT 6SM -SN OVC010 RMK NXT FCST BY 101200Z"
+)
+
+func TestParseTAF(t *testing.T) {
+	taf, err := ParseTAF(testTafCYEG)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if taf.StationID != "CYEG" || taf.Amended || taf.Issued == nil || *taf.Issued != (ForecastDayTime{Day: 10, Hour: 5, Minute: 40}) {
+		t.Errorf("unnexpected header %+v", taf)
+	}
+
+	if taf.ValidFrom != (ForecastDayTime{Day: 10, Hour: 6}) || taf.ValidTo != (ForecastDayTime{Day: 11, Hour: 6}) {
+		t.Error("unnexpected valid period", taf.ValidFrom, taf.ValidTo)
+	}
+
+	if taf.RemarksText != "NXT FCST BY 101200Z" {
+		t.Error("unnexpected remarks", taf.RemarksText)
+	}
+
+	expectedChanges := []string{ForecastChangeBase, ForecastChangeTemporary, ForecastChangeFrom, ForecastChangeBecoming, ForecastChangeProbability}
+	if len(taf.Periods) != len(expectedChanges) {
+		t.Fatal("unnexpected period count", len(taf.Periods))
+	}
+
+	for i, change := range expectedChanges {
+		if taf.Periods[i].Change != change {
+			t.Error("unnexpected change", i, taf.Periods[i].Change)
+		}
+	}
+
+	base := taf.Periods[0]
+	if base.Wind.DirectionDeg != 210 || base.Wind.SpeedKts != 10 || !base.Visibility.GreaterThan || len(base.SkyLayers) != 2 {
+		t.Errorf("unnexpected base %+v", base)
+	}
+
+	tempo := taf.Periods[1]
+	if tempo.Start != (ForecastDayTime{Day: 10, Hour: 6}) || *tempo.End != (ForecastDayTime{Day: 10, Hour: 10}) {
+		t.Error("unnexpected tempo range", tempo.Start, tempo.End)
+	}
+
+	if tempo.Visibility.StatuteMiles != 3 || len(tempo.Weather) != 1 || tempo.Weather[0].Raw != "-SN" {
+		t.Errorf("unnexpected tempo %+v", tempo)
+	}
+
+	from := taf.Periods[2]
+	if from.Start != (ForecastDayTime{Day: 10, Hour: 15}) || from.End != nil || from.Wind.GustKts != 22 {
+		t.Errorf("unnexpected from %+v", from)
+	}
+
+	if becoming := taf.Periods[3]; !becoming.Wind.Variable || becoming.Visibility != nil {
+		t.Errorf("unnexpected becoming %+v", becoming)
+	}
+
+	if prob := taf.Periods[4]; prob.Probability != 30 || prob.SkyLayers[0].BaseFtAGL != 400 {
+		t.Errorf("unnexpected prob %+v", prob)
+	}
+}
+
+func TestParseTAFSplitVisibilityAndAmendment(t *testing.T) {
+	taf, err := ParseTAF(testTafCYYC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !taf.Amended || taf.StationID != "CYYC" {
+		t.Errorf("unnexpected header %+v", taf)
+	}
+
+	if vis := taf.Periods[0].Visibility.StatuteMiles; vis != 1.5 {
+		t.Error("unnexpected visibility", vis)
+	}
+
+	if len(taf.Periods[0].Unparsed) != 0 {
+		t.Error("unnexpected unparsed tokens", taf.Periods[0].Unparsed)
+	}
+}
+
+func TestParseTAFProbTempo(t *testing.T) {
+	taf, err := ParseTAF("TAF KDEN 101720Z 1018/1124 28012KT P6SM SCT080 PROB30 TEMPO 1020/1024 VRB25G35KT 3SM TSRA BKN050CB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prob := taf.Periods[1]
+	if prob.Change != ForecastChangeProbability || prob.Probability != 30 || *prob.End != (ForecastDayTime{Day: 10, Hour: 24}) {
+		t.Errorf("unnexpected prob tempo %+v", prob)
+	}
+
+	if prob.Weather[0].Descriptor != "TS" || prob.SkyLayers[0].CloudType != "CB" {
+		t.Errorf("unnexpected prob groups %+v", prob)
+	}
+}
+
+func TestParseTAFErrors(t *testing.T) {
+	for _, raw := range []string{"", "TAF", "TAF CYEG 100540Z", "TAF CYEG 100540Z 1006/1106 21010KT TEMPO 3SM"} {
+		if _, err := ParseTAF(raw); err == nil {
+			t.Error("expected error", raw)
+		}
+	}
+}
+
+func TestTafReportFlightRulesAt(t *testing.T) {
+	reference := time.Date(2021, 1, 10, 6, 0, 0, 0, time.UTC)
+	report := buildTafReport("CYEG", testTafCYEG, reference, FAAFlightCategoryRules())
+	if report.Error {
+		t.Fatal("unnexpected error report")
+	}
+
+	if !report.IssueTime.Equal(time.Date(2021, 1, 10, 5, 40, 0, 0, time.UTC)) {
+		t.Error("unnexpected issue time", report.IssueTime)
+	}
+
+	if !report.ValidFrom.Equal(time.Date(2021, 1, 10, 6, 0, 0, 0, time.UTC)) || !report.ValidTo.Equal(time.Date(2021, 1, 11, 6, 0, 0, 0, time.UTC)) {
+		t.Error("unnexpected valid period", report.ValidFrom, report.ValidTo)
+	}
+
+	table := []struct {
+		at       time.Time
+		expected string
+	}{
+		{time.Date(2021, 1, 10, 5, 0, 0, 0, time.UTC), ""},
+		{time.Date(2021, 1, 10, 7, 0, 0, 0, time.UTC), common.FlightRuleMVFR},
+		{time.Date(2021, 1, 10, 12, 0, 0, 0, time.UTC), common.FlightRuleVFR},
+		{time.Date(2021, 1, 10, 21, 0, 0, 0, time.UTC), common.FlightRuleVFR},
+		{time.Date(2021, 1, 11, 2, 0, 0, 0, time.UTC), common.FlightRuleLIFR},
+		{time.Date(2021, 1, 11, 6, 0, 0, 0, time.UTC), ""},
+	}
+
+	for _, row := range table {
+		if flightRules := report.FlightRulesAt(row.at); flightRules != row.expected {
+			t.Error("unnexpected flight rules", row.at, flightRules, row.expected)
+		}
+	}
+
+	report = buildTafReport("CYYC", testTafCYYC, reference, FAAFlightCategoryRules())
+	if flightRules := report.FlightRulesAt(time.Date(2021, 1, 10, 8, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleIFR {
+		t.Error("unnexpected base flight rules", flightRules)
+	}
+
+	if flightRules := report.FlightRulesAt(time.Date(2021, 1, 11, 4, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleMVFR {
+		t.Error("unnexpected from flight rules", flightRules)
+	}
+}
+
+func TestTafReportResolvesAcrossMonths(t *testing.T) {
+	reference := time.Date(2021, 2, 1, 0, 10, 0, 0, time.UTC)
+	report := buildTafReport("CYEG", "TAF CYEG 312340Z 0100/0124 21010KT P6SM FEW030 FM011200 24012KT 2SM BR OVC008", reference, FAAFlightCategoryRules())
+	if report.Error {
+		t.Fatal("unnexpected error report")
+	}
+
+	if !report.IssueTime.Equal(time.Date(2021, 1, 31, 23, 40, 0, 0, time.UTC)) {
+		t.Error("unnexpected issue time", report.IssueTime)
+	}
+
+	if !report.ValidTo.Equal(time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Error("unnexpected valid to", report.ValidTo)
+	}
+
+	if flightRules := report.FlightRulesAt(time.Date(2021, 2, 1, 13, 0, 0, 0, time.UTC)); flightRules != common.FlightRuleIFR {
+		t.Error("unnexpected flight rules", flightRules)
+	}
+}
+
+func TestFlightRulesWorse(t *testing.T) {
+	if !FlightRulesWorse(common.FlightRuleLIFR, common.FlightRuleIFR) || FlightRulesWorse(common.FlightRuleVFR, common.FlightRuleMVFR) {
+		t.Error("unnexpected ordering")
+	}
+
+	if !FlightRulesWorse(common.FlightRuleVFR, "") || FlightRulesWorse("", common.FlightRuleVFR) {
+		t.Error("unnexpected unknown ordering")
+	}
+}
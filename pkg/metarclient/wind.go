@@ -0,0 +1,70 @@
+package metarclient
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// IsGusting checks if the wind is gusting above its sustained speed.
+func (w *Wind) IsGusting() bool {
+	return w.GustKts > w.SpeedKts
+}
+
+// providerWind builds the wind from a provider's fields. The direction may be in degrees or "VRB".
+// It's nil when the provider left out the speed so the raw METAR's wind is used as is.
+func providerWind(speedKts *float64, gustKts *float64, direction string) *Wind {
+	if speedKts == nil {
+		return nil
+	}
+
+	wind := &Wind{SpeedKts: *speedKts}
+
+	if gustKts != nil {
+		wind.GustKts = *gustKts
+	}
+
+	if direction == "VRB" {
+		wind.Variable = true
+	} else {
+		wind.DirectionDeg, _ = strconv.Atoi(direction)
+	}
+
+	return wind
+}
+
+// providerWindDirection formats a direction decoded from JSON as either a number or "VRB".
+func providerWindDirection(direction interface{}) string {
+	switch d := direction.(type) {
+	case float64:
+		return strconv.Itoa(int(d))
+	case string:
+		return d
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(d)
+	}
+}
+
+// resolveWind sets the report's wind from the raw METAR, using the provider's speed and gust when the provider
+// has them. The variable range is only reported in the raw text.
+func resolveWind(report *MetarReport, provider *Wind) {
+	var wind Wind
+	switch {
+	case report.Decoded != nil && report.Decoded.Wind != nil:
+		wind = *report.Decoded.Wind
+		if provider != nil {
+			wind.SpeedKts = provider.SpeedKts
+			if provider.GustKts > 0 {
+				wind.GustKts = provider.GustKts
+			}
+		}
+	case provider != nil:
+		wind = *provider
+	default:
+		return
+	}
+
+	report.Wind = &wind
+	report.WindSpeedKts = wind.SpeedKts
+}
@@ -0,0 +1,42 @@
+package metarclient
+
+import "testing"
+
+func TestResolveWind(t *testing.T) {
+	report := &MetarReport{Decoded: decodeRawText("CYEG", "CYEG 100700Z 21015G25KT 180V240 15SM FEW030 M07/M11 A2977")}
+	speed, gust := 16.0, 27.0
+	resolveWind(report, providerWind(&speed, &gust, "210"))
+
+	w := report.Wind
+	if w == nil || w.SpeedKts != 16 || w.GustKts != 27 || w.DirectionDeg != 210 || !w.IsGusting() {
+		t.Errorf("unnexpected wind %+v", w)
+	}
+
+	if !w.HasVariableRange || w.VariableFromDeg != 180 || w.VariableToDeg != 240 || report.WindSpeedKts != 16 {
+		t.Errorf("expected the variable range from the raw text %+v", w)
+	}
+
+	// The decoded speed is kept when the provider leaves it out.
+	report = &MetarReport{Decoded: decodeRawText("CYEG", "CYEG 100700Z 21015G25KT 15SM FEW030 M07/M11 A2977")}
+	resolveWind(report, providerWind(nil, nil, "210"))
+	if w := report.Wind; w == nil || w.SpeedKts != 15 || w.GustKts != 25 || report.WindSpeedKts != 15 {
+		t.Errorf("expected the decoded wind %+v", w)
+	}
+
+	report = &MetarReport{}
+	speed = 3
+	resolveWind(report, providerWind(&speed, nil, providerWindDirection("VRB")))
+	if w := report.Wind; w == nil || !w.Variable || w.IsGusting() || report.WindSpeedKts != 3 {
+		t.Errorf("unnexpected provider wind %+v", w)
+	}
+
+	report = &MetarReport{}
+	resolveWind(report, nil)
+	if report.Wind != nil {
+		t.Error("expected no wind")
+	}
+
+	if d := providerWindDirection(float64(250)); d != "250" {
+		t.Error("unnexpected direction", d)
+	}
+}
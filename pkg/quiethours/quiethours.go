@@ -0,0 +1,71 @@
+package quiethours
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+// Config sets the quiet hours from Start to End as times of day, which may wrap past midnight.
+// The map is woken early by any station at IFR or below with WakeOnIFR, at LIFR with WakeOnLIFR,
+// or reporting a thunderstorm with WakeOnThunderstorm.
+type Config struct {
+	Start              time.Duration
+	End                time.Duration
+	WakeOnIFR          bool
+	WakeOnLIFR         bool
+	WakeOnThunderstorm bool
+	Clock              common.Clock
+}
+
+// QuietHours tells when the map should be quiet and what wakes it.
+type QuietHours struct {
+	config *Config
+	clock  common.Clock
+}
+
+func CreateQuietHours(config *Config) *QuietHours {
+	clock := config.Clock
+	if clock == nil {
+		clock = common.SystemClock{}
+	}
+
+	return &QuietHours{
+		config: config,
+		clock:  clock,
+	}
+}
+
+// InWindow checks if it's quiet hours now.
+func (q *QuietHours) InWindow() bool {
+	return q.InWindowAt(q.clock.Now())
+}
+
+// InWindowAt checks if the time of day is in quiet hours, in the time's location.
+func (q *QuietHours) InWindowAt(t time.Time) bool {
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	start, end := q.config.Start, q.config.End
+
+	if start <= end {
+		return timeOfDay >= start && timeOfDay < end
+	}
+
+	return timeOfDay >= start || timeOfDay < end
+}
+
+// WakeReason gets why the stations should wake the map, or an empty string when nothing should.
+func (q *QuietHours) WakeReason(stations map[string]*stationrepo.Station) string {
+	for _, s := range stations {
+		switch {
+		case q.config.WakeOnLIFR && s.FlightRules == common.FlightRuleLIFR,
+			q.config.WakeOnIFR && (s.FlightRules == common.FlightRuleIFR || s.FlightRules == common.FlightRuleLIFR):
+			return fmt.Sprintf("'%s' is %s", s.ID, s.FlightRules)
+		case q.config.WakeOnThunderstorm && s.HasWeather("TS"):
+			return fmt.Sprintf("'%s' has a thunderstorm", s.ID)
+		}
+	}
+
+	return ""
+}
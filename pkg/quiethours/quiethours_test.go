@@ -0,0 +1,73 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
+)
+
+func TestInWindow(t *testing.T) {
+	rows := []struct {
+		start    time.Duration
+		end      time.Duration
+		hour     int
+		minute   int
+		expected bool
+	}{
+		{23 * time.Hour, 6*time.Hour + 30*time.Minute, 22, 59, false},
+		{23 * time.Hour, 6*time.Hour + 30*time.Minute, 23, 0, true},
+		{23 * time.Hour, 6*time.Hour + 30*time.Minute, 2, 0, true},
+		{23 * time.Hour, 6*time.Hour + 30*time.Minute, 6, 30, false},
+		{1 * time.Hour, 5 * time.Hour, 0, 30, false},
+		{1 * time.Hour, 5 * time.Hour, 3, 0, true},
+		{1 * time.Hour, 5 * time.Hour, 5, 0, false},
+	}
+
+	for _, r := range rows {
+		now := time.Date(2021, 3, 14, r.hour, r.minute, 0, 0, time.UTC)
+		quiet := CreateQuietHours(&Config{
+			Start: r.start,
+			End:   r.end,
+			Clock: common.ClockFunc(func() time.Time { return now }),
+		})
+
+		if quiet.InWindow() != r.expected {
+			t.Errorf("expected %t from %s to %s at %02d:%02d", r.expected, r.start, r.end, r.hour, r.minute)
+		}
+	}
+}
+
+func TestWakeReason(t *testing.T) {
+	thunderstorm := []*metarclient.WeatherPhenomenon{{Raw: "TSRA", Descriptor: "TS", Phenomena: []string{"RA"}}}
+	vicinity := []*metarclient.WeatherPhenomenon{{Raw: "VCTS", Intensity: metarclient.IntensityVicinity, Descriptor: "TS"}}
+
+	rows := []struct {
+		config   Config
+		station  stationrepo.Station
+		expected bool
+	}{
+		{Config{}, stationrepo.Station{FlightRules: common.FlightRuleLIFR, Weather: thunderstorm}, false},
+		{Config{WakeOnIFR: true}, stationrepo.Station{FlightRules: common.FlightRuleIFR}, true},
+		{Config{WakeOnIFR: true}, stationrepo.Station{FlightRules: common.FlightRuleLIFR}, true},
+		{Config{WakeOnIFR: true}, stationrepo.Station{FlightRules: common.FlightRuleSVFR}, false},
+		{Config{WakeOnLIFR: true}, stationrepo.Station{FlightRules: common.FlightRuleIFR}, false},
+		{Config{WakeOnLIFR: true}, stationrepo.Station{FlightRules: common.FlightRuleLIFR}, true},
+		{Config{WakeOnThunderstorm: true}, stationrepo.Station{FlightRules: common.FlightRuleVFR, Weather: thunderstorm}, true},
+		{Config{WakeOnThunderstorm: true}, stationrepo.Station{FlightRules: common.FlightRuleVFR, Weather: vicinity}, false},
+	}
+
+	for i, r := range rows {
+		station := r.station
+		station.ID = "CYEG"
+		config := r.config
+		quiet := CreateQuietHours(&config)
+
+		reason := quiet.WakeReason(map[string]*stationrepo.Station{"CYEG": &station})
+		if (reason != "") != r.expected {
+			t.Errorf("row %d: expected wake %t, got '%s'", i, r.expected, reason)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package stationdb
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+)
+
+const (
+	FileName = "stations.csv"
+)
+
+var header = []string{"icao", "name", "latitude", "longitude", "elevation_m", "country", "region"}
+
+// Station is a station's entry in the database. Region is the state or province.
+type Station struct {
+	ICAO      string
+	Name      string
+	Latitude  float64
+	Longitude float64
+	Elevation float64
+	Country   string
+	Region    string
+}
+
+// Coordinate gets the station's position.
+func (s *Station) Coordinate() *geo.Coordinate {
+	return &geo.Coordinate{
+		Latitude:  s.Latitude,
+		Longitude: s.Longitude,
+		Altitude:  s.Elevation,
+	}
+}
+
+// StationDB is an offline lookup of station positions and names by ICAO ID.
+type StationDB struct {
+	stations map[string]*Station
+}
+
+// DefaultPath is the database bundled in the resources directory.
+func DefaultPath() string {
+	return path.Join(common.GetResourcesRoot(), "stationdb", FileName)
+}
+
+func CreateStationDB(stations []*Station) *StationDB {
+	db := &StationDB{stations: make(map[string]*Station, len(stations))}
+	db.Merge(stations)
+
+	return db
+}
+
+// Load reads the database from a CSV file.
+func Load(filePath string) (*StationDB, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Read(file)
+}
+
+// Read parses the database's CSV, starting with its header row.
+func Read(r io.Reader) (*StationDB, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(header)
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) == 0 || strings.Join(records[0], ",") != strings.Join(header, ",") {
+		return nil, fmt.Errorf("station db must start with the header '%s'", strings.Join(header, ","))
+	}
+
+	stations := make([]*Station, 0, len(records)-1)
+	for i, record := range records[1:] {
+		station, err := parseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("station db line %d: %s", i+2, err)
+		}
+		stations = append(stations, station)
+	}
+
+	return CreateStationDB(stations), nil
+}
+
+// Lookup finds a station by its ICAO ID.
+func (db *StationDB) Lookup(icao string) (*Station, bool) {
+	station, ok := db.stations[strings.ToUpper(icao)]
+
+	return station, ok
+}
+
+// Coordinates gets the positions of the stations found in the database and the IDs that weren't.
+func (db *StationDB) Coordinates(icaos []string) (coordinates map[string]*geo.Coordinate, missing []string) {
+	coordinates = make(map[string]*geo.Coordinate, len(icaos))
+	for _, id := range icaos {
+		station, ok := db.Lookup(id)
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		coordinates[id] = station.Coordinate()
+	}
+
+	return coordinates, missing
+}
+
+// Len is the number of stations in the database.
+func (db *StationDB) Len() int {
+	return len(db.stations)
+}
+
+// Stations gets every station sorted by ICAO ID.
+func (db *StationDB) Stations() []*Station {
+	stations := make([]*Station, 0, len(db.stations))
+	for _, s := range db.stations {
+		stations = append(stations, s)
+	}
+
+	sort.Slice(stations, func(i, j int) bool {
+		return stations[i].ICAO < stations[j].ICAO
+	})
+
+	return stations
+}
+
+// Merge adds the stations, replacing existing entries with the same ICAO ID.
+func (db *StationDB) Merge(stations []*Station) {
+	for _, s := range stations {
+		s.ICAO = strings.ToUpper(s.ICAO)
+		db.stations[s.ICAO] = s
+	}
+}
+
+// MergeDiscovered adds stations found by discovery and returns how many were added or updated.
+func (db *StationDB) MergeDiscovered(discovered []*metarclient.DiscoveredStation) int {
+	stations := make([]*Station, len(discovered))
+	for i, d := range discovered {
+		stations[i] = &Station{
+			ICAO:      d.StationID,
+			Name:      d.Name,
+			Latitude:  d.Latitude,
+			Longitude: d.Longitude,
+			Elevation: d.Elevation,
+			Country:   d.Country,
+			Region:    d.Region,
+		}
+	}
+	db.Merge(stations)
+
+	return len(stations)
+}
+
+// Write writes the database as CSV sorted by ICAO ID.
+func (db *StationDB) Write(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range db.Stations() {
+		err := writer.Write([]string{
+			s.ICAO,
+			s.Name,
+			strconv.FormatFloat(s.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(s.Longitude, 'f', -1, 64),
+			strconv.FormatFloat(s.Elevation, 'f', -1, 64),
+			s.Country,
+			s.Region,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// Save writes the database to the file.
+func (db *StationDB) Save(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, common.CacheFilePermission)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func parseRecord(record []string) (*Station, error) {
+	icao := strings.TrimSpace(record[0])
+	if icao == "" {
+		return nil, errors.New("missing icao")
+	}
+
+	values := make([]float64, 3)
+	for i, field := range record[2:5] {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s '%s' for '%s'", header[i+2], field, icao)
+		}
+		values[i] = value
+	}
+
+	if values[0] < -90 || values[0] > 90 || values[1] < -180 || values[1] > 180 {
+		return nil, fmt.Errorf("position out of range for '%s'", icao)
+	}
+
+	return &Station{
+		ICAO:      icao,
+		Name:      strings.TrimSpace(record[1]),
+		Latitude:  values[0],
+		Longitude: values[1],
+		Elevation: values[2],
+		Country:   strings.TrimSpace(record[5]),
+		Region:    strings.TrimSpace(record[6]),
+	}, nil
+}
@@ -0,0 +1,82 @@
+package stationdb
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+)
+
+func TestLoadBundledStationDB(t *testing.T) {
+	db, err := Load(DefaultPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	station, ok := db.Lookup("cyeg")
+	if !ok {
+		t.Fatal("expected to find CYEG")
+	}
+
+	if station.Name != "Edmonton Intl" || station.Country != "CA" || station.Region != "AB" || station.Latitude != 53.3 {
+		t.Errorf("unnexpected station %+v", station)
+	}
+
+	coordinates, missing := db.Coordinates([]string{"CYEG", "CYYC", "KXXX"})
+	if len(coordinates) != 2 || coordinates["CYYC"].Altitude != 1085 {
+		t.Error("unnexpected coordinates", coordinates)
+	}
+
+	if len(missing) != 1 || missing[0] != "KXXX" {
+		t.Error("unnexpected missing", missing)
+	}
+}
+
+func TestStationDBRoundTrip(t *testing.T) {
+	db := CreateStationDB([]*Station{
+		{ICAO: "CYYC", Name: "Calgary Intl", Latitude: 51.12, Longitude: -114.02, Elevation: 1085, Country: "CA", Region: "AB"},
+	})
+
+	db.MergeDiscovered([]*metarclient.DiscoveredStation{
+		{StationID: "CYEG", Name: "Edmonton Intl", Latitude: 53.3097, Longitude: -113.5792, Elevation: 723, Country: "CA", Region: "AB"},
+		{StationID: "CYYC", Name: "Calgary, Intl", Latitude: 51.1139, Longitude: -114.0203, Elevation: 1084, Country: "CA", Region: "AB"},
+	})
+
+	filePath := path.Join(t.TempDir(), FileName)
+	if err := db.Save(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stations := loaded.Stations()
+	if len(stations) != 2 || stations[0].ICAO != "CYEG" || stations[1].Name != "Calgary, Intl" || stations[1].Latitude != 51.1139 {
+		t.Error("unnexpected stations", stations)
+	}
+}
+
+func TestReadInvalidStationDB(t *testing.T) {
+	invalid := []string{
+		"",
+		"id,name\nCYEG,Edmonton\n",
+		"icao,name,latitude,longitude,elevation_m,country,region\nCYEG,Edmonton,north,-113.58,723,CA,AB\n",
+		"icao,name,latitude,longitude,elevation_m,country,region\nCYEG,Edmonton,95,-113.58,723,CA,AB\n",
+		"icao,name,latitude,longitude,elevation_m,country,region\n,Edmonton,53.3,-113.58,723,CA,AB\n",
+	}
+
+	for _, csv := range invalid {
+		if _, err := Read(strings.NewReader(csv)); err == nil {
+			t.Errorf("expected error for %q", csv)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := CreateStationDB(nil).Write(&buf); err != nil || buf.String() != "icao,name,latitude,longitude,elevation_m,country,region\n" {
+		t.Error("unnexpected empty db", buf.String(), err)
+	}
+}
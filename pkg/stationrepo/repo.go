@@ -1,14 +1,17 @@
 package stationrepo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ataboo/go-metar-blink/pkg/animation"
 	"github.com/ataboo/go-metar-blink/pkg/common"
 	"github.com/ataboo/go-metar-blink/pkg/geo"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationdb"
 	"github.com/yosuke-furukawa/json5/encoding/json5"
 )
 
@@ -20,30 +23,80 @@ type StationRepo struct {
 	client      metarclient.MetarClient
 	coordinates map[string]*geo.Coordinate
 	config      *Config
+	store       *ReportStore
+	clock       common.Clock
+	cacheDir    string
 }
 
 type Station struct {
-	ID           string
-	Ordinal      int
-	FlightRules  string
-	WindSpeedKts float64
-	Coordinate   *geo.Coordinate
-	Color        animation.Color
+	ID                  string
+	Name                string
+	Ordinal             int
+	FlightRules         string
+	WindSpeedKts        float64
+	Wind                *metarclient.Wind
+	ObservationTime     time.Time
+	Stale               bool
+	Speci               bool
+	Weather             []*metarclient.WeatherPhenomenon
+	ForecastFlightRules string
+	Coordinate          *geo.Coordinate
+	Color               animation.Color
 }
 
+// Config sets the stations to load and how long a station's last good report is used.
+// Reports older than StaleAfter are shown as stale and reports older than ExpireAfter are dropped. Both default to the
+// app's defaults when 0.
+// Clock is used to age the reports and defaults to the system clock. Coordinates are known station positions,
+// like those from a station list, used before the cache or the client when they cover every station.
+// StationDB is used after the cache and before the client, and names the stations missing from Names.
+// CacheDir is where reports and positions are cached and defaults to the app settings' cache dir.
 type Config struct {
-	StationIDs []string
+	StationIDs  []string
+	StaleAfter  time.Duration
+	ExpireAfter time.Duration
+	Clock       common.Clock
+	Coordinates map[string]*geo.Coordinate
+	Names       map[string]string
+	StationDB   *stationdb.StationDB
+	CacheDir    string
 }
 
 func CreateStationRepo(client metarclient.MetarClient, config *Config) *StationRepo {
+	if config.StaleAfter == 0 {
+		config.StaleAfter = time.Duration(common.DefaultStaleAfterMins) * time.Minute
+	}
+
+	if config.ExpireAfter == 0 {
+		config.ExpireAfter = time.Duration(common.DefaultExpireAfterMins) * time.Minute
+	}
+
+	cacheDir := config.CacheDir
+	if cacheDir == "" {
+		cacheDir = common.GetAppSettings().CacheDir
+	}
+
+	store := CreateReportStore(cacheDir)
+	if err := store.LoadFromCache(); err == nil {
+		logger.LogInfo("successfully loaded cached reports")
+	}
+
+	clock := config.Clock
+	if clock == nil {
+		clock = common.SystemClock{}
+	}
+
 	return &StationRepo{
-		client: client,
-		config: config,
+		client:   client,
+		config:   config,
+		store:    store,
+		clock:    clock,
+		cacheDir: cacheDir,
 	}
 }
 
-func (r *StationRepo) LoadStations() (stations map[string]*Station, err error) {
-	err = r.loadCoordinatesIfEmpty()
+func (r *StationRepo) LoadStations(ctx context.Context) (stations map[string]*Station, err error) {
+	err = r.loadCoordinatesIfEmpty(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +110,7 @@ func (r *StationRepo) LoadStations() (stations map[string]*Station, err error) {
 		}
 		stations[id] = &Station{
 			ID:           id,
+			Name:         r.stationName(id),
 			Ordinal:      idx,
 			FlightRules:  common.FlightRuleError,
 			WindSpeedKts: 0,
@@ -72,43 +126,198 @@ func (r *StationRepo) LoadStations() (stations map[string]*Station, err error) {
 	return stations, nil
 }
 
-func (r *StationRepo) UpdateReports(stations map[string]*Station) error {
+// UpdateReports fetches fresh reports and updates the stations.
+// Stations without a fresh report fall back to their last good report until it expires.
+// The stations are updated even when the fetch fails, aging the last good reports.
+func (r *StationRepo) UpdateReports(ctx context.Context, stations map[string]*Station) error {
 	logger.LogDebug("repo fetching fresh reports")
-	reports, err := r.client.GetReports()
+	reports, err := r.client.GetReports(ctx)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	if err != nil {
-		return err
+		logger.LogError("failed to fetch reports: %s", err)
 	}
+	r.logProviderHealth()
 
+	now := r.clock.Now()
 	for _, s := range stations {
-		r, ok := reports[s.ID]
+		if report, ok := reports[s.ID]; ok {
+			r.store.Put(report, now)
+		}
+
+		stored, ok := r.store.Get(s.ID)
 		if !ok {
-			r = &metarclient.MetarReport{
-				Error:           true,
-				StationID:       s.ID,
-				ObservationTime: "",
-				FlightRules:     common.FlightRuleError,
-				WindSpeedKts:    0,
-			}
+			r.setStationError(s)
+			continue
+		}
+
+		age := stored.Age(now)
+		if age > r.config.ExpireAfter {
+			logger.LogWarn("last good report for '%s' expired %.0f min ago", s.ID, (age - r.config.ExpireAfter).Minutes())
+			r.setStationError(s)
+			continue
 		}
 
-		s.FlightRules = r.FlightRules
-		s.WindSpeedKts = r.WindSpeedKts
+		s.FlightRules = stored.Report.FlightRules
+		s.WindSpeedKts = stored.Report.WindSpeedKts
+		s.Wind = stored.Report.Wind
+		s.ObservationTime = stored.Report.ObservationTime
+		s.Stale = age > r.config.StaleAfter
+		s.Speci = false
+		s.Weather = nil
+		if decoded := stored.Report.Decoded; decoded != nil {
+			s.Speci = decoded.ReportType == metarclient.ReportTypeSPECI
+			s.Weather = decoded.Weather
+		}
+		if s.Stale {
+			logger.LogInfo("%s obs is %.0f min old", s.ID, age.Minutes())
+		}
+	}
+
+	if err := r.store.SaveToCache(); err != nil {
+		logger.LogWarn("failed to save reports to cache")
+	}
+
+	return err
+}
+
+// UpdateForecasts fetches forecasts and sets the stations' forecast flight rules at the offset from now.
+// Stations without a forecast for that time are set to the error flight rule.
+func (r *StationRepo) UpdateForecasts(ctx context.Context, stations map[string]*Station, offset time.Duration) error {
+	logger.LogDebug("repo fetching fresh forecasts")
+	forecasts, err := r.client.GetForecasts(ctx)
+	if err != nil {
+		for _, s := range stations {
+			s.ForecastFlightRules = common.FlightRuleError
+		}
+
+		return err
+	}
+
+	at := r.clock.Now().Add(offset)
+	for _, s := range stations {
+		s.ForecastFlightRules = common.FlightRuleError
+
+		forecast, ok := forecasts[s.ID]
+		if !ok || forecast.Error {
+			continue
+		}
+
+		if flightRules := forecast.FlightRulesAt(at); flightRules != "" {
+			s.ForecastFlightRules = flightRules
+		} else {
+			logger.LogInfo("forecast for '%s' doesn't cover %s", s.ID, at.Format(time.RFC3339))
+		}
 	}
 
 	return nil
 }
 
-func (r *StationRepo) loadCoordinatesIfEmpty() error {
+func (r *StationRepo) stationName(id string) string {
+	if name := r.config.Names[id]; name != "" {
+		return name
+	}
+
+	if r.config.StationDB != nil {
+		if s, ok := r.config.StationDB.Lookup(id); ok {
+			return s.Name
+		}
+	}
+
+	return ""
+}
+
+// ObservationAge is how long ago the station's report was observed according to the repo's clock.
+func (r *StationRepo) ObservationAge(s *Station) time.Duration {
+	return s.ObservationAge(r.clock.Now())
+}
+
+// ObservationAge is how long ago the station's report was observed or 0 when the time is unknown.
+func (s *Station) ObservationAge(now time.Time) time.Duration {
+	if s.ObservationTime.IsZero() {
+		return 0
+	}
+
+	return now.Sub(s.ObservationTime)
+}
+
+// HasWeather checks if the station is reporting the weather code (ex: "TS"), ignoring weather in the vicinity.
+func (s *Station) HasWeather(code string) bool {
+	for _, w := range s.Weather {
+		if w.Intensity != metarclient.IntensityVicinity && w.Has(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasFreezingPrecipitation checks if the station is reporting freezing rain or drizzle, ignoring weather in the vicinity.
+func (s *Station) HasFreezingPrecipitation() bool {
+	for _, w := range s.Weather {
+		if w.Intensity != metarclient.IntensityVicinity && w.IsFreezingPrecipitation() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *StationRepo) setStationError(s *Station) {
+	s.FlightRules = common.FlightRuleError
+	s.WindSpeedKts = 0
+	s.Wind = nil
+	s.ObservationTime = time.Time{}
+	s.Stale = false
+	s.Speci = false
+	s.Weather = nil
+}
+
+// logProviderHealth logs the health of each provider after a fetch, when the client tracks it.
+func (r *StationRepo) logProviderHealth() {
+	for _, h := range r.ProviderHealth() {
+		if h.ConsecutiveFailures == 0 {
+			logger.LogDebug("provider %s healthy, last response in %s", h.Name, h.LastLatency)
+			continue
+		}
+
+		logger.LogWarn("provider %s failed %d times in a row, last error: %s", h.Name, h.ConsecutiveFailures, h.LastError)
+	}
+}
+
+// ProviderHealth gets the health of the client's providers if it tracks them.
+func (r *StationRepo) ProviderHealth() []*metarclient.ProviderHealth {
+	if reporter, ok := r.client.(metarclient.ProviderHealthReporter); ok {
+		return reporter.ProviderHealth()
+	}
+
+	return nil
+}
+
+func (r *StationRepo) loadCoordinatesIfEmpty(ctx context.Context) error {
 	if r.coordinates != nil {
 		return nil
 	}
 
+	if r.hasAllCoordinates(r.config.Coordinates) {
+		logger.LogInfo("using configured station coordinates")
+		r.coordinates = r.config.Coordinates
+		return nil
+	}
+
 	if err := r.loadCoordinatesFromCache(); err == nil {
 		logger.LogInfo("successfully loaded cached station coordinates")
 		return nil
 	}
 
-	err := r.loadCoordinatesFromClient()
+	if err := r.loadCoordinatesFromDB(); err == nil {
+		logger.LogInfo("loaded station coordinates from the station db")
+		return nil
+	}
+
+	err := r.loadCoordinatesFromClient(ctx)
 	if err != nil {
 		return err
 	}
@@ -119,7 +328,7 @@ func (r *StationRepo) loadCoordinatesIfEmpty() error {
 		return nil
 	}
 
-	err = common.CacheToFile(PositionCacheFileName, bytes)
+	err = common.CacheToFileIn(r.cacheDir, PositionCacheFileName, bytes)
 	if err != nil {
 		logger.LogWarn("failed to save coordinates to cache")
 
@@ -130,8 +339,8 @@ func (r *StationRepo) loadCoordinatesIfEmpty() error {
 	return nil
 }
 
-func (r *StationRepo) loadCoordinatesFromClient() error {
-	positions, err := r.client.GetStationPositions()
+func (r *StationRepo) loadCoordinatesFromClient(ctx context.Context) error {
+	positions, err := r.client.GetStationPositions(ctx)
 	if err != nil {
 		return err
 	}
@@ -148,8 +357,24 @@ func (r *StationRepo) loadCoordinatesFromClient() error {
 	return nil
 }
 
+func (r *StationRepo) loadCoordinatesFromDB() error {
+	if r.config.StationDB == nil {
+		return errors.New("no station db")
+	}
+
+	coordinates, missing := r.config.StationDB.Coordinates(r.config.StationIDs)
+	if len(missing) > 0 {
+		logger.LogInfo("stations %v not found in station db", missing)
+		return errors.New("failed to find a station in the station db")
+	}
+
+	r.coordinates = coordinates
+
+	return nil
+}
+
 func (r *StationRepo) loadCoordinatesFromCache() error {
-	bytes, err := common.LoadCachedFile(PositionCacheFileName)
+	bytes, err := common.LoadCachedFileFrom(r.cacheDir, PositionCacheFileName)
 	if err != nil {
 		return err
 	}
@@ -162,15 +387,7 @@ func (r *StationRepo) loadCoordinatesFromCache() error {
 		return err
 	}
 
-	success := true
-	for _, stationID := range r.config.StationIDs {
-		if _, ok := positionMap[stationID]; !ok {
-			success = false
-			logger.LogInfo("station '%s' not found in cached positions", stationID)
-		}
-	}
-
-	if !success {
+	if !r.hasAllCoordinates(positionMap) {
 		return errors.New("failed to find a station in cached positions")
 	}
 
@@ -178,3 +395,19 @@ func (r *StationRepo) loadCoordinatesFromCache() error {
 
 	return nil
 }
+
+func (r *StationRepo) hasAllCoordinates(coordinates map[string]*geo.Coordinate) bool {
+	if coordinates == nil {
+		return false
+	}
+
+	success := true
+	for _, stationID := range r.config.StationIDs {
+		if _, ok := coordinates[stationID]; !ok {
+			success = false
+			logger.LogInfo("station '%s' not found in positions", stationID)
+		}
+	}
+
+	return success
+}
@@ -0,0 +1,225 @@
+package stationrepo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+)
+
+type fakeMetarClient struct {
+	reports   map[string]*metarclient.MetarReport
+	forecasts map[string]*metarclient.TafReport
+	err       error
+}
+
+func (c *fakeMetarClient) GetReports(ctx context.Context) (map[string]*metarclient.MetarReport, error) {
+	return c.reports, c.err
+}
+
+func (c *fakeMetarClient) GetStationPositions(ctx context.Context) (map[string]*metarclient.MetarPosition, error) {
+	return nil, metarclient.ErrStationPositionsNotSupported
+}
+
+func (c *fakeMetarClient) GetForecasts(ctx context.Context) (map[string]*metarclient.TafReport, error) {
+	if c.forecasts == nil {
+		return nil, metarclient.ErrForecastsNotSupported
+	}
+
+	return c.forecasts, c.err
+}
+
+func (c *fakeMetarClient) Fetch(ctx context.Context, handler metarclient.MetarResponseHandler) {
+	handler(c.GetReports(ctx))
+}
+
+func (c *fakeMetarClient) FetchStationPositions(ctx context.Context, handler metarclient.MetarPositionResponseHandler) {
+	handler(c.GetStationPositions(ctx))
+}
+
+func (c *fakeMetarClient) FetchForecasts(ctx context.Context, handler metarclient.TafResponseHandler) {
+	handler(c.GetForecasts(ctx))
+}
+
+func TestUpdateReportsKeepsLastGoodReport(t *testing.T) {
+	client := &fakeMetarClient{
+		reports: map[string]*metarclient.MetarReport{
+			"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleIFR, WindSpeedKts: 12},
+			"CYYC": {StationID: "CYYC", Error: true, FlightRules: common.FlightRuleError},
+		},
+	}
+
+	repo := createTestRepo(t, client)
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.clock = common.ClockFunc(func() time.Time { return now })
+
+	stations := map[string]*Station{
+		"CYEG": {ID: "CYEG"},
+		"CYYC": {ID: "CYYC"},
+	}
+
+	if err := repo.UpdateReports(context.Background(), stations); err != nil {
+		t.Error("unexpected error", err)
+	}
+
+	assertStation(t, stations["CYEG"], common.FlightRuleIFR, 12, false)
+	assertStation(t, stations["CYYC"], common.FlightRuleError, 0, false)
+
+	client.reports = nil
+	client.err = errors.New("fetch failed")
+	now = now.Add(30 * time.Minute)
+
+	if err := repo.UpdateReports(context.Background(), stations); err == nil {
+		t.Error("expected fetch error")
+	}
+	assertStation(t, stations["CYEG"], common.FlightRuleIFR, 12, false)
+
+	now = now.Add(61 * time.Minute)
+	repo.UpdateReports(context.Background(), stations)
+	assertStation(t, stations["CYEG"], common.FlightRuleIFR, 12, true)
+
+	now = now.Add(2 * time.Hour)
+	repo.UpdateReports(context.Background(), stations)
+	assertStation(t, stations["CYEG"], common.FlightRuleError, 0, false)
+}
+
+func TestUpdateReportsReplacesErrorWithFreshReport(t *testing.T) {
+	client := &fakeMetarClient{
+		reports: map[string]*metarclient.MetarReport{
+			"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleVFR},
+		},
+	}
+
+	repo := createTestRepo(t, client)
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	repo.clock = common.ClockFunc(func() time.Time { return now })
+
+	stations := map[string]*Station{"CYEG": {ID: "CYEG"}}
+	repo.UpdateReports(context.Background(), stations)
+
+	client.reports["CYEG"] = &metarclient.MetarReport{StationID: "CYEG", Error: true, FlightRules: common.FlightRuleError}
+	now = now.Add(2 * time.Hour)
+	repo.UpdateReports(context.Background(), stations)
+	assertStation(t, stations["CYEG"], common.FlightRuleVFR, 0, true)
+
+	client.reports["CYEG"] = &metarclient.MetarReport{StationID: "CYEG", FlightRules: common.FlightRuleMVFR, WindSpeedKts: 4}
+	repo.UpdateReports(context.Background(), stations)
+	assertStation(t, stations["CYEG"], common.FlightRuleMVFR, 4, false)
+}
+
+func TestUpdateReportsAgesByObservationTime(t *testing.T) {
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	client := &fakeMetarClient{
+		reports: map[string]*metarclient.MetarReport{
+			"CYEG": {StationID: "CYEG", FlightRules: common.FlightRuleVFR, ObservationTime: now.Add(-95 * time.Minute)},
+			"CYYC": {StationID: "CYYC", FlightRules: common.FlightRuleVFR, ObservationTime: now.Add(-4 * time.Hour)},
+		},
+	}
+
+	repo := createTestRepo(t, client)
+	repo.clock = common.ClockFunc(func() time.Time { return now })
+
+	stations := map[string]*Station{
+		"CYEG": {ID: "CYEG"},
+		"CYYC": {ID: "CYYC"},
+	}
+	repo.UpdateReports(context.Background(), stations)
+
+	assertStation(t, stations["CYEG"], common.FlightRuleVFR, 0, true)
+	assertStation(t, stations["CYYC"], common.FlightRuleError, 0, false)
+
+	if age := repo.ObservationAge(stations["CYEG"]); age != 95*time.Minute {
+		t.Error("unnexpected observation age", age)
+	}
+
+	if age := repo.ObservationAge(stations["CYYC"]); age != 0 {
+		t.Error("expected 0 age for expired station", age)
+	}
+}
+
+func TestCreateStationRepoDefaultsAges(t *testing.T) {
+	repo := CreateStationRepo(&fakeMetarClient{}, &Config{StationIDs: []string{"CYEG"}, CacheDir: t.TempDir()})
+
+	if repo.config.StaleAfter != 90*time.Minute || repo.config.ExpireAfter != 180*time.Minute {
+		t.Errorf("expected default ages, got %s and %s", repo.config.StaleAfter, repo.config.ExpireAfter)
+	}
+}
+
+func TestUpdateForecasts(t *testing.T) {
+	client := &fakeMetarClient{}
+	repo := createTestRepo(t, client)
+	repo.clock = common.ClockFunc(func() time.Time { return time.Date(2021, 1, 10, 5, 0, 0, 0, time.UTC) })
+
+	stations := map[string]*Station{
+		"CYEG": {ID: "CYEG"},
+		"CYYC": {ID: "CYYC"},
+	}
+
+	if err := repo.UpdateForecasts(context.Background(), stations, 3*time.Hour); err != metarclient.ErrForecastsNotSupported {
+		t.Error("expected forecasts not supported", err)
+	}
+
+	if stations["CYEG"].ForecastFlightRules != common.FlightRuleError {
+		t.Error("expected error forecast flight rules")
+	}
+
+	decoded, err := metarclient.ParseTAF("TAF CYEG 100540Z 1006/1106 21010KT P6SM FEW030 FM100900 24012KT 2SM BR OVC008")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validFrom, validTo, err := decoded.Resolve(time.Date(2021, 1, 10, 5, 40, 0, 0, time.UTC), metarclient.FAAFlightCategoryRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.forecasts = map[string]*metarclient.TafReport{
+		"CYEG": {StationID: "CYEG", ValidFrom: validFrom, ValidTo: validTo, Decoded: decoded},
+		"CYYC": {StationID: "CYYC", Error: true},
+	}
+
+	if err := repo.UpdateForecasts(context.Background(), stations, 3*time.Hour); err != nil {
+		t.Error(err)
+	}
+
+	if stations["CYEG"].ForecastFlightRules != common.FlightRuleVFR {
+		t.Error("unnexpected CYEG forecast flight rules", stations["CYEG"].ForecastFlightRules)
+	}
+
+	if stations["CYYC"].ForecastFlightRules != common.FlightRuleError {
+		t.Error("unnexpected CYYC forecast flight rules", stations["CYYC"].ForecastFlightRules)
+	}
+
+	repo.UpdateForecasts(context.Background(), stations, 5*time.Hour)
+	if stations["CYEG"].ForecastFlightRules != common.FlightRuleIFR {
+		t.Error("unnexpected CYEG forecast flight rules", stations["CYEG"].ForecastFlightRules)
+	}
+}
+
+func createTestRepo(t *testing.T, client metarclient.MetarClient) *StationRepo {
+	return CreateStationRepo(client, &Config{
+		StationIDs:  []string{"CYEG", "CYYC"},
+		StaleAfter:  90 * time.Minute,
+		ExpireAfter: 180 * time.Minute,
+		CacheDir:    t.TempDir(),
+	})
+}
+
+func assertStation(t *testing.T, station *Station, flightRules string, windSpeed float64, stale bool) {
+	t.Helper()
+
+	if station.FlightRules != flightRules {
+		t.Errorf("%s: expected flight rules %s, got %s", station.ID, flightRules, station.FlightRules)
+	}
+
+	if station.WindSpeedKts != windSpeed {
+		t.Errorf("%s: expected wind %.0f, got %.0f", station.ID, windSpeed, station.WindSpeedKts)
+	}
+
+	if station.Stale != stale {
+		t.Errorf("%s: expected stale %t, got %t", station.ID, stale, station.Stale)
+	}
+}
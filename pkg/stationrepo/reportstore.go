@@ -0,0 +1,90 @@
+package stationrepo
+
+import (
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+)
+
+const (
+	LastGoodReportsFileName = "last_good_reports.json"
+)
+
+// StoredReport is the last good report received for a station.
+type StoredReport struct {
+	Report     *metarclient.MetarReport
+	ReceivedAt time.Time
+}
+
+// ReportStore keeps the last good report for each station so a failed update doesn't blank the map.
+type ReportStore struct {
+	reports  map[string]*StoredReport
+	cacheDir string
+}
+
+// CreateReportStore creates a store cached in cacheDir.
+func CreateReportStore(cacheDir string) *ReportStore {
+	return &ReportStore{
+		reports:  make(map[string]*StoredReport),
+		cacheDir: cacheDir,
+	}
+}
+
+// Put stores the report if it isn't an error.
+func (s *ReportStore) Put(report *metarclient.MetarReport, receivedAt time.Time) {
+	if report == nil || report.Error {
+		return
+	}
+
+	s.reports[report.StationID] = &StoredReport{
+		Report:     report,
+		ReceivedAt: receivedAt,
+	}
+}
+
+// Age is the age of the observation, or the time since it was received when the observation time is unknown.
+func (s *StoredReport) Age(now time.Time) time.Duration {
+	if s.Report.ObservationTime.IsZero() {
+		return now.Sub(s.ReceivedAt)
+	}
+
+	return s.Report.ObservationAge(now)
+}
+
+// Get gets the last good report for the station.
+func (s *ReportStore) Get(stationID string) (*StoredReport, bool) {
+	stored, ok := s.reports[stationID]
+
+	return stored, ok
+}
+
+// LoadFromCache restores the reports saved to the file cache.
+func (s *ReportStore) LoadFromCache() error {
+	bytes, err := common.LoadCachedFileFrom(s.cacheDir, LastGoodReportsFileName)
+	if err != nil {
+		return err
+	}
+
+	reports := make(map[string]*StoredReport)
+	if err := json5.Unmarshal(bytes, &reports); err != nil {
+		logger.LogError("failed to unmarshal cached reports")
+		return err
+	}
+
+	s.reports = reports
+
+	return nil
+}
+
+// SaveToCache saves the reports to the file cache.
+func (s *ReportStore) SaveToCache() error {
+	bytes, err := json5.MarshalIndent(s.reports, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return common.CacheToFileIn(s.cacheDir, LastGoodReportsFileName, bytes)
+}
@@ -0,0 +1,105 @@
+package stationrepo
+
+import (
+	"errors"
+	"io/ioutil"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/geo"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+)
+
+// StationList is a list of stations with their positions, like one written by station discovery.
+type StationList struct {
+	Stations []*ListedStation `json:"stations"`
+}
+
+type ListedStation struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Elevation float64  `json:"elevation"`
+	SiteTypes []string `json:"site_types"`
+}
+
+// CreateStationList lists the discovered stations.
+func CreateStationList(discovered []*metarclient.DiscoveredStation) *StationList {
+	list := &StationList{Stations: make([]*ListedStation, len(discovered))}
+	for i, d := range discovered {
+		list.Stations[i] = &ListedStation{
+			ID:        d.StationID,
+			Name:      d.Name,
+			Latitude:  d.Latitude,
+			Longitude: d.Longitude,
+			Elevation: d.Elevation,
+			SiteTypes: d.SiteTypes,
+		}
+	}
+
+	return list
+}
+
+// LoadStationList reads a station list file.
+func LoadStationList(filePath string) (*StationList, error) {
+	bytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &StationList{}
+	if err := json5.Unmarshal(bytes, list); err != nil {
+		return nil, err
+	}
+
+	if len(list.Stations) == 0 {
+		return nil, errors.New("station list is empty")
+	}
+
+	return list, nil
+}
+
+// Save writes the list to the file.
+func (l *StationList) Save(filePath string) error {
+	bytes, err := json5.MarshalIndent(l, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, bytes, common.CacheFilePermission)
+}
+
+// StationIDs gets the IDs of the listed stations in order.
+func (l *StationList) StationIDs() []string {
+	ids := make([]string, len(l.Stations))
+	for i, s := range l.Stations {
+		ids[i] = s.ID
+	}
+
+	return ids
+}
+
+// Coordinates gets the position of each listed station by ID.
+func (l *StationList) Coordinates() map[string]*geo.Coordinate {
+	coordinates := make(map[string]*geo.Coordinate, len(l.Stations))
+	for _, s := range l.Stations {
+		coordinates[s.ID] = &geo.Coordinate{
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Altitude:  s.Elevation,
+		}
+	}
+
+	return coordinates
+}
+
+// Names gets the name of each listed station by ID.
+func (l *StationList) Names() map[string]string {
+	names := make(map[string]string, len(l.Stations))
+	for _, s := range l.Stations {
+		names[s.ID] = s.Name
+	}
+
+	return names
+}
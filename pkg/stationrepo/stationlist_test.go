@@ -0,0 +1,97 @@
+package stationrepo
+
+import (
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationdb"
+)
+
+func TestStationListSaveAndLoad(t *testing.T) {
+	list := CreateStationList([]*metarclient.DiscoveredStation{
+		{StationID: "CYEG", Name: "Edmonton Intl", Latitude: 53.3097, Longitude: -113.5792, Elevation: 723, SiteTypes: []string{"METAR", "TAF"}},
+		{StationID: "CYXD", Name: "Edmonton Muni", Latitude: 53.57, Longitude: -113.52, Elevation: 671, SiteTypes: []string{"METAR"}},
+	})
+
+	filePath := path.Join(t.TempDir(), "station_list.json")
+	if err := list.Save(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadStationList(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids := loaded.StationIDs(); len(ids) != 2 || ids[0] != "CYEG" || ids[1] != "CYXD" {
+		t.Error("unnexpected station ids", ids)
+	}
+
+	if s := loaded.Stations[0]; s.Name != "Edmonton Intl" || len(s.SiteTypes) != 2 {
+		t.Errorf("unnexpected station %+v", s)
+	}
+
+	if c := loaded.Coordinates()["CYXD"]; c.Latitude != 53.57 || c.Longitude != -113.52 || c.Altitude != 671 {
+		t.Errorf("unnexpected coordinate %+v", c)
+	}
+
+	if _, err := LoadStationList(path.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected missing file error")
+	}
+}
+
+func TestLoadStationsUsesConfiguredCoordinates(t *testing.T) {
+	list := CreateStationList([]*metarclient.DiscoveredStation{
+		{StationID: "CYEG", Latitude: 53.3097, Longitude: -113.5792},
+		{StationID: "CYXD", Latitude: 53.57, Longitude: -113.52},
+	})
+
+	repo := CreateStationRepo(&fakeMetarClient{}, &Config{
+		StationIDs:  list.StationIDs(),
+		StaleAfter:  90 * time.Minute,
+		ExpireAfter: 180 * time.Minute,
+		Coordinates: list.Coordinates(),
+		CacheDir:    t.TempDir(),
+	})
+
+	stations, err := repo.LoadStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := stations["CYXD"]; s.Ordinal != 1 || s.Coordinate.Latitude != 53.57 {
+		t.Errorf("unnexpected station %+v", s)
+	}
+}
+
+func TestLoadStationsFallsBackToStationDB(t *testing.T) {
+	db := stationdb.CreateStationDB([]*stationdb.Station{
+		{ICAO: "CYEG", Name: "Edmonton Intl", Latitude: 53.3, Longitude: -113.58, Elevation: 710},
+		{ICAO: "CYYC", Name: "Calgary Intl", Latitude: 51.12, Longitude: -114.02, Elevation: 1085},
+	})
+
+	repo := CreateStationRepo(&fakeMetarClient{}, &Config{
+		StationIDs:  []string{"CYEG", "CYYC"},
+		StaleAfter:  90 * time.Minute,
+		ExpireAfter: 180 * time.Minute,
+		Names:       map[string]string{"CYYC": "Calgary"},
+		StationDB:   db,
+		CacheDir:    t.TempDir(),
+	})
+
+	stations, err := repo.LoadStations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := stations["CYEG"]; s.Name != "Edmonton Intl" || s.Coordinate.Altitude != 710 {
+		t.Errorf("unnexpected station %+v", s)
+	}
+
+	if s := stations["CYYC"]; s.Name != "Calgary" || s.Coordinate.Latitude != 51.12 {
+		t.Errorf("unnexpected station %+v", s)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/ataboo/go-metar-blink/pkg/common"
 	"github.com/ataboo/go-metar-blink/pkg/geo"
+	"github.com/ataboo/go-metar-blink/pkg/ledoutput"
 	"github.com/ataboo/go-metar-blink/pkg/logger"
 	"github.com/ataboo/go-metar-blink/pkg/stationrepo"
 	"github.com/veandco/go-sdl2/sdl"
@@ -24,20 +25,25 @@ const (
 type VirtualMap struct {
 	stations         map[string]*stationrepo.Station
 	renderedIDs      map[string]*sdl.Surface
+	renderedNames    map[string]*sdl.Surface
 	stationIDs       []string
 	window           *sdl.Window
 	windowSurface    *sdl.Surface
 	stationScreenPos map[string]*sdl.Point
+	pipeline         *ledoutput.Pipeline
 	running          bool
 }
 
-func CreateVirtualMap(stations map[string]*stationrepo.Station, brightness byte) (vMap *VirtualMap, err error) {
+// CreateVirtualMap opens a window showing the stations. Colors are shown after the pipeline's gamma, white
+// balance, and brightness to preview the strip.
+func CreateVirtualMap(stations map[string]*stationrepo.Station, pipeline *ledoutput.Pipeline) (vMap *VirtualMap, err error) {
 	if len(stations) == 0 {
 		return nil, errors.New("need at least one station")
 	}
 
 	vMap = &VirtualMap{
 		stations: stations,
+		pipeline: pipeline,
 		running:  true,
 	}
 
@@ -120,6 +126,28 @@ func (m *VirtualMap) renderIds() error {
 		}
 	}
 
+	return m.renderNames()
+}
+
+func (m *VirtualMap) renderNames() error {
+	font, err := ttf.OpenFont(path.Join(common.GetResourcesRoot(), "dev", "meslo_powerline.ttf"), 11)
+	if err != nil {
+		return err
+	}
+	defer font.Close()
+
+	m.renderedNames = make(map[string]*sdl.Surface, len(m.stations))
+	for _, s := range m.stations {
+		if s.Name == "" {
+			continue
+		}
+
+		m.renderedNames[s.ID], err = font.RenderUTF8Blended(s.Name, sdl.Color{220, 220, 220, 255})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -127,6 +155,9 @@ func (m *VirtualMap) Dispose() {
 	for _, i := range m.renderedIDs {
 		i.Free()
 	}
+	for _, i := range m.renderedNames {
+		i.Free()
+	}
 
 	ttf.Quit()
 	sdl.Quit()
@@ -158,7 +189,7 @@ func (m *VirtualMap) Update() error {
 			Y: screenPos.Y - idSolid.H/2 - 18,
 			W: idSolid.W + 8,
 			H: idSolid.H + 4,
-		}, station.Color.ARGB())
+		}, m.pipeline.Correct(station.Color).ARGB())
 
 		m.windowSurface.FillRect(&sdl.Rect{
 			X: screenPos.X - 1,
@@ -177,6 +208,19 @@ func (m *VirtualMap) Update() error {
 		if err != nil {
 			logger.LogError(err.Error())
 		}
+
+		if nameSolid, ok := m.renderedNames[stationID]; ok {
+			err = nameSolid.Blit(&nameSolid.ClipRect, m.windowSurface, &sdl.Rect{
+				X: screenPos.X - nameSolid.W/2,
+				Y: screenPos.Y + 4,
+				W: nameSolid.W,
+				H: nameSolid.H,
+			})
+
+			if err != nil {
+				logger.LogError(err.Error())
+			}
+		}
 	}
 
 	return m.window.UpdateSurface()
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ataboo/go-metar-blink/pkg/common"
+	"github.com/ataboo/go-metar-blink/pkg/logger"
+	"github.com/ataboo/go-metar-blink/pkg/metarclient"
+	"github.com/ataboo/go-metar-blink/pkg/stationdb"
+)
+
+// runRefreshStationDB discovers the stations in an area with the configured client and merges them into
+// the station db file. Returns the exit code.
+func runRefreshStationDB(args []string) int {
+	settings := common.GetAppSettings()
+
+	flags := flag.NewFlagSet("stationdb", flag.ContinueOnError)
+	bbox := flags.String("bbox", "", "bounding box to refresh as \"minLat,minLon,maxLat,maxLon\"")
+	center := flags.String("center", "", "center of the area to refresh as \"lat,lon\"")
+	radius := flags.Float64("radius", 0, "radius around the center to refresh in km")
+	all := flags.Bool("all", false, "include stations that don't report METARs")
+	dbPath := flags.String("db", stationDBPath(settings), "station db file to update")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	area, err := parseDiscoveryArea(*bbox, *center, *radius)
+	if err != nil {
+		fmt.Printf("stationdb: %s\n", err)
+		flags.Usage()
+		return 2
+	}
+
+	db, err := stationdb.Load(*dbPath)
+	if os.IsNotExist(err) {
+		db = stationdb.CreateStationDB(nil)
+	} else if err != nil {
+		logger.LogError("Failed to load station db: %s", err.Error())
+		return 1
+	}
+
+	client, err := createMetarClient(settings, settings.StationIDs)
+	if err != nil {
+		return 1
+	}
+
+	stations, err := metarclient.DiscoverStations(context.Background(), client, area, !*all)
+	if err != nil {
+		logger.LogError("Failed to discover stations: %s", err.Error())
+		return 1
+	}
+
+	before := db.Len()
+	updated := db.MergeDiscovered(stations)
+	if err := db.Save(*dbPath); err != nil {
+		logger.LogError("Failed to save station db: %s", err.Error())
+		return 1
+	}
+	fmt.Printf("updated %d stations, %d new, %d total in '%s'\n", updated, db.Len()-before, db.Len(), *dbPath)
+
+	return 0
+}
@@ -10,6 +10,8 @@ mkdir -p build/$APP
 
 cp settings.json ./build/$APP
 cp -r resources/arm/* ./build/$APP
+mkdir -p build/$APP/resources
+cp -r resources/stationdb ./build/$APP/resources
 
 docker run --rm -v "$PWD":/usr/src/$APP --platform linux/arm/v6 -w /usr/src/$APP ws2811-builder:latest go build -o "./build/$APP/$OUTPUT_BIN" -v
 